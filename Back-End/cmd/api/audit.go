@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
+	"prime-shine-api/internal/wave"
+
+	"github.com/pkg/errors"
+)
+
+// Records a change made by the authenticated user in the audit log.
+func (app *application) recordAudit(
+	tx db.WriteDBExecutor,
+	r *http.Request,
+	action string,
+	entityType string,
+	entityID any,
+	before any,
+	after any,
+) error {
+	id := ""
+	if entityID != nil {
+		id = fmt.Sprint(entityID)
+	}
+
+	err := data.RecordAuditLog(r.Context(), tx, app.contextGetUserID(r), action, entityType, id, before, after)
+	if err != nil {
+		return errors.Wrap(err, "RecordAuditLog")
+	}
+
+	return nil
+}
+
+// Records a Wave change made by the authenticated user as pending, before Wave is called.
+// Wave changes can not be rolled back with the request's transaction, so the entry is committed on
+// its own: every change that Wave may have made is audited, even if the request fails afterwards.
+// If the entry can not be recorded, the change must not be made.
+func (app *application) beginWaveAudit(r *http.Request, action string, entityType string, entityID any, before any, after any) (int, error) {
+	id := ""
+	if entityID != nil {
+		id = fmt.Sprint(entityID)
+	}
+
+	auditLogID, err := data.BeginAuditLog(r.Context(), app.db, app.contextGetUserID(r), action, entityType, id, before, after)
+	if err != nil {
+		return 0, errors.Wrap(err, "BeginAuditLog")
+	}
+
+	return auditLogID, nil
+}
+
+// Records whether a Wave change begun with beginWaveAudit succeeded, given the error Wave returned.
+// A failure to do so is only logged, and leaves the entry pending.
+func (app *application) finishWaveAudit(r *http.Request, auditLogID int, waveErr error, after any) {
+	outcome := data.AUDIT_OUTCOME_SUCCEEDED
+	if waveErr != nil {
+		outcome = data.AUDIT_OUTCOME_FAILED
+	}
+
+	// Wave has answered, so the outcome is recorded even if the client has gone away
+	ctx := context.WithoutCancel(r.Context())
+
+	err := data.FinishAuditLog(ctx, app.db, auditLogID, outcome, after)
+	if err != nil {
+		app.logError(r, errors.Wrap(err, "FinishAuditLog"))
+	}
+}
+
+// The snapshot helpers below grab the state of a Wave entity before it is changed.
+// A failed lookup should not block the change itself, so it is only logged.
+
+func (app *application) waveCustomerSnapshot(r *http.Request, customerID any) *wave.WaveCustomer {
+	businessInfo, err := app.wave.GetBusinessInfo(r.Context())
+	if err != nil {
+		app.logError(r, errors.Wrap(err, "audit snapshot: GetBusinessInfo"))
+		return nil
+	}
+
+	customer, err := app.wave.GetCustomer(r.Context(), businessInfo.BusinessID, fmt.Sprint(customerID))
+	if err != nil {
+		app.logError(r, errors.Wrap(err, "audit snapshot: GetCustomer"))
+		return nil
+	}
+
+	return customer
+}
+
+func (app *application) waveInvoiceSnapshot(r *http.Request, invoiceID any) *wave.WaveInvoice {
+	businessInfo, err := app.wave.GetBusinessInfo(r.Context())
+	if err != nil {
+		app.logError(r, errors.Wrap(err, "audit snapshot: GetBusinessInfo"))
+		return nil
+	}
+
+	invoice, err := app.wave.GetInvoice(r.Context(), businessInfo.BusinessID, fmt.Sprint(invoiceID))
+	if err != nil {
+		app.logError(r, errors.Wrap(err, "audit snapshot: GetInvoice"))
+		return nil
+	}
+
+	return invoice
+}
+
+func (app *application) waveInvoicePaymentSnapshot(
+	r *http.Request,
+	identityBusinessID string,
+	internalInvoiceID string,
+	invoicePaymentID string,
+) *wave.WaveInvoicePayment {
+	payments, err := app.wave.GetInvoicePayments(r.Context(), identityBusinessID, internalInvoiceID)
+	if err != nil {
+		app.logError(r, errors.Wrap(err, "audit snapshot: GetInvoicePayments"))
+		return nil
+	}
+
+	for _, payment := range *payments {
+		if payment.ID == invoicePaymentID {
+			return &payment
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"prime-shine-api/internal/data"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type queryAuditLogBody struct {
+	data.AuditLogFilter
+	PageNum  int `json:"pageNum"`
+	PageSize int `json:"pageSize"`
+}
+
+// Route for querying the audit log (paginated).
+func (app *application) queryAuditLog(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body queryAuditLogBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if body.PageNum < 1 || body.PageSize < 1 {
+		app.errorResponse(w, r, http.StatusBadRequest, "pageNum and pageSize must be positive.")
+		return
+	}
+
+	auditLog, err := data.QueryAuditLog(r.Context(), app.db, body.AuditLogFilter, body.PageNum, body.PageSize)
+	if err != nil {
+		err = errors.Wrap(err, "QueryAuditLog")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data := jsondata{"auditLog": auditLog}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		err = errors.Wrap(err, "writeJSON")
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/assert"
+	"prime-shine-api/internal/mocks"
+	"strings"
+	"testing"
+)
+
+func TestQueryAuditLogInvalidPage(t *testing.T) {
+	app := application{
+		logger:           mocks.Logger(),
+		isSessionRevoked: mocks.SessionNotRevoked,
+	}
+	rr := httptest.NewRecorder()
+
+	body := strings.NewReader(`{"entityType": "schedule", "pageNum": 0, "pageSize": 25}`)
+	r, err := http.NewRequest(http.MethodPost, "/api/audit/query", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := internal.CreateToken("1", "1", internal.ROLE_OWNER, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Header.Add("Authorization", token)
+	app.authenticate(app.requireRole(app.queryAuditLog, internal.ROLE_OWNER))(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusBadRequest)
+}
+
+func TestQueryAuditLogNotOwner(t *testing.T) {
+	app := application{
+		logger:           mocks.Logger(),
+		isSessionRevoked: mocks.SessionNotRevoked,
+	}
+	rr := httptest.NewRecorder()
+
+	body := strings.NewReader(`{"pageNum": 1, "pageSize": 25}`)
+	r, err := http.NewRequest(http.MethodPost, "/api/audit/query", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := internal.CreateToken("1", "1", internal.ROLE_CLEANER, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Header.Add("Authorization", token)
+	app.authenticate(app.requireRole(app.queryAuditLog, internal.ROLE_OWNER))(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusForbidden)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"prime-shine-api/internal/db"
+)
+
+type contextKey string
+
+const (
+	userIDContextKey    = contextKey("userID")
+	accountIDContextKey = contextKey("accountID")
+	sessionIDContextKey = contextKey("sessionID")
+	roleContextKey      = contextKey("role")
+	txContextKey        = contextKey("tx")
+)
+
+// Returns a copy of the request with the authenticated user's ID attached to its context.
+func (app *application) contextSetUserID(r *http.Request, userID int) *http.Request {
+	ctx := context.WithValue(r.Context(), userIDContextKey, userID)
+	return r.WithContext(ctx)
+}
+
+// Grabs the authenticated user's ID from the request context.
+// This should only be called from routes wrapped by authenticate.
+func (app *application) contextGetUserID(r *http.Request) int {
+	userID, ok := r.Context().Value(userIDContextKey).(int)
+	if !ok {
+		panic("missing user ID value in request context")
+	}
+
+	return userID
+}
+
+// Returns a copy of the request with the authenticated user's account ID attached to its context.
+func (app *application) contextSetAccountID(r *http.Request, accountID int) *http.Request {
+	ctx := context.WithValue(r.Context(), accountIDContextKey, accountID)
+	return r.WithContext(ctx)
+}
+
+// Grabs the ID of the account that the authenticated user works in from the request context.
+// Schedules, recurring services, employees and crews are scoped by it rather than by the user.
+// This should only be called from routes wrapped by authenticate.
+func (app *application) contextGetAccountID(r *http.Request) int {
+	accountID, ok := r.Context().Value(accountIDContextKey).(int)
+	if !ok {
+		panic("missing account ID value in request context")
+	}
+
+	return accountID
+}
+
+// Returns a copy of the request with the authenticated session's ID attached to its context.
+func (app *application) contextSetSessionID(r *http.Request, sessionID int) *http.Request {
+	ctx := context.WithValue(r.Context(), sessionIDContextKey, sessionID)
+	return r.WithContext(ctx)
+}
+
+// Grabs the authenticated session's ID from the request context.
+// This should only be called from routes wrapped by authenticate.
+func (app *application) contextGetSessionID(r *http.Request) int {
+	sessionID, ok := r.Context().Value(sessionIDContextKey).(int)
+	if !ok {
+		panic("missing session ID value in request context")
+	}
+
+	return sessionID
+}
+
+// Returns a copy of the request with the authenticated user's role attached to its context.
+func (app *application) contextSetRole(r *http.Request, role string) *http.Request {
+	ctx := context.WithValue(r.Context(), roleContextKey, role)
+	return r.WithContext(ctx)
+}
+
+// Grabs the authenticated user's role from the request context.
+// This should only be called from routes wrapped by authenticate.
+func (app *application) contextGetRole(r *http.Request) string {
+	role, ok := r.Context().Value(roleContextKey).(string)
+	if !ok {
+		panic("missing role value in request context")
+	}
+
+	return role
+}
+
+// Returns a copy of the request with its transaction attached to its context.
+func (app *application) contextSetTx(r *http.Request, tx *requestTx) *http.Request {
+	ctx := context.WithValue(r.Context(), txContextKey, tx)
+	return r.WithContext(ctx)
+}
+
+func (app *application) contextGetRequestTx(r *http.Request) *requestTx {
+	tx, ok := r.Context().Value(txContextKey).(*requestTx)
+	if !ok {
+		panic("missing transaction value in request context")
+	}
+
+	return tx
+}
+
+// Grabs the request's transaction from the request context.
+// This should only be called from routes wrapped by transaction.
+func (app *application) contextGetTx(r *http.Request) db.WriteDBExecutor {
+	return app.contextGetRequestTx(r).tx
+}
+
+// Runs fn in the background once the request's transaction is committed, e.g. to send an email
+// without the response depending on it. fn is dropped if the transaction is rolled back.
+// This should only be called from routes wrapped by transaction.
+func (app *application) contextAfterCommit(r *http.Request, fn func()) {
+	state := app.contextGetRequestTx(r)
+	state.afterCommit = append(state.afterCommit, fn)
+}
+
+// Makes the request's transaction commit on 4xx responses as well, for writes that have to
+// outlive a rejected request (e.g. counting failed login attempts).
+// This should only be called from routes wrapped by transaction.
+func (app *application) commitOnClientError(r *http.Request) {
+	app.contextGetRequestTx(r).commitOnClientError = true
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"prime-shine-api/internal/data"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type createCrewBody struct {
+	Name      string `json:"name"`
+	MemberIDs []int  `json:"memberIDs"`
+}
+
+// Route for creating a crew.
+func (app *application) createCrew(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body createCrewBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	accountID := app.contextGetAccountID(r)
+
+	tx := app.contextGetTx(r)
+
+	crew, err := data.CreateCrew(r.Context(), tx, accountID, body.Name, body.MemberIDs)
+
+	var unavailable *data.EmployeeUnavailableError
+	if errors.As(err, &unavailable) {
+		app.employeeUnavailableResponse(w, r, unavailable)
+		return
+	}
+
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, "Could not find employee.")
+		return
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "CreateCrew")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = app.recordAudit(tx, r, data.AUDIT_ACTION_CREATE, data.AUDIT_ENTITY_CREW, crew.ID, nil, crew)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+		return
+	}
+
+	data := jsondata{"crew": crew}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		err = errors.Wrap(err, "writeJSON")
+		app.serverErrorResponse(w, r, err)
+	}
+}
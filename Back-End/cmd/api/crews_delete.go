@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type deleteCrewBody struct {
+	CrewID int `json:"crewID"`
+}
+
+// Route for deleting a crew.
+// The visits it was assigned to stay in their schedules, without the crew.
+func (app *application) deleteCrew(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body deleteCrewBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	accountID := app.contextGetAccountID(r)
+
+	tx := app.contextGetTx(r)
+
+	query := db.Where(data.CrewColumns.ID.Eq(body.CrewID))
+	before, err := data.FindOneCrew(r.Context(), tx, accountID, query)
+	if err != nil {
+		err = errors.Wrap(err, "FindOneCrew")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	success, err := data.DeleteCrew(r.Context(), tx, accountID, body.CrewID)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, "Could not find crew.")
+		return
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "DeleteCrew")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = app.recordAudit(tx, r, data.AUDIT_ACTION_DELETE, data.AUDIT_ENTITY_CREW, body.CrewID, before, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+		return
+	}
+
+	data := jsondata{"success": success}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		err = errors.Wrap(err, "writeJSON")
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type editCrewBody struct {
+	CrewID    int    `json:"crewID"`
+	Name      string `json:"name"`
+	MemberIDs []int  `json:"memberIDs"`
+}
+
+// Route for editing a crew.
+func (app *application) editCrew(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body editCrewBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	accountID := app.contextGetAccountID(r)
+
+	tx := app.contextGetTx(r)
+
+	query := db.Where(data.CrewColumns.ID.Eq(body.CrewID))
+	before, err := data.FindOneCrew(r.Context(), tx, accountID, query)
+	if err != nil {
+		err = errors.Wrap(err, "FindOneCrew")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if before == nil {
+		app.notFoundResponse(w, r, "Could not find crew.")
+		return
+	}
+
+	crew, err := data.EditCrew(r.Context(), tx, accountID, body.CrewID, body.Name, body.MemberIDs)
+
+	var doubleBooking *data.DoubleBookingError
+	if errors.As(err, &doubleBooking) {
+		app.doubleBookingResponse(w, r, doubleBooking)
+		return
+	}
+
+	var unavailable *data.EmployeeUnavailableError
+	if errors.As(err, &unavailable) {
+		app.employeeUnavailableResponse(w, r, unavailable)
+		return
+	}
+
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, "Could not find crew or employee.")
+		return
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "EditCrew")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = app.recordAudit(tx, r, data.AUDIT_ACTION_EDIT, data.AUDIT_ENTITY_CREW, crew.ID, before, crew)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+		return
+	}
+
+	data := jsondata{"crew": crew}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		err = errors.Wrap(err, "writeJSON")
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"prime-shine-api/internal/data"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type queryCrewScheduledCustomersBody struct {
+	CrewID     int `json:"crewID"`
+	ScheduleID int `json:"scheduleID"`
+}
+
+// Route for querying what a crew is doing during a schedule's week.
+func (app *application) queryCrewScheduledCustomers(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body queryCrewScheduledCustomersBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	accountID := app.contextGetAccountID(r)
+	scheduledCustomers, err := data.QueryCrewScheduledCustomers(r.Context(), app.db, accountID, body.CrewID, body.ScheduleID)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, "Could not find crew or schedule.")
+		return
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "QueryCrewScheduledCustomers")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data := jsondata{"scheduledCustomers": scheduledCustomers}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		err = errors.Wrap(err, "writeJSON")
+		app.serverErrorResponse(w, r, err)
+	}
+}
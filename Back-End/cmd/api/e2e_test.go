@@ -0,0 +1,1203 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/assert"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/wave"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestFakeWave(t *testing.T) {
+	fake := newFakeWave(t)
+	client := fake.Client(FAKE_WAVE_TOKEN)
+	ctx := context.Background()
+
+	businessInfo, err := client.GetBusinessInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, businessInfo.BusinessID, FAKE_WAVE_BUSINESS_ID)
+	assert.Equal(t, businessInfo.ProductID, FAKE_WAVE_PRODUCT_ID)
+	assert.Equal(t, businessInfo.IdentityBusinessID, FAKE_WAVE_IDENTITY_BUSINESS_ID)
+
+	err = client.CreateCustomer(ctx, map[string]any{"businessId": FAKE_WAVE_BUSINESS_ID, "name": "Grace Hopper"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	customers, err := client.GetAllCustomers(ctx, FAKE_WAVE_BUSINESS_ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(*customers), 1)
+	assert.Equal(t, (*customers)[0].Name, "Grace Hopper")
+
+	customer, err := client.GetCustomer(ctx, FAKE_WAVE_BUSINESS_ID, (*customers)[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, customer.ID, (*customers)[0].ID)
+	assert.Equal(t, slices.Contains(fake.Requests(), "customerCreate"), true)
+
+	// requests without the Wave token are rejected
+	_, err = fake.Client("wrong-token").GetBusinessInfo(ctx)
+	assert.NotEqual(t, err, nil)
+}
+
+func TestEndToEndLogin(t *testing.T) {
+	ts := newTestServer(t)
+
+	token := ts.login(t, "manager@example.com", internal.ROLE_OFFICE_MANAGER)
+
+	status, _ := ts.post(t, "/api/handshake", token, jsondata{})
+	assert.Equal(t, status, http.StatusOK)
+
+	status, _ = ts.post(t, "/api/login", "", jsondata{"email": "manager@example.com", "password": "not the password"})
+	assert.Equal(t, status, http.StatusBadRequest)
+
+	status, body := ts.post(t, "/api/login", "", jsondata{"email": "manager@example.com", "password": TEST_PASSWORD})
+	assert.Equal(t, status, http.StatusOK)
+
+	var login struct {
+		User         data.User             `json:"user"`
+		BusinessInfo wave.WaveBusinessInfo `json:"businessInfo"`
+	}
+
+	decodeTestBody(t, body, &login)
+
+	assert.Equal(t, login.User.Email, "manager@example.com")
+	assert.Equal(t, login.BusinessInfo.BusinessID, FAKE_WAVE_BUSINESS_ID)
+	assert.Equal(t, login.BusinessInfo.ProductID, FAKE_WAVE_PRODUCT_ID)
+	assert.Equal(t, login.BusinessInfo.IdentityBusinessID, FAKE_WAVE_IDENTITY_BUSINESS_ID)
+}
+
+func TestEndToEndSchedule(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.login(t, "owner@example.com", internal.ROLE_OWNER)
+
+	week := time.Date(2025, 8, 11, 0, 0, 0, 0, time.UTC)
+
+	status, body := ts.post(t, "/api/schedule/create", token, jsondata{"startDay": week})
+	assert.Equal(t, status, http.StatusOK)
+
+	var created struct {
+		Schedule data.Schedule `json:"schedule"`
+	}
+
+	decodeTestBody(t, body, &created)
+	assert.NotEqual(t, created.Schedule.ID, 0)
+
+	// a second schedule for the same week is refused, whichever day of the week it starts on
+	status, _ = ts.post(t, "/api/schedule/create", token, jsondata{"startDay": week})
+	assert.Equal(t, status, http.StatusConflict)
+
+	status, _ = ts.post(t, "/api/schedule/create", token, jsondata{"startDay": week.AddDate(0, 0, 3)})
+	assert.Equal(t, status, http.StatusConflict)
+
+	customer := ts.wave.AddCustomer(wave.WaveCustomer{Name: "Ada Lovelace"})
+
+	status, _ = ts.post(t, "/api/scheduledCustomer/create", token, jsondata{
+		"waveCustomerID": customer.ID,
+		"startTime":      week.Add(9 * time.Hour),
+		"endTime":        week.Add(11 * time.Hour),
+		"dayOffset":      0,
+		"scheduleID":     created.Schedule.ID,
+	})
+
+	assert.Equal(t, status, http.StatusOK)
+
+	status, _ = ts.post(t, "/api/scheduledCustomer/create", token, jsondata{
+		"waveCustomerID": customer.ID,
+		"startTime":      week.Add(9 * time.Hour),
+		"endTime":        week.Add(11 * time.Hour),
+		"dayOffset":      0,
+		"scheduleID":     created.Schedule.ID + 1,
+	})
+
+	assert.Equal(t, status, http.StatusNotFound)
+
+	status, body = ts.post(t, "/api/scheduledCustomer/query", token, jsondata{"scheduleID": created.Schedule.ID})
+	assert.Equal(t, status, http.StatusOK)
+
+	var queried struct {
+		ScheduledCustomers []data.ScheduledCustomer `json:"scheduledCustomers"`
+	}
+
+	decodeTestBody(t, body, &queried)
+
+	assert.Equal(t, len(queried.ScheduledCustomers), 1)
+	assert.Equal(t, queried.ScheduledCustomers[0].CustomerID, customer.ID)
+
+	status, body = ts.post(t, "/api/audit/query", token, jsondata{
+		"entityType": data.AUDIT_ENTITY_SCHEDULED_CUSTOMER,
+		"pageNum":    1,
+		"pageSize":   10,
+	})
+
+	assert.Equal(t, status, http.StatusOK)
+
+	var audit struct {
+		AuditLog []data.AuditLogEntry `json:"auditLog"`
+	}
+
+	decodeTestBody(t, body, &audit)
+
+	assert.Equal(t, len(audit.AuditLog), 1)
+	assert.Equal(t, audit.AuditLog[0].Action, data.AUDIT_ACTION_CREATE)
+}
+
+func TestEndToEndWaveCustomer(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.login(t, "owner@example.com", internal.ROLE_OWNER)
+	cleanerToken := ts.login(t, "cleaner@example.com", internal.ROLE_CLEANER)
+
+	input := jsondata{"customerCreateInput": jsondata{"name": "Grace Hopper", "email": "grace@example.com"}}
+
+	status, _ := ts.post(t, "/api/wave/customer/create", cleanerToken, input)
+	assert.Equal(t, status, http.StatusForbidden)
+
+	status, _ = ts.post(t, "/api/wave/customer/create", token, input)
+	assert.Equal(t, status, http.StatusOK)
+
+	status, body := ts.post(t, "/api/wave/customers/queryAll", token, jsondata{"businessID": FAKE_WAVE_BUSINESS_ID})
+	assert.Equal(t, status, http.StatusOK)
+
+	var queried struct {
+		Customers []wave.WaveCustomer `json:"customers"`
+	}
+
+	decodeTestBody(t, body, &queried)
+
+	assert.Equal(t, len(queried.Customers), 1)
+	assert.Equal(t, queried.Customers[0].Name, "Grace Hopper")
+
+	customer, ok := ts.wave.Customer(queried.Customers[0].ID)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, customer.Email, "grace@example.com")
+
+	status, _ = ts.post(t, "/api/wave/customer/delete", token, jsondata{"customerID": customer.ID})
+	assert.Equal(t, status, http.StatusOK)
+
+	_, ok = ts.wave.Customer(customer.ID)
+	assert.Equal(t, ok, false)
+
+	// Wave refuses to delete the customer twice
+	status, _ = ts.post(t, "/api/wave/customer/delete", token, jsondata{"customerID": customer.ID})
+	assert.NotEqual(t, status, http.StatusOK)
+
+	assert.Equal(t, slices.Contains(ts.wave.Requests(), "customerCreate"), true)
+	assert.Equal(t, slices.Contains(ts.wave.Requests(), "customerDelete"), true)
+
+	// every call to Wave is audited, whether it succeeded or not
+	var outcomes []string
+	err := ts.db.SelectContext(context.Background(), &outcomes, `
+		SELECT outcome
+		  FROM audit_log
+		 WHERE entity_type = $1
+		 ORDER BY auditlogid
+	`, data.AUDIT_ENTITY_WAVE_CUSTOMER)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(outcomes), 3)
+	assert.Equal(t, outcomes[0], data.AUDIT_OUTCOME_SUCCEEDED)
+	assert.Equal(t, outcomes[1], data.AUDIT_OUTCOME_SUCCEEDED)
+	assert.Equal(t, outcomes[2], data.AUDIT_OUTCOME_FAILED)
+}
+
+func TestEndToEndRecurringService(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.login(t, "owner@example.com", internal.ROLE_OWNER)
+
+	week := time.Date(2025, 8, 11, 0, 0, 0, 0, time.UTC)
+	weekly := ts.wave.AddCustomer(wave.WaveCustomer{Name: "Ada Lovelace"})
+	monthly := ts.wave.AddCustomer(wave.WaveCustomer{Name: "Grace Hopper"})
+
+	status, body := ts.post(t, "/api/recurringService/create", token, jsondata{
+		"waveCustomerID": weekly.ID,
+		"weekday":        int(time.Monday),
+		"startTime":      "09:00",
+		"endTime":        "11:00",
+		"frequency":      data.FREQUENCY_WEEKLY,
+		"startDate":      week,
+	})
+
+	assert.Equal(t, status, http.StatusOK)
+
+	var created struct {
+		RecurringService data.RecurringService `json:"recurringService"`
+	}
+
+	decodeTestBody(t, body, &created)
+	assert.NotEqual(t, created.RecurringService.ID, 0)
+
+	status, _ = ts.post(t, "/api/recurringService/create", token, jsondata{
+		"waveCustomerID": monthly.ID,
+		"weekday":        int(time.Wednesday),
+		"startTime":      "13:00",
+		"endTime":        "12:00",
+		"frequency":      data.FREQUENCY_MONTHLY,
+		"weekOfMonth":    2,
+		"startDate":      week,
+	})
+
+	// ends before it starts
+	assert.Equal(t, status, http.StatusBadRequest)
+
+	status, _ = ts.post(t, "/api/recurringService/create", token, jsondata{
+		"waveCustomerID": monthly.ID,
+		"weekday":        int(time.Wednesday),
+		"startTime":      "13:00",
+		"endTime":        "15:00",
+		"frequency":      data.FREQUENCY_MONTHLY,
+		"weekOfMonth":    2,
+		"startDate":      week,
+	})
+
+	assert.Equal(t, status, http.StatusOK)
+
+	status, body = ts.post(t, "/api/recurringServices/query", token, jsondata{})
+	assert.Equal(t, status, http.StatusOK)
+
+	var queried struct {
+		RecurringServices []data.RecurringService `json:"recurringServices"`
+	}
+
+	decodeTestBody(t, body, &queried)
+	assert.Equal(t, len(queried.RecurringServices), 2)
+
+	status, body = ts.post(t, "/api/schedule/create", token, jsondata{"startDay": week})
+	assert.Equal(t, status, http.StatusOK)
+
+	var schedule struct {
+		Schedule           data.Schedule            `json:"schedule"`
+		ScheduledCustomers []data.ScheduledCustomer `json:"scheduledCustomers"`
+	}
+
+	decodeTestBody(t, body, &schedule)
+
+	// the weekly service on Monday and the monthly one on the second Wednesday of August
+	assert.Equal(t, len(schedule.ScheduledCustomers), 2)
+	assert.Equal(t, schedule.ScheduledCustomers[0].CustomerID, weekly.ID)
+	assert.Equal(t, schedule.ScheduledCustomers[0].DayOffset, 0)
+	assert.Equal(t, schedule.ScheduledCustomers[1].CustomerID, monthly.ID)
+	assert.Equal(t, schedule.ScheduledCustomers[1].DayOffset, 2)
+
+	// the next week only has the weekly service; it is entered by hand before populating
+	nextWeek := week.AddDate(0, 0, 7)
+
+	status, _ = ts.post(t, "/api/recurringService/delete", token, jsondata{"recurringServiceID": created.RecurringService.ID})
+	assert.Equal(t, status, http.StatusOK)
+
+	status, body = ts.post(t, "/api/schedule/create", token, jsondata{"startDay": nextWeek})
+	assert.Equal(t, status, http.StatusOK)
+
+	decodeTestBody(t, body, &schedule)
+	assert.Equal(t, len(schedule.ScheduledCustomers), 0)
+
+	status, _ = ts.post(t, "/api/recurringService/create", token, jsondata{
+		"waveCustomerID": weekly.ID,
+		"weekday":        int(time.Monday),
+		"startTime":      "09:00",
+		"endTime":        "11:00",
+		"frequency":      data.FREQUENCY_WEEKLY,
+		"startDate":      week,
+	})
+
+	assert.Equal(t, status, http.StatusOK)
+
+	status, _ = ts.post(t, "/api/scheduledCustomer/create", token, jsondata{
+		"waveCustomerID": weekly.ID,
+		"startTime":      nextWeek.Add(10 * time.Hour),
+		"endTime":        nextWeek.Add(12 * time.Hour),
+		"dayOffset":      0,
+		"scheduleID":     schedule.Schedule.ID,
+	})
+
+	assert.Equal(t, status, http.StatusOK)
+
+	status, body = ts.post(t, "/api/schedule/populate", token, jsondata{"scheduleID": schedule.Schedule.ID})
+	assert.Equal(t, status, http.StatusOK)
+
+	var populated struct {
+		ScheduledCustomers []data.ScheduledCustomer `json:"scheduledCustomers"`
+	}
+
+	decodeTestBody(t, body, &populated)
+	assert.Equal(t, len(populated.ScheduledCustomers), 0)
+
+	status, _ = ts.post(t, "/api/schedule/populate", token, jsondata{"scheduleID": schedule.Schedule.ID + 100})
+	assert.Equal(t, status, http.StatusNotFound)
+}
+
+func TestEndToEndScheduleCopy(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.login(t, "owner@example.com", internal.ROLE_OWNER)
+
+	// Toronto leaves daylight saving time between these weeks
+	week := time.Date(2025, 10, 27, 0, 0, 0, 0, time.UTC)
+	nextWeek := week.AddDate(0, 0, 7)
+
+	toronto, err := time.LoadLocation("America/Toronto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, body := ts.post(t, "/api/schedule/create", token, jsondata{"startDay": week})
+	assert.Equal(t, status, http.StatusOK)
+
+	var created struct {
+		Schedule data.Schedule `json:"schedule"`
+	}
+
+	decodeTestBody(t, body, &created)
+
+	ada := ts.wave.AddCustomer(wave.WaveCustomer{Name: "Ada Lovelace"})
+	grace := ts.wave.AddCustomer(wave.WaveCustomer{Name: "Grace Hopper"})
+
+	for i, customer := range []wave.WaveCustomer{ada, grace} {
+		status, _ = ts.post(t, "/api/scheduledCustomer/create", token, jsondata{
+			"waveCustomerID": customer.ID,
+			"startTime":      time.Date(2025, 10, 27, 9+2*i, 0, 0, 0, toronto),
+			"endTime":        time.Date(2025, 10, 27, 11+2*i, 0, 0, 0, toronto),
+			"dayOffset":      0,
+			"scheduleID":     created.Schedule.ID,
+		})
+
+		assert.Equal(t, status, http.StatusOK)
+	}
+
+	var copied struct {
+		ScheduleCopy data.ScheduleCopy `json:"scheduleCopy"`
+	}
+
+	// a dry run into a week without a schedule creates nothing
+	status, body = ts.post(t, "/api/schedule/copy", token, jsondata{"scheduleID": created.Schedule.ID, "startDay": nextWeek, "dryRun": true})
+	assert.Equal(t, status, http.StatusOK)
+
+	decodeTestBody(t, body, &copied)
+
+	assert.Equal(t, copied.ScheduleCopy.CreatedSchedule, true)
+	assert.Equal(t, copied.ScheduleCopy.Schedule.ID, 0)
+	assert.Equal(t, len(copied.ScheduleCopy.Entries), 2)
+	assert.Equal(t, copied.ScheduleCopy.Entries[0].Action, data.COPY_ACTION_CREATE)
+
+	status, body = ts.post(t, "/api/schedules/query", token, jsondata{})
+	assert.Equal(t, status, http.StatusOK)
+
+	var schedules struct {
+		Schedules []data.Schedule `json:"schedules"`
+	}
+
+	decodeTestBody(t, body, &schedules)
+	assert.Equal(t, len(schedules.Schedules), 1)
+
+	status, body = ts.post(t, "/api/schedule/copy", token, jsondata{"scheduleID": created.Schedule.ID, "startDay": nextWeek})
+	assert.Equal(t, status, http.StatusOK)
+
+	decodeTestBody(t, body, &copied)
+	target := copied.ScheduleCopy.Schedule
+
+	assert.Equal(t, copied.ScheduleCopy.CreatedSchedule, true)
+	assert.NotEqual(t, target.ID, 0)
+
+	// 9:00 in Toronto is 13:00 UTC before the change and 14:00 UTC after it
+	visit := copied.ScheduleCopy.Entries[0].Target
+	assert.NotEqual(t, visit.ID, 0)
+	assert.Equal(t, visit.DayOffset, 0)
+	assert.Equal(t, visit.StartTime.Time.Equal(time.Date(2025, 11, 3, 14, 0, 0, 0, time.UTC)), true)
+	assert.Equal(t, visit.EndTime.Time.Equal(time.Date(2025, 11, 3, 16, 0, 0, 0, time.UTC)), true)
+
+	// copying again meets the same visits
+	status, _ = ts.post(t, "/api/schedule/copy", token, jsondata{"scheduleID": created.Schedule.ID, "startDay": nextWeek, "conflictMode": data.COPY_CONFLICT_FAIL})
+	assert.Equal(t, status, http.StatusConflict)
+
+	status, body = ts.post(t, "/api/schedule/copy", token, jsondata{"scheduleID": created.Schedule.ID, "startDay": nextWeek, "conflictMode": data.COPY_CONFLICT_SKIP})
+	assert.Equal(t, status, http.StatusOK)
+
+	decodeTestBody(t, body, &copied)
+	assert.Equal(t, copied.ScheduleCopy.CreatedSchedule, false)
+	assert.Equal(t, copied.ScheduleCopy.Schedule.ID, target.ID)
+	assert.Equal(t, copied.ScheduleCopy.Entries[0].Action, data.COPY_ACTION_SKIP)
+	assert.Equal(t, copied.ScheduleCopy.Entries[1].Action, data.COPY_ACTION_SKIP)
+
+	// a visit moved by hand is put back by overwriting it
+	status, _ = ts.post(t, "/api/scheduledCustomer/edit", token, jsondata{
+		"scheduledCustomerID": visit.ID,
+		"waveCustomerID":      visit.CustomerID,
+		"startTime":           time.Date(2025, 11, 3, 12, 0, 0, 0, toronto),
+		"endTime":             time.Date(2025, 11, 3, 14, 0, 0, 0, toronto),
+		"dayOffset":           0,
+		"scheduleID":          target.ID,
+	})
+
+	assert.Equal(t, status, http.StatusOK)
+
+	status, body = ts.post(t, "/api/schedule/copy", token, jsondata{"scheduleID": created.Schedule.ID, "startDay": nextWeek, "conflictMode": data.COPY_CONFLICT_OVERWRITE})
+	assert.Equal(t, status, http.StatusOK)
+
+	decodeTestBody(t, body, &copied)
+	assert.Equal(t, copied.ScheduleCopy.Entries[0].Action, data.COPY_ACTION_OVERWRITE)
+	assert.Equal(t, copied.ScheduleCopy.Entries[0].Target.ID, visit.ID)
+	assert.Equal(t, copied.ScheduleCopy.Entries[0].Conflict.StartTime.Time.Equal(time.Date(2025, 11, 3, 17, 0, 0, 0, time.UTC)), true)
+	assert.Equal(t, copied.ScheduleCopy.Entries[0].Target.StartTime.Time.Equal(visit.StartTime.Time), true)
+
+	status, body = ts.post(t, "/api/scheduledCustomer/query", token, jsondata{"scheduleID": target.ID})
+	assert.Equal(t, status, http.StatusOK)
+
+	var queried struct {
+		ScheduledCustomers []data.ScheduledCustomer `json:"scheduledCustomers"`
+	}
+
+	decodeTestBody(t, body, &queried)
+	assert.Equal(t, len(queried.ScheduledCustomers), 2)
+
+	status, _ = ts.post(t, "/api/schedule/copy", token, jsondata{"scheduleID": created.Schedule.ID, "startDay": week})
+	assert.Equal(t, status, http.StatusBadRequest)
+
+	status, _ = ts.post(t, "/api/schedule/copy", token, jsondata{"scheduleID": created.Schedule.ID, "startDay": nextWeek, "conflictMode": "merge"})
+	assert.Equal(t, status, http.StatusBadRequest)
+
+	status, _ = ts.post(t, "/api/schedule/copy", token, jsondata{"scheduleID": created.Schedule.ID + 100, "startDay": nextWeek})
+	assert.Equal(t, status, http.StatusNotFound)
+}
+
+func TestEndToEndCrewAssignment(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.login(t, "owner@example.com", internal.ROLE_OWNER)
+
+	week := time.Date(2025, 8, 11, 0, 0, 0, 0, time.UTC)
+
+	status, body := ts.post(t, "/api/schedule/create", token, jsondata{"startDay": week})
+	assert.Equal(t, status, http.StatusOK)
+
+	var schedule struct {
+		Schedule data.Schedule `json:"schedule"`
+	}
+
+	decodeTestBody(t, body, &schedule)
+
+	// two visits on Monday morning that overlap by an hour, and one in the afternoon
+	visits := []data.ScheduledCustomer{}
+	for _, hour := range []int{9, 10, 14} {
+		customer := ts.wave.AddCustomer(wave.WaveCustomer{Name: "Customer"})
+
+		status, body = ts.post(t, "/api/scheduledCustomer/create", token, jsondata{
+			"waveCustomerID": customer.ID,
+			"startTime":      week.Add(time.Duration(hour) * time.Hour),
+			"endTime":        week.Add(time.Duration(hour+2) * time.Hour),
+			"dayOffset":      0,
+			"scheduleID":     schedule.Schedule.ID,
+		})
+
+		assert.Equal(t, status, http.StatusOK)
+
+		var created struct {
+			ScheduledCustomer data.ScheduledCustomer `json:"scheduledCustomer"`
+		}
+
+		decodeTestBody(t, body, &created)
+		visits = append(visits, created.ScheduledCustomer)
+	}
+
+	var employees []data.Employee
+	for _, name := range []string{"Marie Curie", "Pierre Curie"} {
+		status, body = ts.post(t, "/api/employee/create", token, jsondata{"name": name})
+		assert.Equal(t, status, http.StatusOK)
+
+		var created struct {
+			Employee data.Employee `json:"employee"`
+		}
+
+		decodeTestBody(t, body, &created)
+		employees = append(employees, created.Employee)
+	}
+
+	status, body = ts.post(t, "/api/crew/create", token, jsondata{"name": "Curies", "memberIDs": []int{employees[0].ID}})
+	assert.Equal(t, status, http.StatusOK)
+
+	var crew struct {
+		Crew data.Crew `json:"crew"`
+	}
+
+	decodeTestBody(t, body, &crew)
+
+	status, _ = ts.post(t, "/api/scheduledCustomer/assign", token, jsondata{"scheduledCustomerID": visits[0].ID, "crewIDs": []int{crew.Crew.ID}})
+	assert.Equal(t, status, http.StatusOK)
+
+	// the crew can not be at the overlapping visit as well
+	status, body = ts.post(t, "/api/scheduledCustomer/assign", token, jsondata{"scheduledCustomerID": visits[1].ID, "crewIDs": []int{crew.Crew.ID}})
+	assert.Equal(t, status, http.StatusConflict)
+
+	var conflict struct {
+		CrewID   int                    `json:"crewID"`
+		Conflict data.ScheduledCustomer `json:"conflict"`
+	}
+
+	decodeTestBody(t, body, &conflict)
+	assert.Equal(t, conflict.CrewID, crew.Crew.ID)
+	assert.Equal(t, conflict.Conflict.ID, visits[0].ID)
+
+	// nor can its members on their own
+	status, _ = ts.post(t, "/api/scheduledCustomer/assign", token, jsondata{"scheduledCustomerID": visits[1].ID, "employeeIDs": []int{employees[0].ID}})
+	assert.Equal(t, status, http.StatusConflict)
+
+	status, _ = ts.post(t, "/api/scheduledCustomer/assign", token, jsondata{"scheduledCustomerID": visits[1].ID, "employeeIDs": []int{employees[1].ID}})
+	assert.Equal(t, status, http.StatusOK)
+
+	// adding Pierre to the crew would put him at both morning visits
+	status, _ = ts.post(t, "/api/crew/edit", token, jsondata{"crewID": crew.Crew.ID, "name": "Curies", "memberIDs": []int{employees[0].ID, employees[1].ID}})
+	assert.Equal(t, status, http.StatusConflict)
+
+	status, _ = ts.post(t, "/api/scheduledCustomer/assign", token, jsondata{"scheduledCustomerID": visits[2].ID, "crewIDs": []int{crew.Crew.ID}})
+	assert.Equal(t, status, http.StatusOK)
+
+	// moving the afternoon visit into the morning double-books the crew
+	status, _ = ts.post(t, "/api/scheduledCustomer/edit", token, jsondata{
+		"scheduledCustomerID": visits[2].ID,
+		"waveCustomerID":      visits[2].CustomerID,
+		"startTime":           week.Add(10 * time.Hour),
+		"endTime":             week.Add(11 * time.Hour),
+		"dayOffset":           0,
+		"scheduleID":          schedule.Schedule.ID,
+	})
+
+	assert.Equal(t, status, http.StatusConflict)
+
+	status, body = ts.post(t, "/api/crew/scheduledCustomers/query", token, jsondata{"crewID": crew.Crew.ID, "scheduleID": schedule.Schedule.ID})
+	assert.Equal(t, status, http.StatusOK)
+
+	var crewWeek struct {
+		ScheduledCustomers []data.ScheduledCustomer `json:"scheduledCustomers"`
+	}
+
+	decodeTestBody(t, body, &crewWeek)
+	assert.Equal(t, len(crewWeek.ScheduledCustomers), 2)
+	assert.Equal(t, crewWeek.ScheduledCustomers[0].ID, visits[0].ID)
+	assert.Equal(t, crewWeek.ScheduledCustomers[1].ID, visits[2].ID)
+
+	status, body = ts.post(t, "/api/scheduledCustomer/assignments/query", token, jsondata{"scheduleID": schedule.Schedule.ID})
+	assert.Equal(t, status, http.StatusOK)
+
+	var assignments struct {
+		Assignments []data.ScheduledCustomerAssignment `json:"assignments"`
+	}
+
+	decodeTestBody(t, body, &assignments)
+	assert.Equal(t, len(assignments.Assignments), 3)
+	assert.Equal(t, assignments.Assignments[1].ScheduledCustomerID, visits[1].ID)
+	assert.Equal(t, len(assignments.Assignments[1].CrewIDs), 0)
+	assert.Equal(t, assignments.Assignments[1].EmployeeIDs[0], employees[1].ID)
+
+	status, _ = ts.post(t, "/api/crew/scheduledCustomers/query", token, jsondata{"crewID": crew.Crew.ID + 100, "scheduleID": schedule.Schedule.ID})
+	assert.Equal(t, status, http.StatusNotFound)
+}
+
+func TestEndToEndScheduleWarnings(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.login(t, "owner@example.com", internal.ROLE_OWNER)
+
+	week := time.Date(2025, 8, 11, 0, 0, 0, 0, time.UTC)
+
+	status, body := ts.post(t, "/api/schedule/create", token, jsondata{"startDay": week})
+	assert.Equal(t, status, http.StatusOK)
+
+	var schedule struct {
+		Schedule data.Schedule `json:"schedule"`
+	}
+
+	decodeTestBody(t, body, &schedule)
+
+	customer := ts.wave.AddCustomer(wave.WaveCustomer{Name: "Ada Lovelace"})
+
+	// ends before it starts
+	status, _ = ts.post(t, "/api/scheduledCustomer/create", token, jsondata{
+		"waveCustomerID": customer.ID,
+		"startTime":      week.Add(11 * time.Hour),
+		"endTime":        week.Add(9 * time.Hour),
+		"dayOffset":      0,
+		"scheduleID":     schedule.Schedule.ID,
+	})
+
+	assert.Equal(t, status, http.StatusBadRequest)
+
+	// schedules are a week long
+	status, _ = ts.post(t, "/api/scheduledCustomer/create", token, jsondata{
+		"waveCustomerID": customer.ID,
+		"startTime":      week.Add(9 * time.Hour),
+		"endTime":        week.Add(11 * time.Hour),
+		"dayOffset":      7,
+		"scheduleID":     schedule.Schedule.ID,
+	})
+
+	assert.Equal(t, status, http.StatusBadRequest)
+
+	var visits []data.ScheduledCustomer
+	var warnings [][]data.ScheduleWarning
+	for _, hour := range []int{9, 10} {
+		other := ts.wave.AddCustomer(wave.WaveCustomer{Name: "Customer"})
+
+		status, body = ts.post(t, "/api/scheduledCustomer/create", token, jsondata{
+			"waveCustomerID": other.ID,
+			"startTime":      week.Add(time.Duration(hour) * time.Hour),
+			"endTime":        week.Add(time.Duration(hour+2) * time.Hour),
+			"dayOffset":      0,
+			"scheduleID":     schedule.Schedule.ID,
+		})
+
+		assert.Equal(t, status, http.StatusOK)
+
+		var created struct {
+			ScheduledCustomer data.ScheduledCustomer `json:"scheduledCustomer"`
+			Warnings          []data.ScheduleWarning `json:"warnings"`
+		}
+
+		decodeTestBody(t, body, &created)
+		visits = append(visits, created.ScheduledCustomer)
+		warnings = append(warnings, created.Warnings)
+	}
+
+	assert.Equal(t, len(warnings[0]), 0)
+	assert.Equal(t, len(warnings[1]), 1)
+	assert.Equal(t, warnings[1][0].Code, data.WARNING_OVERLAP)
+
+	status, body = ts.post(t, "/api/schedule/warnings/query", token, jsondata{"scheduleID": schedule.Schedule.ID})
+	assert.Equal(t, status, http.StatusOK)
+
+	var scheduleWarnings struct {
+		Warnings []data.ScheduleWarning `json:"warnings"`
+	}
+
+	decodeTestBody(t, body, &scheduleWarnings)
+	assert.Equal(t, len(scheduleWarnings.Warnings), 1)
+	assert.Equal(t, scheduleWarnings.Warnings[0].ScheduledCustomerIDs[0], visits[0].ID)
+	assert.Equal(t, scheduleWarnings.Warnings[0].ScheduledCustomerIDs[1], visits[1].ID)
+
+	// moving the second visit to the afternoon clears the warning
+	status, body = ts.post(t, "/api/scheduledCustomer/edit", token, jsondata{
+		"scheduledCustomerID": visits[1].ID,
+		"waveCustomerID":      visits[1].CustomerID,
+		"startTime":           week.Add(14 * time.Hour),
+		"endTime":             week.Add(16 * time.Hour),
+		"dayOffset":           0,
+		"scheduleID":          schedule.Schedule.ID,
+	})
+
+	assert.Equal(t, status, http.StatusOK)
+
+	var edited struct {
+		Warnings []data.ScheduleWarning `json:"warnings"`
+	}
+
+	decodeTestBody(t, body, &edited)
+	assert.Equal(t, len(edited.Warnings), 0)
+
+	status, _ = ts.post(t, "/api/schedule/warnings/query", token, jsondata{"scheduleID": schedule.Schedule.ID + 100})
+	assert.Equal(t, status, http.StatusNotFound)
+}
+
+func TestEndToEndUserRole(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.login(t, "owner@example.com", internal.ROLE_OWNER)
+	otherToken := ts.login(t, "other@example.com", internal.ROLE_OWNER)
+
+	var ownerID, otherID int
+	err := ts.db.GetContext(context.Background(), &ownerID, `SELECT userid FROM users WHERE email = 'owner@example.com'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ts.db.GetContext(context.Background(), &otherID, `SELECT userid FROM users WHERE email = 'other@example.com'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, _ := ts.post(t, "/api/users/role/edit", token, jsondata{"userID": otherID, "role": internal.ROLE_CLEANER})
+	assert.Equal(t, status, http.StatusOK)
+
+	// the demoted owner's token still claims the owner role, but its session is gone
+	status, _ = ts.post(t, "/api/handshake", otherToken, jsondata{})
+	assert.Equal(t, status, http.StatusUnauthorized)
+
+	status, _ = ts.post(t, "/api/invitations/create", otherToken, jsondata{"email": "new@example.com", "role": internal.ROLE_OWNER})
+	assert.Equal(t, status, http.StatusUnauthorized)
+
+	// nobody would be left to manage users
+	status, _ = ts.post(t, "/api/users/role/edit", token, jsondata{"userID": ownerID, "role": internal.ROLE_OFFICE_MANAGER})
+	assert.Equal(t, status, http.StatusConflict)
+
+	status, _ = ts.post(t, "/api/handshake", token, jsondata{})
+	assert.Equal(t, status, http.StatusOK)
+
+	var audited int
+	err = ts.db.GetContext(context.Background(), &audited, `SELECT count(*) FROM audit_log WHERE entity_type = $1`, data.AUDIT_ENTITY_USER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, audited, 1)
+}
+
+type failingMailer struct{}
+
+func (failingMailer) Send(to string, subject string, body string) error {
+	return errors.New("mail server is down")
+}
+
+func TestEndToEndPasswordReset(t *testing.T) {
+	ts := newTestServer(t)
+	ts.login(t, "owner@example.com", internal.ROLE_OWNER)
+
+	status, _ := ts.post(t, "/api/password/forgot", "", jsondata{"email": "owner@example.com"})
+	assert.Equal(t, status, http.StatusOK)
+
+	ts.app.backgroundTasks.Wait()
+
+	messages := ts.mailer.Messages()
+	assert.Equal(t, len(messages), 1)
+	assert.Equal(t, messages[0].To, "owner@example.com")
+
+	_, link, found := strings.Cut(messages[0].Body, "/reset-password?token=")
+	assert.Equal(t, found, true)
+
+	token, err := url.QueryUnescape(strings.Fields(link)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// unknown emails get the same answer, without an email
+	status, _ = ts.post(t, "/api/password/forgot", "", jsondata{"email": "nobody@example.com"})
+	assert.Equal(t, status, http.StatusOK)
+
+	ts.app.backgroundTasks.Wait()
+	assert.Equal(t, len(ts.mailer.Messages()), 1)
+
+	newPassword := "a brand new password"
+	status, _ = ts.post(t, "/api/password/reset", "", jsondata{"token": token, "password": newPassword})
+	assert.Equal(t, status, http.StatusOK)
+
+	status, _ = ts.post(t, "/api/login", "", jsondata{"email": "owner@example.com", "password": newPassword})
+	assert.Equal(t, status, http.StatusOK)
+
+	// a broken mailer does not tell which emails have accounts either
+	ts.app.mailer = failingMailer{}
+
+	status, _ = ts.post(t, "/api/password/forgot", "", jsondata{"email": "owner@example.com"})
+	assert.Equal(t, status, http.StatusOK)
+
+	ts.app.backgroundTasks.Wait()
+}
+
+func TestEndToEndLoginMFAThrottle(t *testing.T) {
+	ts := newTestServer(t)
+	ts.login(t, "owner@example.com", internal.ROLE_OWNER)
+
+	_, err := ts.db.ExecContext(context.Background(), `
+		UPDATE users
+		SET    totp_secret = 'GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ'
+		     , totp_enabled = true
+		WHERE email = 'owner@example.com'
+	`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	challenge := func() string {
+		status, body := ts.post(t, "/api/login", "", jsondata{"email": "owner@example.com", "password": TEST_PASSWORD})
+		assert.Equal(t, status, http.StatusOK)
+
+		var login struct {
+			MFAToken string `json:"mfaToken"`
+		}
+
+		decodeTestBody(t, body, &login)
+
+		return login.MFAToken
+	}
+
+	// a fresh challenge for every guess does not reset the count of wrong codes
+	for range internal.ACCOUNT_LOGIN_THROTTLE.FreeAttempts {
+		status, _ := ts.post(t, "/api/login/mfa", "", jsondata{"mfaToken": challenge(), "code": "000000"})
+		assert.Equal(t, status, http.StatusBadRequest)
+	}
+
+	status, _ := ts.post(t, "/api/login", "", jsondata{"email": "owner@example.com", "password": TEST_PASSWORD})
+	assert.Equal(t, status, http.StatusTooManyRequests)
+
+	var failures int
+	err = ts.db.GetContext(context.Background(), &failures, `
+		SELECT count(*)
+		  FROM login_events
+		 WHERE email = 'owner@example.com'
+		   AND NOT succeeded
+	`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, failures, internal.ACCOUNT_LOGIN_THROTTLE.FreeAttempts)
+}
+
+func TestEndToEndLoginThrottleParallel(t *testing.T) {
+	ts := newTestServer(t)
+	ts.login(t, "owner@example.com", internal.ROLE_OWNER)
+
+	// parallel guesses are serialized, so only the free attempts get to check the password
+	attempts := 2 * internal.ACCOUNT_LOGIN_THROTTLE.FreeAttempts
+	statuses := make(chan int, attempts)
+
+	var wg sync.WaitGroup
+	for range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			body := strings.NewReader(`{"email": "owner@example.com", "password": "not the password"}`)
+			rs, err := ts.Client().Post(ts.URL+"/api/login", "application/json", body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			rs.Body.Close()
+			statuses <- rs.StatusCode
+		}()
+	}
+
+	wg.Wait()
+	close(statuses)
+
+	rejected := 0
+	for status := range statuses {
+		if status == http.StatusBadRequest {
+			rejected++
+		} else {
+			assert.Equal(t, status, http.StatusTooManyRequests)
+		}
+	}
+
+	assert.Equal(t, rejected, internal.ACCOUNT_LOGIN_THROTTLE.FreeAttempts)
+}
+
+func TestEndToEndCrossUserAccess(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.login(t, "owner@example.com", internal.ROLE_OWNER)
+	otherToken := ts.login(t, "other@example.com", internal.ROLE_OWNER)
+
+	week := time.Date(2025, 8, 11, 0, 0, 0, 0, time.UTC)
+
+	var schedules []data.Schedule
+	for _, userToken := range []string{token, otherToken} {
+		status, body := ts.post(t, "/api/schedule/create", userToken, jsondata{"startDay": week})
+		assert.Equal(t, status, http.StatusOK)
+
+		var created struct {
+			Schedule data.Schedule `json:"schedule"`
+		}
+
+		decodeTestBody(t, body, &created)
+		schedules = append(schedules, created.Schedule)
+	}
+
+	schedule, otherSchedule := schedules[0], schedules[1]
+	customer := ts.wave.AddCustomer(wave.WaveCustomer{Name: "Ada Lovelace"})
+
+	status, body := ts.post(t, "/api/scheduledCustomer/create", token, jsondata{
+		"waveCustomerID": customer.ID,
+		"startTime":      week.Add(9 * time.Hour),
+		"endTime":        week.Add(11 * time.Hour),
+		"dayOffset":      0,
+		"scheduleID":     schedule.ID,
+	})
+
+	assert.Equal(t, status, http.StatusOK)
+
+	var created struct {
+		ScheduledCustomer data.ScheduledCustomer `json:"scheduledCustomer"`
+	}
+
+	decodeTestBody(t, body, &created)
+	visit := created.ScheduledCustomer
+
+	var ownerID int
+	err := ts.db.GetContext(context.Background(), &ownerID, `SELECT userid FROM users WHERE email = 'owner@example.com'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		body   jsondata
+		status int
+	}{
+		{"list schedules", "/api/schedules/query", jsondata{"userID": ownerID}, http.StatusForbidden},
+		{"query schedule", "/api/schedule/query", jsondata{"scheduleID": schedule.ID}, http.StatusNotFound},
+		{"edit schedule", "/api/schedule/edit", jsondata{"scheduleID": schedule.ID, "startDay": week.AddDate(0, 0, 14)}, http.StatusNotFound},
+		{"delete schedule", "/api/schedule/delete", jsondata{"scheduleID": schedule.ID}, http.StatusNotFound},
+		{"export schedule", "/api/schedule/pdf", jsondata{"scheduleID": schedule.ID, "businessID": FAKE_WAVE_BUSINESS_ID}, http.StatusNotFound},
+		{"query scheduled customers", "/api/scheduledCustomer/query", jsondata{"scheduleID": schedule.ID}, http.StatusNotFound},
+		{"create scheduled customer", "/api/scheduledCustomer/create", jsondata{
+			"waveCustomerID": customer.ID,
+			"startTime":      week.Add(13 * time.Hour),
+			"endTime":        week.Add(15 * time.Hour),
+			"dayOffset":      0,
+			"scheduleID":     schedule.ID,
+		}, http.StatusNotFound},
+		{"edit scheduled customer", "/api/scheduledCustomer/edit", jsondata{
+			"scheduledCustomerID": visit.ID,
+			"waveCustomerID":      customer.ID,
+			"startTime":           week.Add(13 * time.Hour),
+			"endTime":             week.Add(15 * time.Hour),
+			"dayOffset":           0,
+			"scheduleID":          schedule.ID,
+		}, http.StatusNotFound},
+		// through a schedule of their own
+		{"edit scheduled customer through own schedule", "/api/scheduledCustomer/edit", jsondata{
+			"scheduledCustomerID": visit.ID,
+			"waveCustomerID":      customer.ID,
+			"startTime":           week.Add(13 * time.Hour),
+			"endTime":             week.Add(15 * time.Hour),
+			"dayOffset":           0,
+			"scheduleID":          otherSchedule.ID,
+		}, http.StatusNotFound},
+		{"delete scheduled customer", "/api/scheduledCustomer/delete", jsondata{"scheduledCustomerID": visit.ID}, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _ := ts.post(t, tt.path, otherToken, tt.body)
+			assert.Equal(t, status, tt.status)
+		})
+	}
+
+	// the owner's schedule is untouched
+	status, body = ts.post(t, "/api/scheduledCustomer/query", token, jsondata{"scheduleID": schedule.ID})
+	assert.Equal(t, status, http.StatusOK)
+
+	var visits struct {
+		ScheduledCustomers []data.ScheduledCustomer `json:"scheduledCustomers"`
+	}
+
+	decodeTestBody(t, body, &visits)
+	assert.Equal(t, len(visits.ScheduledCustomers), 1)
+	assert.Equal(t, visits.ScheduledCustomers[0].StartTime.Time.Equal(visit.StartTime.Time), true)
+}
+
+func TestEndToEndAccountStaff(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.login(t, "owner@example.com", internal.ROLE_OWNER)
+	otherToken := ts.login(t, "other@example.com", internal.ROLE_OWNER)
+
+	// invites someone into the owner's account, and logs them in once they registered
+	join := func(email string, role string) string {
+		t.Helper()
+
+		status, _ := ts.post(t, "/api/invitations/create", token, jsondata{"email": email, "role": role})
+		assert.Equal(t, status, http.StatusOK)
+
+		ts.app.backgroundTasks.Wait()
+
+		messages := ts.mailer.Messages()
+		_, link, found := strings.Cut(messages[len(messages)-1].Body, "/register?token=")
+		assert.Equal(t, found, true)
+
+		invitationToken, err := url.QueryUnescape(strings.Fields(link)[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		status, _ = ts.post(t, "/api/register", "", jsondata{"token": invitationToken, "name": "Staff", "password": TEST_PASSWORD})
+		assert.Equal(t, status, http.StatusOK)
+
+		status, body := ts.post(t, "/api/login", "", jsondata{"email": email, "password": TEST_PASSWORD})
+		assert.Equal(t, status, http.StatusOK)
+
+		var login struct {
+			JWT string `json:"jwt"`
+		}
+
+		decodeTestBody(t, body, &login)
+		return login.JWT
+	}
+
+	managerToken := join("manager@example.com", internal.ROLE_OFFICE_MANAGER)
+	cleanerToken := join("cleaner@example.com", internal.ROLE_CLEANER)
+
+	week := time.Date(2025, 8, 11, 0, 0, 0, 0, time.UTC)
+
+	// the office manager creates a schedule in the owner's account
+	status, _ := ts.post(t, "/api/schedule/create", managerToken, jsondata{"startDay": week})
+	assert.Equal(t, status, http.StatusOK)
+
+	status, _ = ts.post(t, "/api/schedule/create", cleanerToken, jsondata{"startDay": week.AddDate(0, 0, 7)})
+	assert.Equal(t, status, http.StatusForbidden)
+
+	countSchedules := func(userToken string) int {
+		t.Helper()
+
+		status, body := ts.post(t, "/api/schedules/query", userToken, jsondata{})
+		assert.Equal(t, status, http.StatusOK)
+
+		var queried struct {
+			Schedules []data.Schedule `json:"schedules"`
+		}
+
+		decodeTestBody(t, body, &queried)
+		return len(queried.Schedules)
+	}
+
+	assert.Equal(t, countSchedules(token), 1)
+	assert.Equal(t, countSchedules(managerToken), 1)
+	assert.Equal(t, countSchedules(cleanerToken), 1)
+	assert.Equal(t, countSchedules(otherToken), 0)
+
+	// the owner holds the account, so they can only be deleted once their staff is
+	var ownerID int
+	err := ts.db.Get(&ownerID, `SELECT userid FROM users WHERE email = 'owner@example.com'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, _ = ts.post(t, "/api/users/delete", otherToken, jsondata{"userID": ownerID})
+	assert.Equal(t, status, http.StatusConflict)
+}
+
+func TestEndToEndEmployeeAvailability(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.login(t, "owner@example.com", internal.ROLE_OWNER)
+
+	week := time.Date(2025, 8, 11, 0, 0, 0, 0, time.UTC)
+
+	status, body := ts.post(t, "/api/schedule/create", token, jsondata{"startDay": week})
+	assert.Equal(t, status, http.StatusOK)
+
+	var schedule struct {
+		Schedule data.Schedule `json:"schedule"`
+	}
+
+	decodeTestBody(t, body, &schedule)
+
+	// at 5:00 and 9:00 on Monday in Toronto, the business time zone of the test server
+	visits := []data.ScheduledCustomer{}
+	for _, hour := range []int{9, 13} {
+		customer := ts.wave.AddCustomer(wave.WaveCustomer{Name: "Customer"})
+
+		status, body = ts.post(t, "/api/scheduledCustomer/create", token, jsondata{
+			"waveCustomerID": customer.ID,
+			"startTime":      week.Add(time.Duration(hour) * time.Hour),
+			"endTime":        week.Add(time.Duration(hour+2) * time.Hour),
+			"dayOffset":      0,
+			"scheduleID":     schedule.Schedule.ID,
+		})
+
+		assert.Equal(t, status, http.StatusOK)
+
+		var created struct {
+			ScheduledCustomer data.ScheduledCustomer `json:"scheduledCustomer"`
+		}
+
+		decodeTestBody(t, body, &created)
+		visits = append(visits, created.ScheduledCustomer)
+	}
+
+	employees := []data.Employee{}
+	for _, active := range []bool{true, false} {
+		status, body = ts.post(t, "/api/employee/create", token, jsondata{
+			"name":   "Marie Curie",
+			"active": active,
+			"availability": []jsondata{
+				{"weekday": int(time.Monday), "startTime": "08:00", "endTime": "12:00"},
+			},
+		})
+
+		assert.Equal(t, status, http.StatusOK)
+
+		var created struct {
+			Employee data.Employee `json:"employee"`
+		}
+
+		decodeTestBody(t, body, &created)
+		employees = append(employees, created.Employee)
+	}
+
+	status, body = ts.post(t, "/api/scheduledCustomer/assign", token, jsondata{"scheduledCustomerID": visits[0].ID, "employeeIDs": []int{employees[0].ID}})
+	assert.Equal(t, status, http.StatusConflict)
+
+	var unavailable struct {
+		EmployeeID int  `json:"employeeID"`
+		Inactive   bool `json:"inactive"`
+	}
+
+	decodeTestBody(t, body, &unavailable)
+	assert.Equal(t, unavailable.EmployeeID, employees[0].ID)
+	assert.Equal(t, unavailable.Inactive, false)
+
+	status, _ = ts.post(t, "/api/scheduledCustomer/assign", token, jsondata{"scheduledCustomerID": visits[1].ID, "employeeIDs": []int{employees[0].ID}})
+	assert.Equal(t, status, http.StatusOK)
+
+	status, body = ts.post(t, "/api/scheduledCustomer/assign", token, jsondata{"scheduledCustomerID": visits[1].ID, "employeeIDs": []int{employees[1].ID}})
+	assert.Equal(t, status, http.StatusConflict)
+
+	decodeTestBody(t, body, &unavailable)
+	assert.Equal(t, unavailable.EmployeeID, employees[1].ID)
+	assert.Equal(t, unavailable.Inactive, true)
+
+	// inactive employees can not join a crew either
+	status, _ = ts.post(t, "/api/crew/create", token, jsondata{"name": "Curies", "memberIDs": []int{employees[1].ID}})
+	assert.Equal(t, status, http.StatusConflict)
+}
+
+func TestEndToEndUserEditVerification(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.login(t, "owner@example.com", internal.ROLE_OWNER)
+
+	_, err := ts.db.ExecContext(context.Background(), `
+		UPDATE users
+		SET    email_verified_at = NULL
+		WHERE email = 'owner@example.com'
+	`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// renaming an unverified user does not send another link
+	status, _ := ts.post(t, "/api/users/edit", token, jsondata{"name": "Ada Lovelace"})
+	assert.Equal(t, status, http.StatusOK)
+
+	ts.app.backgroundTasks.Wait()
+	assert.Equal(t, len(ts.mailer.Messages()), 0)
+
+	status, _ = ts.post(t, "/api/users/edit", token, jsondata{"email": "owner@example.com"})
+	assert.Equal(t, status, http.StatusOK)
+
+	ts.app.backgroundTasks.Wait()
+	assert.Equal(t, len(ts.mailer.Messages()), 0)
+
+	status, _ = ts.post(t, "/api/users/edit", token, jsondata{"email": "ada@example.com"})
+	assert.Equal(t, status, http.StatusOK)
+
+	ts.app.backgroundTasks.Wait()
+
+	messages := ts.mailer.Messages()
+	assert.Equal(t, len(messages), 1)
+	assert.Equal(t, messages[0].To, "ada@example.com")
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+// Emails a user a link for verifying their current email.
+// The email is sent once the request's transaction commits, so that a rolled back request sends no
+// link for a token that does not exist; failing to send it is only logged.
+// This should only be called from routes wrapped by transaction.
+func (app *application) sendEmailVerification(r *http.Request, tx db.WriteDBExecutor, user *data.User) error {
+	token, err := data.CreateEmailVerificationToken(r.Context(), tx, user.ID, user.Email)
+	if err != nil {
+		return errors.Wrap(err, "CreateEmailVerificationToken")
+	}
+
+	link := fmt.Sprintf("%v/verify-email?token=%v", app.config.frontendURL, url.QueryEscape(token))
+	message := fmt.Sprintf(
+		"Hi %v,\n\nUse the link below to verify your email for Prime Shine Accounting. It expires in %v.\n\n%v\n",
+		user.Name,
+		data.EMAIL_VERIFICATION_TOKEN_DURATION,
+		link,
+	)
+
+	app.contextAfterCommit(r, func() {
+		err := app.mailer.Send(user.Email, "Verify your email", message)
+		if err != nil {
+			app.logError(r, errors.Wrap(err, "mailer.Send"))
+		}
+	})
+
+	return nil
+}
+
+type verifyEmailBody struct {
+	Token string `json:"token"`
+}
+
+// Route for verifying an email with the link sent to it.
+func (app *application) verifyEmail(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body verifyEmailBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if body.Token == "" {
+		app.errorResponse(w, r, http.StatusBadRequest, "Token is required.")
+		return
+	}
+
+	tx := app.contextGetTx(r)
+
+	err = data.VerifyUserEmail(r.Context(), tx, body.Token)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.errorResponse(w, r, http.StatusBadRequest, "Email verification link is invalid or has expired.")
+		return
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "VerifyUserEmail")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := jsondata{"success": true}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "writeJSON"))
+	}
+}
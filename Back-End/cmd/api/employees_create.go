@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"prime-shine-api/internal/data"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type createEmployeeBody struct {
+	Name         string                      `json:"name"`
+	Email        pgtype.Text                 `json:"email"`
+	Phone        pgtype.Text                 `json:"phone"`
+	Active       *bool                       `json:"active"` // defaults to true
+	Availability []data.EmployeeAvailability `json:"availability"`
+}
+
+func (body createEmployeeBody) employee() data.Employee {
+	employee := data.Employee{
+		Name:         body.Name,
+		Email:        body.Email,
+		Phone:        body.Phone,
+		Active:       true,
+		Availability: body.Availability,
+	}
+
+	if body.Active != nil {
+		employee.Active = *body.Active
+	}
+
+	return employee
+}
+
+// Route for creating an employee.
+func (app *application) createEmployee(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body createEmployeeBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	accountID := app.contextGetAccountID(r)
+
+	tx := app.contextGetTx(r)
+
+	employee, err := data.CreateEmployee(r.Context(), tx, accountID, body.employee())
+	if err != nil {
+		err = errors.Wrap(err, "CreateEmployee")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = app.recordAudit(tx, r, data.AUDIT_ACTION_CREATE, data.AUDIT_ENTITY_EMPLOYEE, employee.ID, nil, employee)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+		return
+	}
+
+	data := jsondata{"employee": employee}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		err = errors.Wrap(err, "writeJSON")
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type deleteEmployeeBody struct {
+	EmployeeID int `json:"employeeID"`
+}
+
+// Route for deleting an employee.
+// Employees who left are better made inactive, so that their past visits still show them.
+func (app *application) deleteEmployee(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body deleteEmployeeBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	accountID := app.contextGetAccountID(r)
+
+	tx := app.contextGetTx(r)
+
+	query := db.Where(data.EmployeeColumns.ID.Eq(body.EmployeeID))
+	before, err := data.FindOneEmployee(r.Context(), tx, accountID, query)
+	if err != nil {
+		err = errors.Wrap(err, "FindOneEmployee")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	success, err := data.DeleteEmployee(r.Context(), tx, accountID, body.EmployeeID)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, "Could not find employee.")
+		return
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "DeleteEmployee")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = app.recordAudit(tx, r, data.AUDIT_ACTION_DELETE, data.AUDIT_ENTITY_EMPLOYEE, body.EmployeeID, before, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+		return
+	}
+
+	data := jsondata{"success": success}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		err = errors.Wrap(err, "writeJSON")
+		app.serverErrorResponse(w, r, err)
+	}
+}
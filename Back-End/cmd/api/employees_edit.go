@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type editEmployeeBody struct {
+	EmployeeID int `json:"employeeID"`
+	createEmployeeBody
+}
+
+// Route for editing an employee.
+func (app *application) editEmployee(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body editEmployeeBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	accountID := app.contextGetAccountID(r)
+
+	tx := app.contextGetTx(r)
+
+	query := db.Where(data.EmployeeColumns.ID.Eq(body.EmployeeID))
+	before, err := data.FindOneEmployee(r.Context(), tx, accountID, query)
+	if err != nil {
+		err = errors.Wrap(err, "FindOneEmployee")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if before == nil {
+		app.notFoundResponse(w, r, "Could not find employee.")
+		return
+	}
+
+	employee := body.employee()
+	employee.ID = body.EmployeeID
+
+	editedEmployee, err := data.EditEmployee(r.Context(), tx, accountID, employee)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, "Could not find employee.")
+		return
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "EditEmployee")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = app.recordAudit(tx, r, data.AUDIT_ACTION_EDIT, data.AUDIT_ENTITY_EMPLOYEE, editedEmployee.ID, before, editedEmployee)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+		return
+	}
+
+	data := jsondata{"employee": editedEmployee}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		err = errors.Wrap(err, "writeJSON")
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/http"
+	"prime-shine-api/internal/data"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+// Route for querying employees.
+func (app *application) queryEmployees(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	accountID := app.contextGetAccountID(r)
+
+	employees, err := data.QueryEmployees(r.Context(), app.db, accountID)
+	if err != nil {
+		err = errors.Wrap(err, "QueryEmployees")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data := jsondata{"employees": employees}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		err = errors.Wrap(err, "writeJSON")
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"prime-shine-api/internal/mocks"
+	"prime-shine-api/internal/wave"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	FAKE_WAVE_TOKEN                = "fake-wave-token"
+	FAKE_WAVE_BUSINESS_ID          = "QnVzaW5lc3M6ZmFrZQ=="
+	FAKE_WAVE_PRODUCT_ID           = "UHJvZHVjdDpmYWtl"
+	FAKE_WAVE_IDENTITY_BUSINESS_ID = "fake-identity-business"
+)
+
+// A stand-in for both Wave APIs, keeping customers, invoices and payments in memory.
+// GraphQL operations are recognized by the fields the wave package queries,
+// which is enough to drive the API end to end without reaching the real Wave.
+type fakeWave struct {
+	mu        sync.Mutex
+	server    *httptest.Server
+	customers map[string]wave.WaveCustomer
+	invoices  map[string]wave.WaveInvoice
+	payments  map[string][]wave.WaveInvoicePayment // keyed by internal invoice ID
+	nextID    int
+	requests  []string
+}
+
+// Starts a fake Wave that lives as long as the test.
+func newFakeWave(t *testing.T) *fakeWave {
+	t.Helper()
+
+	fake := &fakeWave{
+		customers: map[string]wave.WaveCustomer{},
+		invoices:  map[string]wave.WaveInvoice{},
+		payments:  map[string][]wave.WaveInvoicePayment{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /graphql/public", fake.graphQL)
+	mux.HandleFunc("/businesses/", fake.business)
+
+	fake.server = httptest.NewServer(fake.authorize(mux))
+	t.Cleanup(fake.server.Close)
+
+	return fake
+}
+
+// Returns a Wave client that talks to the fake, authorized with the given token.
+func (f *fakeWave) Client(token string) *wave.Client {
+	return wave.NewClient(
+		f.server.URL+"/graphql/public",
+		f.server.URL+"/businesses",
+		wave.StaticToken(token),
+		f.server.Client(),
+		mocks.Logger(),
+		wave.DefaultClientOptions(),
+	)
+}
+
+func (f *fakeWave) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+FAKE_WAVE_TOKEN {
+			http.Error(w, `{"errors": [{"message": "Unauthorized"}]}`, http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Adds a customer, as if it was created in Wave directly.
+func (f *fakeWave) AddCustomer(customer wave.WaveCustomer) wave.WaveCustomer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if customer.ID == "" {
+		customer.ID = f.newID("Customer")
+	}
+
+	f.customers[customer.ID] = customer
+	return customer
+}
+
+// Returns a customer, and whether it exists.
+func (f *fakeWave) Customer(customerID string) (wave.WaveCustomer, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	customer, ok := f.customers[customerID]
+	return customer, ok
+}
+
+// Returns the GraphQL operations and business API requests received so far, in order.
+func (f *fakeWave) Requests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.requests)
+}
+
+func (f *fakeWave) newID(kind string) string {
+	f.nextID++
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%v:%d", kind, f.nextID)))
+}
+
+func (f *fakeWave) graphQL(w http.ResponseWriter, r *http.Request) {
+	var body wave.WaveGraphQLBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	input, _ := body.Variables["input"].(map[string]any)
+
+	var operation string
+	var data any
+
+	switch {
+	case strings.Contains(body.Query, "businesses {"):
+		operation = "businesses"
+		data = f.businessesData()
+	case strings.Contains(body.Query, "customerCreate("):
+		operation = "customerCreate"
+		customer := customerFromInput(wave.WaveCustomer{ID: f.newID("Customer")}, input)
+		f.customers[customer.ID] = customer
+		data = mutationResult(operation, true)
+	case strings.Contains(body.Query, "customerPatch("):
+		operation = "customerPatch"
+		customer, ok := f.customers[fmt.Sprint(input["id"])]
+		if ok {
+			f.customers[customer.ID] = customerFromInput(customer, input)
+		}
+		data = mutationResult(operation, ok)
+	case strings.Contains(body.Query, "customerDelete("):
+		operation = "customerDelete"
+		_, ok := f.customers[fmt.Sprint(input["id"])]
+		delete(f.customers, fmt.Sprint(input["id"]))
+		data = mutationResult(operation, ok)
+	case strings.Contains(body.Query, "customers("):
+		operation = "customers"
+		data = f.customersData(body.Variables)
+	case strings.Contains(body.Query, "customer(id"):
+		operation = "customer"
+		customer := f.customers[fmt.Sprint(body.Variables["customerId"])]
+		data = map[string]any{"business": map[string]any{"customer": customer}}
+	case strings.Contains(body.Query, "invoiceCreate("):
+		operation = "invoiceCreate"
+		invoice := wave.WaveInvoice{
+			ID:         f.newID("Invoice"),
+			InternalID: fmt.Sprint(f.nextID),
+			Status:     "DRAFT",
+			Memo:       fmt.Sprint(input["memo"]),
+			Customer:   f.customers[fmt.Sprint(input["customerId"])],
+		}
+		f.invoices[invoice.ID] = invoice
+		data = mutationResult(operation, true)
+	case strings.Contains(body.Query, "invoicePatch("):
+		operation = "invoicePatch"
+		invoice, ok := f.invoices[fmt.Sprint(input["id"])]
+		if memo, hasMemo := input["memo"]; ok && hasMemo {
+			invoice.Memo = fmt.Sprint(memo)
+			f.invoices[invoice.ID] = invoice
+		}
+		data = mutationResult(operation, ok)
+	case strings.Contains(body.Query, "invoiceDelete("):
+		operation = "invoiceDelete"
+		_, ok := f.invoices[fmt.Sprint(input["invoiceId"])]
+		delete(f.invoices, fmt.Sprint(input["invoiceId"]))
+		data = mutationResult(operation, ok)
+	case strings.Contains(body.Query, "invoices("):
+		operation = "invoices"
+		data = f.invoicesData()
+	case strings.Contains(body.Query, "invoice(id"):
+		operation = "invoice"
+		invoice := f.invoices[fmt.Sprint(body.Variables["invoiceId"])]
+		data = map[string]any{"business": map[string]any{"invoice": invoice}}
+	default:
+		f.requests = append(f.requests, "unknown")
+		writeFakeWaveJSON(w, map[string]any{"errors": []map[string]any{{"message": "fake Wave does not support this query"}}})
+		return
+	}
+
+	f.requests = append(f.requests, operation)
+	writeFakeWaveJSON(w, map[string]any{"data": data})
+}
+
+func (f *fakeWave) businessesData() map[string]any {
+	business := map[string]any{
+		"id":   FAKE_WAVE_BUSINESS_ID,
+		"name": wave.WAVE_BUSINESS_NAME,
+		"products": map[string]any{
+			"edges": []map[string]any{
+				{"node": map[string]any{"id": FAKE_WAVE_PRODUCT_ID, "name": wave.WAVE_CLEANING_PRODUCT_NAME}},
+			},
+		},
+	}
+
+	return map[string]any{
+		"businesses": map[string]any{
+			"edges": []map[string]any{{"node": business}},
+		},
+	}
+}
+
+func (f *fakeWave) customersData(variables wave.WaveGraphQLVariables) map[string]any {
+	customers := []wave.WaveCustomer{}
+	for _, customer := range f.customers {
+		customers = append(customers, customer)
+	}
+
+	slices.SortFunc(customers, func(a, b wave.WaveCustomer) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	pageNum := max(jsonInt(variables["pageNum"]), 1)
+	pageSize := max(jsonInt(variables["pageSize"]), 1)
+	totalPages := max((len(customers)+pageSize-1)/pageSize, 1)
+
+	start := min((pageNum-1)*pageSize, len(customers))
+	end := min(start+pageSize, len(customers))
+
+	edges := []map[string]any{}
+	for _, customer := range customers[start:end] {
+		edges = append(edges, map[string]any{"node": customer})
+	}
+
+	return map[string]any{
+		"business": map[string]any{
+			"customers": map[string]any{
+				"pageInfo": wave.WavePageInfoData{CurrentPage: pageNum, TotalPages: totalPages, TotalCount: len(customers)},
+				"edges":    edges,
+			},
+		},
+	}
+}
+
+func (f *fakeWave) invoicesData() map[string]any {
+	edges := []map[string]any{}
+	for _, invoice := range f.invoices {
+		edges = append(edges, map[string]any{"node": invoice})
+	}
+
+	return map[string]any{
+		"business": map[string]any{
+			"invoices": map[string]any{
+				"pageInfo": wave.WavePageInfoData{CurrentPage: 1, TotalPages: 1, TotalCount: len(edges)},
+				"edges":    edges,
+			},
+		},
+	}
+}
+
+// Handles the business API, which is only used for business info, accounts and invoice payments.
+func (f *fakeWave) business(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/businesses"), "/")
+	segments := strings.Split(path, "/")
+
+	f.requests = append(f.requests, r.Method+" /businesses/"+path)
+
+	switch {
+	case path == "" && r.Method == http.MethodGet:
+		writeFakeWaveJSON(w, []wave.WaveInternalBusinessInfo{
+			{ID: FAKE_WAVE_IDENTITY_BUSINESS_ID, CompanyName: wave.WAVE_BUSINESS_NAME},
+		})
+	case len(segments) == 3 && segments[1] == "accountsv2" && r.Method == http.MethodGet:
+		writeFakeWaveJSON(w, map[string]any{
+			"accounts": []wave.WaveBusinessAccount{
+				{GUID: "cash", AccountName: "Cash on Hand", Active: true, ID: 1},
+				{GUID: "old", AccountName: "Closed Account", Archived: true, ID: 2},
+			},
+		})
+	case len(segments) == 3 && segments[1] == "invoices" && r.Method == http.MethodGet:
+		writeFakeWaveJSON(w, map[string]any{"payments": f.payments[segments[2]]})
+	case len(segments) == 4 && segments[3] == "payments" && r.Method == http.MethodPost:
+		var payment wave.WaveInvoicePayment
+		if err := json.NewDecoder(r.Body).Decode(&payment); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		f.nextID++
+		payment.ID = fmt.Sprint(f.nextID)
+		f.payments[segments[2]] = append(f.payments[segments[2]], payment)
+		writeFakeWaveJSON(w, payment)
+	case len(segments) == 5 && segments[3] == "payments" && r.Method == http.MethodDelete:
+		f.payments[segments[2]] = slices.DeleteFunc(f.payments[segments[2]], func(payment wave.WaveInvoicePayment) bool {
+			return payment.ID == segments[4]
+		})
+		writeFakeWaveJSON(w, map[string]any{})
+	default:
+		http.Error(w, `{"detail": "Not found."}`, http.StatusNotFound)
+	}
+}
+
+func customerFromInput(customer wave.WaveCustomer, input map[string]any) wave.WaveCustomer {
+	if name, ok := input["name"].(string); ok {
+		customer.Name = name
+	}
+
+	if email, ok := input["email"].(string); ok {
+		customer.Email = email
+	}
+
+	if phone, ok := input["phone"].(string); ok {
+		customer.Phone = phone
+	}
+
+	if mobile, ok := input["mobile"].(string); ok {
+		customer.Mobile = mobile
+	}
+
+	return customer
+}
+
+func mutationResult(operation string, didSucceed bool) map[string]any {
+	return map[string]any{operation: map[string]any{"didSucceed": didSucceed, "inputErrors": nil}}
+}
+
+func jsonInt(value any) int {
+	number, _ := value.(float64)
+	return int(number)
+}
+
+func writeFakeWaveJSON(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
@@ -28,7 +28,8 @@ func TestHandshakeNoToken(t *testing.T) {
 
 func TestHandshakeInvalidToken(t *testing.T) {
 	app := application{
-		logger: mocks.Logger(),
+		logger:           mocks.Logger(),
+		isSessionRevoked: mocks.SessionNotRevoked,
 	}
 	rr := httptest.NewRecorder()
 
@@ -42,12 +43,13 @@ func TestHandshakeInvalidToken(t *testing.T) {
 
 	rs := rr.Result()
 
-	assert.Equal(t, rs.StatusCode, http.StatusInternalServerError)
+	assert.Equal(t, rs.StatusCode, http.StatusUnauthorized)
 }
 
 func TestAuthenticationValidToken(t *testing.T) {
 	app := application{
-		logger: log.Default(),
+		logger:           log.Default(),
+		isSessionRevoked: mocks.SessionNotRevoked,
 	}
 	rr := httptest.NewRecorder()
 
@@ -56,7 +58,7 @@ func TestAuthenticationValidToken(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	token, err := internal.CreateToken("1234")
+	token, err := internal.CreateToken("1234", "1234", internal.ROLE_OWNER, 1)
 	if err != nil {
 		t.Fatal(err)
 	}
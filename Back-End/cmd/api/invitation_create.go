@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/data"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type createInvitationBody struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// Route for inviting someone to create an account.
+// The invitee is emailed a link to the registration page.
+func (app *application) createInvitation(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body createInvitationBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if body.Email == "" {
+		app.errorResponse(w, r, http.StatusBadRequest, "Email is required.")
+		return
+	}
+
+	if !internal.IsValidRole(body.Role) {
+		app.errorResponse(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid role: %v", body.Role))
+		return
+	}
+
+	tx := app.contextGetTx(r)
+
+	accountID := app.contextGetAccountID(r)
+	invitation, token, err := data.CreateInvitation(r.Context(), tx, body.Email, body.Role, accountID, app.contextGetUserID(r))
+	if err != nil {
+		err = errors.Wrap(err, "CreateInvitation")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = app.recordAudit(tx, r, data.AUDIT_ACTION_CREATE, data.AUDIT_ENTITY_INVITATION, invitation.ID, nil, invitation)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+		return
+	}
+
+	link := fmt.Sprintf("%v/register?token=%v", app.config.frontendURL, url.QueryEscape(token))
+	message := fmt.Sprintf(
+		"Hi,\n\nYou have been invited to Prime Shine Accounting. Use the link below to create your account. It expires in %v.\n\n%v\n",
+		data.INVITATION_DURATION,
+		link,
+	)
+
+	// sent once the invitation is committed; if sending fails, the owner can invite them again
+	app.contextAfterCommit(r, func() {
+		err := app.mailer.Send(invitation.Email, "You're invited to Prime Shine Accounting", message)
+		if err != nil {
+			app.logError(r, errors.Wrap(err, "mailer.Send"))
+		}
+	})
+
+	data := jsondata{"invitation": invitation}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "writeJSON"))
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/assert"
+	"prime-shine-api/internal/mocks"
+	"strings"
+	"testing"
+)
+
+func TestCreateInvitationInvalidBody(t *testing.T) {
+	app := application{
+		logger:           mocks.Logger(),
+		isSessionRevoked: mocks.SessionNotRevoked,
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing email", `{"role": "cleaner"}`},
+		{"unknown role", `{"email": "alice@fakeuser.com", "role": "admin"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+
+			r, err := http.NewRequest(http.MethodPost, "/api/invitations/create", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := internal.CreateToken("1", "1", internal.ROLE_OWNER, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r.Header.Add("Authorization", token)
+			app.authenticate(app.requireRole(app.createInvitation, internal.ROLE_OWNER))(rr, r, nil)
+
+			rs := rr.Result()
+
+			assert.Equal(t, rs.StatusCode, http.StatusBadRequest)
+		})
+	}
+}
+
+func TestCreateInvitationNotOwner(t *testing.T) {
+	app := application{
+		logger:           mocks.Logger(),
+		isSessionRevoked: mocks.SessionNotRevoked,
+	}
+	rr := httptest.NewRecorder()
+
+	body := strings.NewReader(`{"email": "alice@fakeuser.com", "role": "owner"}`)
+	r, err := http.NewRequest(http.MethodPost, "/api/invitations/create", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := internal.CreateToken("1", "1", internal.ROLE_OFFICE_MANAGER, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Header.Add("Authorization", token)
+	app.authenticate(app.requireRole(app.createInvitation, internal.ROLE_OWNER))(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusForbidden)
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"net/http"
+	"prime-shine-api/internal"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+// Route for publishing the public keys that our tokens can be verified with.
+func (app *application) jwksHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	headers := http.Header{}
+	headers.Set("Cache-Control", "public, max-age=300")
+
+	data := jsondata{"keys": internal.JWKS()}
+	err := app.writeJSON(w, http.StatusOK, data, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "writeJSON"))
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/assert"
+	"prime-shine-api/internal/mocks"
+	"testing"
+)
+
+func writeKeyFile(t *testing.T, key any) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func useJWTKeys(t *testing.T, signingKeyFile string, verificationKeyFiles string) {
+	t.Setenv("JWT_SIGNING_KEY_FILE", signingKeyFile)
+	t.Setenv("JWT_VERIFICATION_KEY_FILES", verificationKeyFiles)
+
+	keys, err := internal.LoadJWTKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	internal.SetJWTKeys(keys)
+	t.Cleanup(func() { internal.SetJWTKeys(nil) })
+}
+
+func authenticateToken(t *testing.T, token string) int {
+	app := application{
+		logger:           mocks.Logger(),
+		isSessionRevoked: mocks.SessionNotRevoked,
+	}
+	rr := httptest.NewRecorder()
+
+	r, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Header.Add("Authorization", token)
+	app.authenticate(app.pingCheckHandler)(rr, r, nil)
+
+	return rr.Result().StatusCode
+}
+
+func TestJWTKeyRotation(t *testing.T) {
+	_, oldKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldKeyFile := writeKeyFile(t, oldKey)
+	newKeyFile := writeKeyFile(t, newKey)
+
+	useJWTKeys(t, oldKeyFile, "")
+	oldToken, err := internal.CreateToken("1234", "1234", internal.ROLE_OWNER, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, authenticateToken(t, oldToken), http.StatusOK)
+
+	// Tokens signed with the old key stay valid while it is still listed for verification.
+	useJWTKeys(t, newKeyFile, oldKeyFile)
+	newToken, err := internal.CreateToken("1234", "1234", internal.ROLE_OWNER, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, authenticateToken(t, newToken), http.StatusOK)
+	assert.Equal(t, authenticateToken(t, oldToken), http.StatusOK)
+
+	// Once the old key is dropped, its tokens are no longer accepted.
+	useJWTKeys(t, newKeyFile, "")
+	assert.Equal(t, authenticateToken(t, newToken), http.StatusOK)
+	assert.NotEqual(t, authenticateToken(t, oldToken), http.StatusOK)
+}
+
+func TestJWKS(t *testing.T) {
+	_, oldKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("JWT_TOKEN", "shared-secret")
+	useJWTKeys(t, writeKeyFile(t, newKey), writeKeyFile(t, oldKey))
+
+	app := application{}
+	rr := httptest.NewRecorder()
+
+	r, err := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.jwksHandler(rr, r, nil)
+
+	rs := rr.Result()
+	assert.Equal(t, rs.StatusCode, http.StatusOK)
+
+	var body struct {
+		Keys []internal.JWK `json:"keys"`
+	}
+
+	err = json.NewDecoder(rs.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The shared secret must never be published.
+	assert.Equal(t, len(body.Keys), 2)
+
+	algorithms := map[string]bool{}
+	for _, key := range body.Keys {
+		algorithms[key.Algorithm] = true
+		assert.NotEqual(t, key.KeyID, "")
+	}
+
+	assert.Equal(t, algorithms["RS256"], true)
+	assert.Equal(t, algorithms["EdDSA"], true)
+}
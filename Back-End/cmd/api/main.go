@@ -1,29 +1,50 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/data"
 	"prime-shine-api/internal/db"
+	"prime-shine-api/internal/mailer"
+	"prime-shine-api/internal/wave"
+	"sync"
 	"syscall"
 	"time"
+	_ "time/tzdata" // recurring services are in IANA time zones, which minimal images have no database of
 
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 )
 
 type config struct {
-	port int
-	dev  bool
+	port        int
+	dev         bool
+	frontendURL string
+	trustProxy  bool
+	autoMigrate bool
+	timeZone    *time.Location // where the business is; recurring services, availability and copied schedules are in it
+	weekStart   time.Weekday   // the day schedules start on
+
+	passwordPolicy internal.PasswordPolicy
+	queryTimeouts  db.QueryTimeouts
+	waveOptions    wave.ClientOptions
 }
 
 type application struct {
-	config config
-	logger *log.Logger
-	db     *sqlx.DB
+	config           config
+	logger           *log.Logger
+	db               *sqlx.DB
+	isSessionRevoked internal.SessionRevokedFunc
+	mailer           mailer.Mailer
+	wave             wave.API
+	newTx            func(ctx context.Context) db.Tx // overrides beginTx, for tests
+	backgroundTasks  sync.WaitGroup                  // work that outlives its request, waited for on shutdown
 }
 
 func waitForSignals(app *application) {
@@ -33,6 +54,9 @@ func waitForSignals(app *application) {
 	s := <-quit
 
 	app.logger.Printf("Caught signal %s", s.String())
+	app.logger.Println("Waiting for background tasks")
+	app.backgroundTasks.Wait()
+
 	app.logger.Println("Disconnecting from database")
 
 	if err := app.db.Close(); err != nil {
@@ -47,9 +71,45 @@ func main() {
 
 	flag.IntVar(&cfg.port, "port", 5000, "API server port")
 	flag.BoolVar(&cfg.dev, "dev", true, "Development mode")
+	flag.StringVar(&cfg.frontendURL, "frontend-url", "http://local.prime-shine-cleaning.com", "Base URL of the front-end, used in emailed links")
+	flag.BoolVar(&cfg.trustProxy, "trust-proxy", false, "Trust the X-Real-IP header set by the reverse proxy; only enable it when clients can not reach the API directly")
+	flag.BoolVar(&cfg.autoMigrate, "auto-migrate", false, "Apply pending database migrations on startup")
+	timeZone := flag.String("business-time-zone", "UTC", "IANA time zone of the business, e.g. America/Toronto, used for recurring services and to keep visits at the same wall clock time across weeks")
+	weekStart := flag.String("business-week-start", "monday", "Day of the week that schedules start on, e.g. sunday")
+	flag.IntVar(&cfg.passwordPolicy.MinLength, "password-min-length", 10, "Minimum length of new passwords")
+	flag.BoolVar(&cfg.passwordPolicy.RequireMixed, "password-require-mixed-case", false, "Require upper and lower case letters in new passwords")
+	flag.BoolVar(&cfg.passwordPolicy.RequireDigit, "password-require-digit", true, "Require a digit in new passwords")
+	flag.BoolVar(&cfg.passwordPolicy.RequireSymbol, "password-require-symbol", false, "Require a symbol in new passwords")
+	flag.DurationVar(&cfg.queryTimeouts.Default, "db-query-timeout", db.DEFAULT_QUERY_TIMEOUT, "How long a database operation may run")
+	queryTimeoutOverrides := flag.String("db-query-timeouts", "", "Per-operation database timeouts, e.g. QueryAuditLog=15s,QueryLoginEvents=10s")
+	flag.DurationVar(&cfg.waveOptions.Timeout, "wave-timeout", wave.DEFAULT_TIMEOUT, "How long a single Wave request may take")
+	flag.IntVar(&cfg.waveOptions.MaxRetries, "wave-max-retries", wave.DEFAULT_MAX_RETRIES, "How many times failed Wave queries are retried")
+	flag.DurationVar(&cfg.waveOptions.RetryBaseDelay, "wave-retry-base-delay", wave.DEFAULT_RETRY_BASE_DELAY, "Initial delay between Wave retries, doubled on every attempt")
+	flag.DurationVar(&cfg.waveOptions.RetryMaxDelay, "wave-retry-max-delay", wave.DEFAULT_RETRY_MAX_DELAY, "Longest delay between Wave retries")
+	flag.IntVar(&cfg.waveOptions.BreakerThreshold, "wave-breaker-threshold", wave.DEFAULT_BREAKER_THRESHOLD, "Consecutive Wave failures before requests fail fast (0 disables)")
+	flag.DurationVar(&cfg.waveOptions.BreakerCooldown, "wave-breaker-cooldown", wave.DEFAULT_BREAKER_COOLDOWN, "How long Wave requests fail fast before Wave is tried again")
 	flag.Parse()
 
 	logger := log.New(os.Stdout, "", log.Ldate|log.Ltime)
+
+	operationTimeouts, err := db.ParseQueryTimeouts(*queryTimeoutOverrides)
+	if err != nil {
+		logger.Fatalf("Invalid -db-query-timeouts: %v", err.Error())
+	}
+
+	cfg.queryTimeouts.Operations = operationTimeouts
+	db.SetQueryTimeouts(cfg.queryTimeouts)
+
+	cfg.timeZone, err = time.LoadLocation(*timeZone)
+	if err != nil {
+		logger.Fatalf("Invalid -business-time-zone: %v", err.Error())
+	}
+
+	cfg.weekStart, err = data.ParseWeekday(*weekStart)
+	if err != nil {
+		logger.Fatalf("Invalid -business-week-start: %v", err.Error())
+	}
+
 	db, err := db.SetupDB(logger)
 	if err != nil {
 		logger.Fatalf("Could not connect to database: %v", err.Error())
@@ -57,10 +117,51 @@ func main() {
 
 	logger.Println("Connected to database")
 
+	// e.g. `api migrate up`; flags go before the subcommand
+	if flag.NArg() > 0 {
+		if flag.Arg(0) != "migrate" {
+			logger.Fatalf("Unknown command %q", flag.Arg(0))
+		}
+
+		if err := runMigrateCommand(logger, db, flag.Args()[1:]); err != nil {
+			logger.Fatalf("Could not migrate database: %v", err.Error())
+		}
+
+		return
+	}
+
+	if cfg.autoMigrate {
+		if err := migrateUp(logger, db); err != nil {
+			logger.Fatalf("Could not migrate database: %v", err.Error())
+		}
+	}
+
+	if err := reportMisalignedSchedules(logger, db, cfg.weekStart); err != nil {
+		logger.Fatalf("Could not check schedules: %v", err.Error())
+	}
+
+	jwtKeys, err := internal.LoadJWTKeys()
+	if err != nil {
+		logger.Fatalf("Could not load JWT keys: %v", err.Error())
+	}
+
+	internal.SetJWTKeys(jwtKeys)
+
+	smtpMailer, err := mailer.NewSMTPMailer()
+	if err != nil {
+		logger.Fatalf("Could not configure mailer: %v", err.Error())
+	}
+
 	app := &application{
 		config: cfg,
 		logger: logger,
 		db:     db,
+		mailer: smtpMailer,
+		wave:   wave.NewClientFromEnv(logger, cfg.waveOptions),
+	}
+
+	app.isSessionRevoked = func(ctx context.Context, sessionID int) (bool, error) {
+		return data.IsSessionRevoked(ctx, app.db, sessionID)
 	}
 
 	server := &http.Server{
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+// Route for starting TOTP enrollment.
+// The returned secret (or otpauth URI) is added to an authenticator app, then confirmed via verifyTOTP.
+func (app *application) enrollTOTP(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := app.contextGetUserID(r)
+
+	tx := app.contextGetTx(r)
+
+	query := db.Where(data.UserColumns.ID.Eq(userID))
+	user, err := data.FindOneUser(r.Context(), tx, query)
+	if err != nil {
+		err = errors.Wrap(err, "FindOneUser")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if user == nil {
+		app.notFoundResponse(w, r, "Could not find user.")
+		return
+	}
+
+	secret, err := internal.GenerateTOTPSecret()
+	if err != nil {
+		err = errors.Wrap(err, "GenerateTOTPSecret")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = data.BeginTOTPEnrollment(r.Context(), tx, user.ID, secret)
+	if err != nil {
+		err = errors.Wrap(err, "BeginTOTPEnrollment")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data := jsondata{"secret": secret, "otpauthURI": internal.TOTPURI(secret, user.Email)}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "writeJSON"))
+	}
+}
+
+type verifyTOTPBody struct {
+	Code string `json:"code"`
+}
+
+// Route for finishing TOTP enrollment with the first code from the authenticator app.
+// Recovery codes are only ever shown in this response.
+func (app *application) verifyTOTP(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body verifyTOTPBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID := app.contextGetUserID(r)
+
+	tx := app.contextGetTx(r)
+
+	query := db.Where(data.UserColumns.ID.Eq(userID))
+	user, err := data.FindOneUser(r.Context(), tx, query)
+	if err != nil {
+		err = errors.Wrap(err, "FindOneUser")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if user == nil {
+		app.notFoundResponse(w, r, "Could not find user.")
+		return
+	}
+
+	if user.TOTPEnabled || !user.TOTPSecret.Valid {
+		app.errorResponse(w, r, http.StatusBadRequest, "Two-factor authentication enrollment was not started.")
+		return
+	}
+
+	step, ok := internal.ValidateTOTP(user.TOTPSecret.String, body.Code, time.Now())
+	if !ok {
+		app.errorResponse(w, r, http.StatusBadRequest, "Code is invalid.")
+		return
+	}
+
+	_, err = data.UseTOTPStep(r.Context(), tx, user.ID, step)
+	if err != nil {
+		err = errors.Wrap(err, "UseTOTPStep")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	recoveryCodes, err := internal.GenerateRecoveryCodes()
+	if err != nil {
+		err = errors.Wrap(err, "GenerateRecoveryCodes")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = data.EnableTOTP(r.Context(), tx, user.ID, recoveryCodes)
+	if err != nil {
+		err = errors.Wrap(err, "EnableTOTP")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = app.recordTOTPAudit(tx, r, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordTOTPAudit"))
+		return
+	}
+
+	data := jsondata{"recoveryCodes": recoveryCodes}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "writeJSON"))
+	}
+}
+
+type disableTOTPBody struct {
+	Code string `json:"code"`
+}
+
+// Route for turning off TOTP. A current code is required.
+func (app *application) disableTOTP(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body disableTOTPBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID := app.contextGetUserID(r)
+
+	tx := app.contextGetTx(r)
+
+	query := db.Where(data.UserColumns.ID.Eq(userID))
+	user, err := data.FindOneUser(r.Context(), tx, query)
+	if err != nil {
+		err = errors.Wrap(err, "FindOneUser")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if user == nil {
+		app.notFoundResponse(w, r, "Could not find user.")
+		return
+	}
+
+	if !user.TOTPEnabled {
+		app.errorResponse(w, r, http.StatusBadRequest, "Two-factor authentication is not enabled.")
+		return
+	}
+
+	step, ok := internal.ValidateTOTP(user.TOTPSecret.String, body.Code, time.Now())
+	if !ok {
+		app.errorResponse(w, r, http.StatusBadRequest, "Code is invalid.")
+		return
+	}
+
+	codeUnused, err := data.UseTOTPStep(r.Context(), tx, user.ID, step)
+	if err != nil {
+		err = errors.Wrap(err, "UseTOTPStep")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !codeUnused {
+		app.errorResponse(w, r, http.StatusBadRequest, "Code is invalid.")
+		return
+	}
+
+	err = data.DisableTOTP(r.Context(), tx, user.ID)
+	if err != nil {
+		err = errors.Wrap(err, "DisableTOTP")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.recordTOTPAudit(tx, r, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordTOTPAudit"))
+		return
+	}
+
+	data := jsondata{"success": true}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "writeJSON"))
+	}
+}
+
+// Audits a change to a user's two-factor settings, comparing the user before it with the stored user.
+func (app *application) recordTOTPAudit(tx db.WriteDBExecutor, r *http.Request, before *data.User) error {
+	query := db.Where(data.UserColumns.ID.Eq(before.ID))
+	after, err := data.FindOneUser(r.Context(), tx, query)
+	if err != nil {
+		return errors.Wrap(err, "FindOneUser")
+	}
+
+	return app.recordAudit(tx, r, data.AUDIT_ACTION_EDIT, data.AUDIT_ENTITY_USER, before.ID, before, after)
+}
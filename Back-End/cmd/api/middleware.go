@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"prime-shine-api/internal"
+	"prime-shine-api/internal/db"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -35,22 +40,132 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 }
 
 // Ensures that requests have a valid JSON Web Token.
+// The token may be sent with or without the "Bearer " prefix.
 func (app *application) authenticate(next httprouter.Handle) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		token := r.Header.Get("Authorization")
+		token := strings.TrimSpace(r.Header.Get("Authorization"))
+		if len(token) > len("Bearer ") && strings.EqualFold(token[:len("Bearer ")], "Bearer ") {
+			token = strings.TrimSpace(token[len("Bearer "):])
+		}
+
 		if token == "" {
-			app.errorResponse(w, r, http.StatusUnauthorized, "Unauthorized.")
+			app.unauthorizedResponse(w, r, internal.TOKEN_ERROR_MISSING)
+			return
+		}
+
+		session, err := internal.VerifyToken(r.Context(), token, app.isSessionRevoked)
+
+		var tokenErr *internal.TokenError
+		if errors.As(err, &tokenErr) {
+			app.unauthorizedResponse(w, r, tokenErr.Code)
 			return
 		}
 
-		tokenValid, err := internal.VerifyToken(token)
 		if err != nil {
 			app.serverErrorResponse(w, r, errors.Wrap(err, "VerifyToken"))
 			return
 		}
 
-		if !tokenValid {
-			app.errorResponse(w, r, http.StatusUnauthorized, "Unauthorized.")
+		userID, err := strconv.Atoi(session.UserID)
+		if err != nil {
+			app.unauthorizedResponse(w, r, internal.TOKEN_ERROR_INVALID_CLAIMS)
+			return
+		}
+
+		accountID, err := strconv.Atoi(session.AccountID)
+		if err != nil {
+			app.unauthorizedResponse(w, r, internal.TOKEN_ERROR_INVALID_CLAIMS)
+			return
+		}
+
+		r = app.contextSetUserID(r, userID)
+		r = app.contextSetAccountID(r, accountID)
+		r = app.contextSetSessionID(r, session.SessionID)
+		r = app.contextSetRole(r, session.Role)
+		next(w, r, ps)
+	}
+}
+
+// The transaction of a request wrapped by transaction.
+type requestTx struct {
+	tx                  db.Tx
+	commitOnClientError bool
+	afterCommit         []func()
+}
+
+// Commits for 2xx responses, and for 4xx responses if the handler asked for it.
+func (t *requestTx) shouldCommit(status int) bool {
+	if status >= 200 && status < 300 {
+		return true
+	}
+
+	return t.commitOnClientError && status >= 400 && status < 500
+}
+
+// Starts the transaction for a request.
+// The transaction is rolled back by the database driver if ctx is cancelled.
+func (app *application) beginTx(ctx context.Context) db.Tx {
+	if app.newTx != nil {
+		return app.newTx(ctx)
+	}
+
+	return db.NewLazyTx(ctx, app.db)
+}
+
+// Runs a handler inside a database transaction, which it grabs with contextGetTx.
+// The response is held back until the transaction is done: it is committed for 2xx responses
+// and rolled back otherwise, so that a failed commit is still reported to the client.
+func (app *application) transaction(next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		state := &requestTx{tx: app.beginTx(r.Context())}
+		r = app.contextSetTx(r, state)
+		buffer := newResponseBuffer()
+
+		defer func() {
+			if rec := recover(); rec != nil {
+				_ = state.tx.Rollback()
+				w.Header().Set("Connection", "close")
+				app.serverErrorResponse(w, r, errors.Errorf("%v", rec))
+			}
+		}()
+
+		next(buffer, r, ps)
+
+		if r.Context().Err() != nil {
+			// req is cancelled by client, timeout, or app ctx cancelled.
+			_ = state.tx.Rollback()
+			return
+		}
+
+		if !state.shouldCommit(buffer.statusCode()) {
+			if err := state.tx.Rollback(); err != nil {
+				app.logError(r, errors.Wrap(err, "Rollback"))
+			}
+
+			buffer.flush(w)
+			return
+		}
+
+		if err := state.tx.Commit(); err != nil {
+			app.serverErrorResponse(w, r, errors.Wrap(err, "Commit"))
+			return
+		}
+
+		buffer.flush(w)
+
+		for _, fn := range state.afterCommit {
+			app.background(r, fn)
+		}
+	}
+}
+
+// Ensures that the authenticated user has one of the given roles.
+// This must be wrapped by authenticate.
+func (app *application) requireRole(next httprouter.Handle, roles ...string) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		role := app.contextGetRole(r)
+		if !slices.Contains(roles, role) {
+			app.forbiddenResponse(w, r)
 			return
 		}
 
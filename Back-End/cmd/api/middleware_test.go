@@ -1,13 +1,20 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"prime-shine-api/internal"
 	"prime-shine-api/internal/assert"
+	"prime-shine-api/internal/db"
 	"prime-shine-api/internal/mocks"
+	"strings"
 	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
 )
 
 func TestAuthenticationNoJWT(t *testing.T) {
@@ -26,9 +33,10 @@ func TestAuthenticationNoJWT(t *testing.T) {
 	assert.Equal(t, rs.StatusCode, http.StatusUnauthorized)
 }
 
-func TestAuthenticationInvalidJWTServerError(t *testing.T) {
+func TestAuthenticationMalformedJWT(t *testing.T) {
 	app := application{
-		logger: mocks.Logger(),
+		logger:           mocks.Logger(),
+		isSessionRevoked: mocks.SessionNotRevoked,
 	}
 	rr := httptest.NewRecorder()
 
@@ -42,12 +50,14 @@ func TestAuthenticationInvalidJWTServerError(t *testing.T) {
 
 	rs := rr.Result()
 
-	assert.Equal(t, rs.StatusCode, http.StatusInternalServerError)
+	assert.Equal(t, rs.StatusCode, http.StatusUnauthorized)
+	assert.Equal(t, unauthorizedCode(t, rs), internal.TOKEN_ERROR_MALFORMED)
 }
 
 func TestAuthenticationInvalidJWTClientError(t *testing.T) {
 	app := application{
-		logger: log.Default(),
+		logger:           log.Default(),
+		isSessionRevoked: mocks.SessionNotRevoked,
 	}
 	rr := httptest.NewRecorder()
 
@@ -67,11 +77,104 @@ func TestAuthenticationInvalidJWTClientError(t *testing.T) {
 	rs := rr.Result()
 
 	assert.Equal(t, rs.StatusCode, http.StatusUnauthorized)
+	assert.Equal(t, unauthorizedCode(t, rs), internal.TOKEN_ERROR_EXPIRED)
+}
+
+func TestAuthenticationWrongAudience(t *testing.T) {
+	app := application{
+		logger:           mocks.Logger(),
+		isSessionRevoked: mocks.SessionNotRevoked,
+	}
+	rr := httptest.NewRecorder()
+
+	r, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := mocks.WrongAudienceJWT("1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Header.Add("Authorization", token)
+	app.authenticate(app.pingCheckHandler)(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusUnauthorized)
+	assert.Equal(t, unauthorizedCode(t, rs), internal.TOKEN_ERROR_INVALID_CLAIMS)
+}
+
+func TestAuthenticationBearerPrefix(t *testing.T) {
+	app := application{
+		logger:           mocks.Logger(),
+		isSessionRevoked: mocks.SessionNotRevoked,
+	}
+	rr := httptest.NewRecorder()
+
+	r, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := internal.CreateToken("1234", "1234", internal.ROLE_OWNER, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Header.Add("Authorization", "Bearer "+token)
+	app.authenticate(app.pingCheckHandler)(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusOK)
+}
+
+func TestAuthenticationSessionLookupFails(t *testing.T) {
+	app := application{
+		logger:           mocks.Logger(),
+		isSessionRevoked: mocks.SessionLookupFails,
+	}
+	rr := httptest.NewRecorder()
+
+	r, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := internal.CreateToken("1234", "1234", internal.ROLE_OWNER, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Header.Add("Authorization", token)
+	app.authenticate(app.pingCheckHandler)(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusInternalServerError)
+}
+
+func unauthorizedCode(t *testing.T, rs *http.Response) string {
+	t.Helper()
+
+	var body struct {
+		Code string `json:"code"`
+	}
+
+	err := json.NewDecoder(rs.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return body.Code
 }
 
 func TestAuthenticationValidJWT(t *testing.T) {
 	app := application{
-		logger: log.Default(),
+		logger:           log.Default(),
+		isSessionRevoked: mocks.SessionNotRevoked,
 	}
 	rr := httptest.NewRecorder()
 
@@ -80,7 +183,7 @@ func TestAuthenticationValidJWT(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	token, err := internal.CreateToken("1234")
+	token, err := internal.CreateToken("1234", "1234", internal.ROLE_OWNER, 1)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -92,3 +195,376 @@ func TestAuthenticationValidJWT(t *testing.T) {
 
 	assert.Equal(t, rs.StatusCode, http.StatusOK)
 }
+
+func TestAuthenticationSetsUserID(t *testing.T) {
+	app := application{
+		logger:           mocks.Logger(),
+		isSessionRevoked: mocks.SessionNotRevoked,
+	}
+	rr := httptest.NewRecorder()
+
+	r, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := internal.CreateToken("1234", "1234", internal.ROLE_OWNER, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID := 0
+	next := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		userID = app.contextGetUserID(r)
+	}
+
+	r.Header.Add("Authorization", token)
+	app.authenticate(next)(rr, r, nil)
+
+	assert.Equal(t, userID, 1234)
+}
+
+func TestAuthenticationNonNumericSubject(t *testing.T) {
+	app := application{
+		logger:           mocks.Logger(),
+		isSessionRevoked: mocks.SessionNotRevoked,
+	}
+	rr := httptest.NewRecorder()
+
+	r, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := internal.CreateToken("not-a-user-id", "1234", internal.ROLE_OWNER, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Header.Add("Authorization", token)
+	app.authenticate(app.pingCheckHandler)(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusUnauthorized)
+}
+
+func TestAuthenticationMissingAccount(t *testing.T) {
+	app := application{
+		logger:           mocks.Logger(),
+		isSessionRevoked: mocks.SessionNotRevoked,
+	}
+	rr := httptest.NewRecorder()
+
+	r, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := internal.CreateToken("1234", "", internal.ROLE_OWNER, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Header.Add("Authorization", token)
+	app.authenticate(app.pingCheckHandler)(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusUnauthorized)
+}
+
+func TestAuthenticationRevokedSession(t *testing.T) {
+	app := application{
+		logger:           mocks.Logger(),
+		isSessionRevoked: mocks.SessionRevoked,
+	}
+	rr := httptest.NewRecorder()
+
+	r, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := internal.CreateToken("1234", "1234", internal.ROLE_OWNER, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Header.Add("Authorization", token)
+	app.authenticate(app.pingCheckHandler)(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusUnauthorized)
+}
+
+func TestRequireRoleAllowed(t *testing.T) {
+	app := application{
+		logger:           mocks.Logger(),
+		isSessionRevoked: mocks.SessionNotRevoked,
+	}
+	rr := httptest.NewRecorder()
+
+	r, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := internal.CreateToken("1234", "1234", internal.ROLE_OFFICE_MANAGER, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Header.Add("Authorization", token)
+	handler := app.requireRole(app.pingCheckHandler, internal.ROLE_OWNER, internal.ROLE_OFFICE_MANAGER)
+	app.authenticate(handler)(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusOK)
+}
+
+func TestRequireRoleForbidden(t *testing.T) {
+	app := application{
+		logger:           mocks.Logger(),
+		isSessionRevoked: mocks.SessionNotRevoked,
+	}
+	rr := httptest.NewRecorder()
+
+	r, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := internal.CreateToken("1234", "1234", internal.ROLE_CLEANER, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Header.Add("Authorization", token)
+	handler := app.requireRole(app.pingCheckHandler, internal.ROLE_OWNER)
+	app.authenticate(handler)(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusForbidden)
+}
+
+func TestAuthenticationUnknownRole(t *testing.T) {
+	app := application{
+		logger:           mocks.Logger(),
+		isSessionRevoked: mocks.SessionNotRevoked,
+	}
+	rr := httptest.NewRecorder()
+
+	r, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := internal.CreateToken("1234", "1234", "superuser", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Header.Add("Authorization", token)
+	app.authenticate(app.pingCheckHandler)(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusUnauthorized)
+}
+
+func transactionApp(tx *mocks.Tx) application {
+	return application{
+		logger: mocks.Logger(),
+		newTx:  func(ctx context.Context) db.Tx { return tx },
+	}
+}
+
+func TestTransactionCommitsOnSuccess(t *testing.T) {
+	tx := &mocks.Tx{}
+	app := transactionApp(tx)
+	rr := httptest.NewRecorder()
+
+	r, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		app.writeJSON(w, http.StatusOK, jsondata{"success": true}, nil)
+	}
+
+	app.transaction(next)(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusOK)
+	assert.Equal(t, rs.Header.Get("Content-Type"), "application/json")
+	assert.Equal(t, tx.Committed, true)
+	assert.Equal(t, tx.RolledBack, false)
+}
+
+func TestTransactionRollsBackOnError(t *testing.T) {
+	tx := &mocks.Tx{}
+	app := transactionApp(tx)
+	rr := httptest.NewRecorder()
+
+	r, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		app.errorResponse(w, r, http.StatusBadRequest, "Bad request.")
+	}
+
+	app.transaction(next)(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusBadRequest)
+	assert.Equal(t, tx.Committed, false)
+	assert.Equal(t, tx.RolledBack, true)
+}
+
+func TestTransactionCommitOnClientError(t *testing.T) {
+	tx := &mocks.Tx{}
+	app := transactionApp(tx)
+	rr := httptest.NewRecorder()
+
+	r, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		app.commitOnClientError(r)
+		app.errorResponse(w, r, http.StatusBadRequest, "Bad request.")
+	}
+
+	app.transaction(next)(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusBadRequest)
+	assert.Equal(t, tx.Committed, true)
+}
+
+func TestTransactionPanic(t *testing.T) {
+	tx := &mocks.Tx{}
+	app := transactionApp(tx)
+	rr := httptest.NewRecorder()
+
+	r, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		app.writeJSON(w, http.StatusOK, jsondata{"success": true}, nil)
+		panic("something went wrong")
+	}
+
+	app.transaction(next)(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusInternalServerError)
+	assert.Equal(t, tx.Committed, false)
+	assert.Equal(t, tx.RolledBack, true)
+}
+
+func TestTransactionClientCancelled(t *testing.T) {
+	tx := &mocks.Tx{}
+	app := transactionApp(tx)
+	rr := httptest.NewRecorder()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		cancel()
+		app.writeJSON(w, http.StatusOK, jsondata{"success": true}, nil)
+	}
+
+	app.transaction(next)(rr, r, nil)
+
+	assert.Equal(t, tx.Committed, false)
+	assert.Equal(t, tx.RolledBack, true)
+	assert.Equal(t, rr.Body.Len(), 0)
+}
+
+func TestTransactionCommitFailure(t *testing.T) {
+	tx := &mocks.Tx{CommitErr: errors.New("connection reset")}
+	app := transactionApp(tx)
+	rr := httptest.NewRecorder()
+
+	r, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		app.writeJSON(w, http.StatusOK, jsondata{"success": true}, nil)
+	}
+
+	app.transaction(next)(rr, r, nil)
+
+	rs := rr.Result()
+
+	// Only the error response reaches the client.
+	assert.Equal(t, rs.StatusCode, http.StatusInternalServerError)
+	assert.Equal(t, strings.Contains(rr.Body.String(), "success"), false)
+}
+
+func TestTransactionRunsAfterCommit(t *testing.T) {
+	tx := &mocks.Tx{}
+	app := transactionApp(tx)
+	rr := httptest.NewRecorder()
+
+	r, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	committedFirst := false
+	next := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		app.contextAfterCommit(r, func() { committedFirst = tx.Committed })
+		app.writeJSON(w, http.StatusOK, jsondata{"success": true}, nil)
+	}
+
+	app.transaction(next)(rr, r, nil)
+	app.backgroundTasks.Wait()
+
+	assert.Equal(t, rr.Result().StatusCode, http.StatusOK)
+	assert.Equal(t, committedFirst, true)
+}
+
+func TestTransactionSkipsAfterCommitOnRollback(t *testing.T) {
+	tx := &mocks.Tx{}
+	app := transactionApp(tx)
+	rr := httptest.NewRecorder()
+
+	r, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ran := false
+	next := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		app.contextAfterCommit(r, func() { ran = true })
+		app.errorResponse(w, r, http.StatusBadRequest, "Bad request.")
+	}
+
+	app.transaction(next)(rr, r, nil)
+	app.backgroundTasks.Wait()
+
+	assert.Equal(t, rr.Result().StatusCode, http.StatusBadRequest)
+	assert.Equal(t, ran, false)
+}
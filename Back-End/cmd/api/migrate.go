@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"log"
+	"prime-shine-api/internal/db"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Runs the `migrate` subcommand of the API binary:
+//
+//	migrate up          applies every pending migration
+//	migrate down [n]    reverts the last n migrations (default 1)
+//	migrate status      lists migrations and when they were applied
+func runMigrateCommand(logger *log.Logger, conn *sqlx.DB, args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected migrate up, down [n] or status")
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		return migrateUp(logger, conn)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return errors.Errorf("expected a positive number of migrations to revert, got %q", args[1])
+			}
+		}
+
+		reverted, err := db.MigrateDown(ctx, conn, steps)
+		for _, migration := range reverted {
+			logger.Printf("Reverted migration %d_%s", migration.Version, migration.Name)
+		}
+
+		if err != nil {
+			return errors.Wrap(err, "MigrateDown")
+		}
+
+		if len(reverted) == 0 {
+			logger.Println("No migrations to revert")
+		}
+
+		return nil
+	case "status":
+		statuses, err := db.MigrationStatuses(ctx, conn)
+		if err != nil {
+			return errors.Wrap(err, "MigrationStatuses")
+		}
+
+		for _, status := range statuses {
+			switch {
+			case status.Unknown:
+				logger.Printf("%d_%s applied %s (unknown to this release)", status.Version, status.Name, status.AppliedAt.Time.Format(time.RFC3339))
+			case status.AppliedAt.Valid:
+				logger.Printf("%d_%s applied %s", status.Version, status.Name, status.AppliedAt.Time.Format(time.RFC3339))
+			default:
+				logger.Printf("%d_%s pending", status.Version, status.Name)
+			}
+		}
+
+		return nil
+	default:
+		return errors.Errorf("unknown migrate command %q", args[0])
+	}
+}
+
+// Applies every pending migration, logging each one.
+// Used by both `migrate up` and -auto-migrate.
+func migrateUp(logger *log.Logger, conn *sqlx.DB) error {
+	applied, err := db.MigrateUp(context.Background(), conn)
+	for _, migration := range applied {
+		logger.Printf("Applied migration %d_%s", migration.Version, migration.Name)
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "MigrateUp")
+	}
+
+	if len(applied) == 0 {
+		logger.Println("Database schema is up to date")
+	}
+
+	return nil
+}
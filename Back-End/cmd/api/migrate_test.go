@@ -0,0 +1,25 @@
+package main
+
+import (
+	"io"
+	"log"
+	"prime-shine-api/internal/assert"
+	"testing"
+)
+
+func TestMigrateCommandInvalid(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+
+	commands := [][]string{
+		{},
+		{"sideways"},
+		{"down", "two"},
+		{"down", "0"},
+	}
+
+	for _, args := range commands {
+		// Rejected before the database is touched, so no connection is needed.
+		err := runMigrateCommand(logger, nil, args)
+		assert.NotEqual(t, err, nil)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type forgotPasswordBody struct {
+	Email string `json:"email"`
+}
+
+// Route for requesting a password reset link.
+// The response is the same whether or not the email belongs to an account.
+func (app *application) forgotPassword(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body forgotPasswordBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if body.Email == "" {
+		app.errorResponse(w, r, http.StatusBadRequest, "Email is required.")
+		return
+	}
+
+	tx := app.contextGetTx(r)
+
+	query := db.Where(data.UserColumns.Email.Eq(body.Email))
+	user, err := data.FindOneUser(r.Context(), tx, query)
+	if err != nil {
+		err = errors.Wrap(err, "FindOneUser")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if user != nil {
+		token, err := data.CreatePasswordResetToken(r.Context(), tx, user.ID)
+		if err != nil {
+			err = errors.Wrap(err, "CreatePasswordResetToken")
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		link := fmt.Sprintf("%v/reset-password?token=%v", app.config.frontendURL, url.QueryEscape(token))
+		message := fmt.Sprintf(
+			"Hi %v,\n\nUse the link below to reset your Prime Shine Accounting password. It expires in %v.\n\n%v\n\nIf you did not ask for this, you can ignore this email.\n",
+			user.Name,
+			data.PASSWORD_RESET_TOKEN_DURATION,
+			link,
+		)
+
+		// sent after the response is settled, so that neither a failure nor the time it takes
+		// tells whether the email belongs to an account
+		app.contextAfterCommit(r, func() {
+			err := app.mailer.Send(user.Email, "Reset your password", message)
+			if err != nil {
+				app.logError(r, errors.Wrap(err, "mailer.Send"))
+			}
+		})
+	}
+
+	data := jsondata{"success": true}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "writeJSON"))
+	}
+}
+
+type resetPasswordBody struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// Route for setting a new password with a password reset token.
+func (app *application) resetPassword(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body resetPasswordBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = app.config.passwordPolicy.Validate(body.Password)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx := app.contextGetTx(r)
+
+	userID, err := data.ConsumePasswordResetToken(r.Context(), tx, body.Token)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.errorResponse(w, r, http.StatusBadRequest, "Password reset link is invalid or has expired.")
+		return
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "ConsumePasswordResetToken")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = data.SetUserPassword(r.Context(), tx, userID, body.Password)
+	if err != nil {
+		err = errors.Wrap(err, "SetUserPassword")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Anyone holding the old password may still have a session open.
+	err = data.RevokeUserSessions(r.Context(), tx, userID, 0)
+	if err != nil {
+		err = errors.Wrap(err, "RevokeUserSessions")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := jsondata{"success": true}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "writeJSON"))
+	}
+}
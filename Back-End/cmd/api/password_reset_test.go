@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"prime-shine-api/internal/assert"
+	"prime-shine-api/internal/mailer"
+	"prime-shine-api/internal/mocks"
+	"strings"
+	"testing"
+)
+
+func TestForgotPasswordNoEmail(t *testing.T) {
+	memoryMailer := mailer.NewMemoryMailer()
+	app := application{
+		logger: mocks.Logger(),
+		mailer: memoryMailer,
+	}
+	rr := httptest.NewRecorder()
+
+	body := strings.NewReader(`{"email": ""}`)
+	r, err := http.NewRequest(http.MethodPost, "/api/password/forgot", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.forgotPassword(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusBadRequest)
+	assert.Equal(t, len(memoryMailer.Messages()), 0)
+}
+
+func TestResetPasswordNoPassword(t *testing.T) {
+	app := application{
+		logger: mocks.Logger(),
+	}
+	rr := httptest.NewRecorder()
+
+	body := strings.NewReader(`{"token": "abc", "password": ""}`)
+	r, err := http.NewRequest(http.MethodPost, "/api/password/reset", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.resetPassword(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusBadRequest)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"prime-shine-api/internal/assert"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func testRecurringService(frequency string) data.RecurringService {
+	return data.RecurringService{
+		CustomerID: "QnVzaW5lc3M6Y3VzdG9tZXI=",
+		Weekday:    int(time.Tuesday),
+		StartTime:  data.NewTimeOfDay(9, 0),
+		EndTime:    data.NewTimeOfDay(11, 30),
+		Frequency:  frequency,
+		StartDate:  db.GetDateFromTimeStruct(time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)),
+	}
+}
+
+func day(month time.Month, dayOfMonth int) time.Time {
+	return time.Date(2025, month, dayOfMonth, 0, 0, 0, 0, time.UTC)
+}
+
+func TestRecurringServiceOccursOn(t *testing.T) {
+	weekly := testRecurringService(data.FREQUENCY_WEEKLY)
+	weekly.EndDate = db.GetDateFromTimeStruct(day(time.September, 30))
+	weekly.SkipDates = []pgtype.Date{db.GetDateFromTimeStruct(day(time.August, 19))}
+
+	// the first Tuesday on or after the start date is Aug 5th
+	biweekly := testRecurringService(data.FREQUENCY_BIWEEKLY)
+
+	second := testRecurringService(data.FREQUENCY_MONTHLY)
+	second.WeekOfMonth = pgtype.Int2{Int16: 2, Valid: true}
+
+	last := testRecurringService(data.FREQUENCY_MONTHLY)
+	last.WeekOfMonth = pgtype.Int2{Int16: data.LAST_WEEK_OF_MONTH, Valid: true}
+
+	tests := []struct {
+		name     string
+		service  data.RecurringService
+		date     time.Time
+		expected bool
+	}{
+		{"weekly on its weekday", weekly, day(time.August, 12), true},
+		{"weekly on another weekday", weekly, day(time.August, 13), false},
+		{"weekly before the start date", weekly, day(time.July, 29), false},
+		{"weekly on its end date", weekly, day(time.September, 30), true},
+		{"weekly after the end date", weekly, day(time.October, 7), false},
+		{"weekly on a skip date", weekly, day(time.August, 19), false},
+		{"biweekly first occurrence", biweekly, day(time.August, 5), true},
+		{"biweekly week off", biweekly, day(time.August, 12), false},
+		{"biweekly week on", biweekly, day(time.August, 19), true},
+		{"biweekly across months", biweekly, day(time.September, 30), true},
+		{"second Tuesday", second, day(time.August, 12), true},
+		{"first Tuesday", second, day(time.August, 5), false},
+		{"second Tuesday of the next month", second, day(time.September, 9), true},
+		{"last Tuesday of a four Tuesday month", last, day(time.August, 26), true},
+		{"last Tuesday of a five Tuesday month", last, day(time.September, 30), true},
+		{"fourth Tuesday of a five Tuesday month", last, day(time.September, 23), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.service.OccursOn(tt.date), tt.expected)
+		})
+	}
+}
+
+func TestRecurringServiceTimesOn(t *testing.T) {
+	service := testRecurringService(data.FREQUENCY_WEEKLY)
+
+	toronto, err := time.LoadLocation("America/Toronto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	startTime, endTime := service.TimesOn(day(time.August, 12), toronto)
+
+	// Toronto is on daylight time (UTC-4) in August
+	assert.Equal(t, startTime.UTC(), time.Date(2025, 8, 12, 13, 0, 0, 0, time.UTC))
+	assert.Equal(t, endTime.UTC(), time.Date(2025, 8, 12, 15, 30, 0, 0, time.UTC))
+
+	// and on standard time (UTC-5) in December
+	startTime, _ = service.TimesOn(day(time.December, 9), toronto)
+
+	assert.Equal(t, startTime.UTC(), time.Date(2025, 12, 9, 14, 0, 0, 0, time.UTC))
+}
+
+func TestRecurringServiceValidate(t *testing.T) {
+	valid := testRecurringService(data.FREQUENCY_WEEKLY)
+	assert.Equal(t, valid.Validate(), nil)
+
+	tests := []struct {
+		name   string
+		modify func(service *data.RecurringService)
+	}{
+		{"no customer", func(s *data.RecurringService) { s.CustomerID = "" }},
+		{"weekday out of range", func(s *data.RecurringService) { s.Weekday = 7 }},
+		{"ends before it starts", func(s *data.RecurringService) { s.EndTime = data.NewTimeOfDay(8, 0) }},
+		{"unknown frequency", func(s *data.RecurringService) { s.Frequency = "daily" }},
+		{"monthly without a week", func(s *data.RecurringService) { s.Frequency = data.FREQUENCY_MONTHLY }},
+		{"weekly with a week", func(s *data.RecurringService) { s.WeekOfMonth = pgtype.Int2{Int16: 1, Valid: true} }},
+		{"monthly in the fifth week", func(s *data.RecurringService) {
+			s.Frequency = data.FREQUENCY_MONTHLY
+			s.WeekOfMonth = pgtype.Int2{Int16: 5, Valid: true}
+		}},
+		{"end date before start date", func(s *data.RecurringService) {
+			s.EndDate = db.GetDateFromTimeStruct(day(time.July, 1))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := testRecurringService(data.FREQUENCY_WEEKLY)
+			tt.modify(&service)
+
+			assert.NotEqual(t, service.Validate(), nil)
+		})
+	}
+}
+
+func TestTimeOfDayJSON(t *testing.T) {
+	var body struct {
+		StartTime data.TimeOfDay `json:"startTime"`
+	}
+
+	err := json.Unmarshal([]byte(`{"startTime": "09:45"}`), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hour, minute := body.StartTime.Clock()
+	assert.Equal(t, hour, 9)
+	assert.Equal(t, minute, 45)
+
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, string(encoded), `{"startTime":"09:45"}`)
+
+	err = json.Unmarshal([]byte(`{"startTime": "9am"}`), &body)
+	assert.NotEqual(t, err, nil)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type createRecurringServiceBody struct {
+	CustomerID  string         `json:"waveCustomerID"`
+	Weekday     int            `json:"weekday"`
+	StartTime   data.TimeOfDay `json:"startTime"`
+	EndTime     data.TimeOfDay `json:"endTime"`
+	Frequency   string         `json:"frequency"`
+	WeekOfMonth pgtype.Int2    `json:"weekOfMonth"`
+	StartDate   time.Time      `json:"startDate"`
+	EndDate     *time.Time     `json:"endDate"`
+	SkipDates   []time.Time    `json:"skipDates"`
+}
+
+func (body createRecurringServiceBody) recurringService() data.RecurringService {
+	service := data.RecurringService{
+		CustomerID:  body.CustomerID,
+		Weekday:     body.Weekday,
+		StartTime:   body.StartTime,
+		EndTime:     body.EndTime,
+		Frequency:   body.Frequency,
+		WeekOfMonth: body.WeekOfMonth,
+		StartDate:   db.GetDateFromTimeStruct(body.StartDate),
+		SkipDates:   []pgtype.Date{},
+	}
+
+	if body.EndDate != nil {
+		service.EndDate = db.GetDateFromTimeStruct(*body.EndDate)
+	}
+
+	for _, skipDate := range body.SkipDates {
+		service.SkipDates = append(service.SkipDates, db.GetDateFromTimeStruct(skipDate))
+	}
+
+	return service
+}
+
+// Route for creating a recurring service.
+func (app *application) createRecurringService(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body createRecurringServiceBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	accountID := app.contextGetAccountID(r)
+
+	tx := app.contextGetTx(r)
+
+	service, err := data.CreateRecurringService(r.Context(), tx, accountID, body.recurringService())
+	if err != nil {
+		err = errors.Wrap(err, "CreateRecurringService")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = app.recordAudit(tx, r, data.AUDIT_ACTION_CREATE, data.AUDIT_ENTITY_RECURRING_SERVICE, service.ID, nil, service)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+		return
+	}
+
+	data := jsondata{"recurringService": service}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		err = errors.Wrap(err, "writeJSON")
+		app.serverErrorResponse(w, r, err)
+	}
+}
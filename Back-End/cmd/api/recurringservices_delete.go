@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type deleteRecurringServiceBody struct {
+	RecurringServiceID int `json:"recurringServiceID"`
+}
+
+// Route for deleting a recurring service.
+// Scheduled customers that were generated from it stay in their schedules.
+func (app *application) deleteRecurringService(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body deleteRecurringServiceBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	accountID := app.contextGetAccountID(r)
+
+	tx := app.contextGetTx(r)
+
+	query := db.Where(data.RecurringServiceColumns.ID.Eq(body.RecurringServiceID))
+	before, err := data.FindOneRecurringService(r.Context(), tx, accountID, query)
+	if err != nil {
+		err = errors.Wrap(err, "FindOneRecurringService")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	success, err := data.DeleteRecurringService(r.Context(), tx, accountID, body.RecurringServiceID)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, "Could not find recurring service.")
+		return
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "DeleteRecurringService")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = app.recordAudit(tx, r, data.AUDIT_ACTION_DELETE, data.AUDIT_ENTITY_RECURRING_SERVICE, body.RecurringServiceID, before, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+		return
+	}
+
+	data := jsondata{"success": success}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		err = errors.Wrap(err, "writeJSON")
+		app.serverErrorResponse(w, r, err)
+	}
+}
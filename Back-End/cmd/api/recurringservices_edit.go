@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type editRecurringServiceBody struct {
+	RecurringServiceID int `json:"recurringServiceID"`
+	createRecurringServiceBody
+}
+
+// Route for editing a recurring service.
+// Schedules that were populated from it already keep their scheduled customers.
+func (app *application) editRecurringService(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body editRecurringServiceBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	accountID := app.contextGetAccountID(r)
+
+	tx := app.contextGetTx(r)
+
+	query := db.Where(data.RecurringServiceColumns.ID.Eq(body.RecurringServiceID))
+	before, err := data.FindOneRecurringService(r.Context(), tx, accountID, query)
+	if err != nil {
+		err = errors.Wrap(err, "FindOneRecurringService")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if before == nil {
+		app.notFoundResponse(w, r, "Could not find recurring service.")
+		return
+	}
+
+	service := body.recurringService()
+	service.ID = body.RecurringServiceID
+
+	editedService, err := data.EditRecurringService(r.Context(), tx, accountID, service)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, "Could not find recurring service.")
+		return
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "EditRecurringService")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = app.recordAudit(tx, r, data.AUDIT_ACTION_EDIT, data.AUDIT_ENTITY_RECURRING_SERVICE, editedService.ID, before, editedService)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+		return
+	}
+
+	data := jsondata{"recurringService": editedService}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		err = errors.Wrap(err, "writeJSON")
+		app.serverErrorResponse(w, r, err)
+	}
+}
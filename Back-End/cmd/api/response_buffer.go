@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// Holds back a response so that it can be replaced before anything reaches the client.
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{header: http.Header{}}
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *responseBuffer) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+
+	return b.body.Write(p)
+}
+
+// The status code of the held back response; handlers that never write one answer with a 200.
+func (b *responseBuffer) statusCode() int {
+	if b.status == 0 {
+		return http.StatusOK
+	}
+
+	return b.status
+}
+
+// Sends the held back response to the client.
+func (b *responseBuffer) flush(w http.ResponseWriter) {
+	for key, value := range b.header {
+		w.Header()[key] = value
+	}
+
+	w.WriteHeader(b.statusCode())
+	w.Write(b.body.Bytes())
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"net/http"
+	"prime-shine-api/internal"
 
 	"github.com/julienschmidt/httprouter"
 )
@@ -9,53 +10,100 @@ import (
 func (app *application) routes() http.Handler {
 	router := httprouter.New()
 
+	// roles allowed on the more sensitive routes. Schedules, recurring services, employees and crews
+	// belong to the account that the user was invited into, so staff work on the same data as the
+	// owner; cleaners may only read it.
+	owner := []string{internal.ROLE_OWNER}
+	staff := []string{internal.ROLE_OWNER, internal.ROLE_OFFICE_MANAGER}
+
 	router.GET("/api/ping", app.pingCheckHandler)
+	router.GET("/.well-known/jwks.json", app.jwksHandler)
 
 	// route for login session verification
 	router.POST("/api/handshake", app.authenticate(app.handshakeHandler))
 
 	// user routes
-	router.POST("/api/login", app.loginUser)
-	router.POST("/api/register", app.createUser)
-	router.POST("/api/users/edit", app.authenticate(app.editUser))
-	router.POST("/api/users/delete", app.authenticate(app.deleteUser))
+	router.POST("/api/login", app.transaction(app.loginUser))
+	router.POST("/api/login/mfa", app.transaction(app.loginUserMFA))
+	router.POST("/api/register", app.transaction(app.createUser))
+	router.POST("/api/invitations/create", app.authenticate(app.requireRole(app.transaction(app.createInvitation), owner...)))
+	router.POST("/api/users/email/verify", app.transaction(app.verifyEmail))
+	router.POST("/api/logout", app.authenticate(app.transaction(app.logoutUser)))
+	router.POST("/api/logout/all", app.authenticate(app.transaction(app.logoutUserEverywhere)))
+	router.POST("/api/token/refresh", app.transaction(app.refreshToken))
+	router.POST("/api/password/forgot", app.transaction(app.forgotPassword))
+	router.POST("/api/password/reset", app.transaction(app.resetPassword))
+	router.POST("/api/users/edit", app.authenticate(app.transaction(app.editUser)))
+	router.POST("/api/users/password/edit", app.authenticate(app.transaction(app.editUserPassword)))
+	router.POST("/api/users/role/edit", app.authenticate(app.requireRole(app.transaction(app.editUserRole), owner...)))
+	router.POST("/api/users/delete", app.authenticate(app.requireRole(app.transaction(app.deleteUser), owner...)))
+	router.POST("/api/mfa/totp/enroll", app.authenticate(app.transaction(app.enrollTOTP)))
+	router.POST("/api/mfa/totp/verify", app.authenticate(app.transaction(app.verifyTOTP)))
+	router.POST("/api/mfa/totp/disable", app.authenticate(app.transaction(app.disableTOTP)))
+	router.POST("/api/login/events/query", app.authenticate(app.requireRole(app.queryLoginEvents, owner...)))
+	router.POST("/api/audit/query", app.authenticate(app.requireRole(app.queryAuditLog, owner...)))
 
 	// scheduled customer routes
 	router.POST("/api/scheduledCustomer/query", app.authenticate(app.queryScheduledCustomers))
-	router.POST("/api/scheduledCustomer/create", app.authenticate(app.createScheduledCustomer))
-	router.POST("/api/scheduledCustomer/edit", app.authenticate(app.editScheduledCustomer))
-	router.POST("/api/scheduledCustomer/delete", app.authenticate(app.deleteScheduledCustomer))
+	router.POST("/api/scheduledCustomer/create", app.authenticate(app.requireRole(app.transaction(app.createScheduledCustomer), staff...)))
+	router.POST("/api/scheduledCustomer/edit", app.authenticate(app.requireRole(app.transaction(app.editScheduledCustomer), staff...)))
+	router.POST("/api/scheduledCustomer/delete", app.authenticate(app.requireRole(app.transaction(app.deleteScheduledCustomer), staff...)))
+	router.POST("/api/scheduledCustomer/assign", app.authenticate(app.requireRole(app.transaction(app.assignScheduledCustomer), staff...)))
+	router.POST("/api/scheduledCustomer/assignments/query", app.authenticate(app.queryScheduledCustomerAssignments))
 
 	// schedule routes
 	router.POST("/api/schedules/query", app.authenticate(app.querySchedules))
 	router.POST("/api/schedule/query", app.authenticate(app.querySchedule))
-	router.POST("/api/schedule/create", app.authenticate(app.createSchedule))
-	router.POST("/api/schedule/edit", app.authenticate(app.editSchedule))
-	router.POST("/api/schedule/delete", app.authenticate(app.deleteSchedule))
+	router.POST("/api/schedule/create", app.authenticate(app.requireRole(app.transaction(app.createSchedule), staff...)))
+	router.POST("/api/schedule/edit", app.authenticate(app.requireRole(app.transaction(app.editSchedule), staff...)))
+	router.POST("/api/schedule/delete", app.authenticate(app.requireRole(app.transaction(app.deleteSchedule), staff...)))
+	router.POST("/api/schedule/copy", app.authenticate(app.requireRole(app.transaction(app.copySchedule), staff...)))
+	router.POST("/api/schedule/populate", app.authenticate(app.requireRole(app.transaction(app.populateSchedule), staff...)))
+	router.POST("/api/schedule/warnings/query", app.authenticate(app.queryScheduleWarnings))
+
+	// recurring service routes
+	router.POST("/api/recurringServices/query", app.authenticate(app.queryRecurringServices))
+	router.POST("/api/recurringService/create", app.authenticate(app.requireRole(app.transaction(app.createRecurringService), staff...)))
+	router.POST("/api/recurringService/edit", app.authenticate(app.requireRole(app.transaction(app.editRecurringService), staff...)))
+	router.POST("/api/recurringService/delete", app.authenticate(app.requireRole(app.transaction(app.deleteRecurringService), staff...)))
+
+	// employee routes
+	router.POST("/api/employees/query", app.authenticate(app.queryEmployees))
+	router.POST("/api/employee/create", app.authenticate(app.requireRole(app.transaction(app.createEmployee), staff...)))
+	router.POST("/api/employee/edit", app.authenticate(app.requireRole(app.transaction(app.editEmployee), staff...)))
+	router.POST("/api/employee/delete", app.authenticate(app.requireRole(app.transaction(app.deleteEmployee), staff...)))
+
+	// crew routes
+	router.POST("/api/crews/query", app.authenticate(app.queryCrews))
+	router.POST("/api/crew/create", app.authenticate(app.requireRole(app.transaction(app.createCrew), staff...)))
+	router.POST("/api/crew/edit", app.authenticate(app.requireRole(app.transaction(app.editCrew), staff...)))
+	router.POST("/api/crew/delete", app.authenticate(app.requireRole(app.transaction(app.deleteCrew), staff...)))
+	router.POST("/api/crew/scheduledCustomers/query", app.authenticate(app.queryCrewScheduledCustomers))
 
-	// wave customer routes
+	// wave customer routes. Changes to Wave can not be rolled back, so none of the wave routes run in a
+	// transaction; their audit entries are committed on their own (see beginWaveAudit).
 	router.POST("/api/wave/customer/query", app.authenticate(app.queryWaveCustomer))
-	router.POST("/api/wave/customer/create", app.authenticate(app.createWaveCustomer))
-	router.POST("/api/wave/customer/edit", app.authenticate(app.editWaveCustomer))
-	router.POST("/api/wave/customer/delete", app.authenticate(app.deleteWaveCustomer))
+	router.POST("/api/wave/customer/create", app.authenticate(app.requireRole(app.createWaveCustomer, staff...)))
+	router.POST("/api/wave/customer/edit", app.authenticate(app.requireRole(app.editWaveCustomer, staff...)))
+	router.POST("/api/wave/customer/delete", app.authenticate(app.requireRole(app.deleteWaveCustomer, staff...)))
 	router.POST("/api/wave/customers/query", app.authenticate(app.queryWaveCustomersPaginated))
 	router.POST("/api/wave/customers/queryAll", app.authenticate(app.queryWaveCustomers))
 
 	// wave invoice routes
-	router.POST("/api/wave/invoices/query", app.authenticate(app.queryWaveInvoices))
-	router.POST("/api/wave/invoice/query", app.authenticate(app.queryWaveInvoice))
-	router.POST("/api/wave/invoice/create", app.authenticate(app.createWaveInvoice))
-	router.POST("/api/wave/invoice/edit", app.authenticate(app.editWaveInvoice))
-	router.POST("/api/wave/invoice/delete", app.authenticate(app.deleteWaveInvoice))
+	router.POST("/api/wave/invoices/query", app.authenticate(app.requireRole(app.queryWaveInvoices, owner...)))
+	router.POST("/api/wave/invoice/query", app.authenticate(app.requireRole(app.queryWaveInvoice, owner...)))
+	router.POST("/api/wave/invoice/create", app.authenticate(app.requireRole(app.createWaveInvoice, owner...)))
+	router.POST("/api/wave/invoice/edit", app.authenticate(app.requireRole(app.editWaveInvoice, owner...)))
+	router.POST("/api/wave/invoice/delete", app.authenticate(app.requireRole(app.deleteWaveInvoice, owner...)))
 
 	// wave invoice payment routes
-	router.POST("/api/wave/invoice/payments/query", app.authenticate(app.queryWaveInvoicePayments))
-	router.POST("/api/wave/invoice/payments/create", app.authenticate(app.createWaveInvoicePayment))
-	router.POST("/api/wave/invoice/payments/edit", app.authenticate(app.editWaveInvoicePayment))
-	router.POST("/api/wave/invoice/payments/delete", app.authenticate(app.deleteWaveInvoicePayment))
+	router.POST("/api/wave/invoice/payments/query", app.authenticate(app.requireRole(app.queryWaveInvoicePayments, owner...)))
+	router.POST("/api/wave/invoice/payments/create", app.authenticate(app.requireRole(app.createWaveInvoicePayment, owner...)))
+	router.POST("/api/wave/invoice/payments/edit", app.authenticate(app.requireRole(app.editWaveInvoicePayment, owner...)))
+	router.POST("/api/wave/invoice/payments/delete", app.authenticate(app.requireRole(app.deleteWaveInvoicePayment, owner...)))
 
 	// wave business account routes
-	router.POST("/api/wave/accounts/query", app.authenticate(app.queryWaveBusinessAccounts))
+	router.POST("/api/wave/accounts/query", app.authenticate(app.requireRole(app.queryWaveBusinessAccounts, owner...)))
 
 	router.POST("/api/schedule/pdf", app.authenticate(app.getSchedulePDF))
 
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"log"
+	"prime-shine-api/internal/data"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Logs the schedules that do not start on the configured first day of the week, e.g. from before it was configured.
+// No schedule can be created in a week they cover, so each one should be moved (see /api/schedule/edit) or deleted.
+func reportMisalignedSchedules(logger *log.Logger, conn *sqlx.DB, weekStart time.Weekday) error {
+	schedules, err := data.QueryMisalignedSchedules(context.Background(), conn, weekStart)
+	if err != nil {
+		return errors.Wrap(err, "QueryMisalignedSchedules")
+	}
+
+	for _, schedule := range schedules {
+		logger.Printf(
+			"Schedule %v of user %v starts on %v, not %v; move it to the start of its week",
+			schedule.ID,
+			schedule.UserID,
+			schedule.StartDay.Time.Format(time.DateOnly),
+			weekStart,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"prime-shine-api/internal/assert"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var warningsWeek = time.Date(2025, 8, 11, 0, 0, 0, 0, time.UTC)
+
+func testVisit(id int, customerID string, dayOffset int, startHour int, endHour int) *data.ScheduledCustomer {
+	day := warningsWeek.AddDate(0, 0, dayOffset)
+
+	return &data.ScheduledCustomer{
+		ID:         id,
+		CustomerID: customerID,
+		StartTime:  db.GetTimestamptzFromTimeStruct(day.Add(time.Duration(startHour) * time.Hour)),
+		EndTime:    db.GetTimestamptzFromTimeStruct(day.Add(time.Duration(endHour) * time.Hour)),
+		DayOffset:  dayOffset,
+	}
+}
+
+func TestValidateScheduledCustomer(t *testing.T) {
+	start := db.GetTimestamptzFromTimeStruct(warningsWeek.Add(9 * time.Hour))
+	end := db.GetTimestamptzFromTimeStruct(warningsWeek.Add(11 * time.Hour))
+
+	assert.Equal(t, data.ValidateScheduledCustomer(start, end, 0), nil)
+	assert.Equal(t, data.ValidateScheduledCustomer(start, end, 6), nil)
+
+	assert.NotEqual(t, data.ValidateScheduledCustomer(end, start, 0), nil)
+	assert.NotEqual(t, data.ValidateScheduledCustomer(start, start, 0), nil)
+	assert.NotEqual(t, data.ValidateScheduledCustomer(start, end, -1), nil)
+	assert.NotEqual(t, data.ValidateScheduledCustomer(start, end, 7), nil)
+	assert.NotEqual(t, data.ValidateScheduledCustomer(start, pgtype.Timestamptz{}, 0), nil)
+}
+
+func TestOverlapWarnings(t *testing.T) {
+	visits := []*data.ScheduledCustomer{
+		testVisit(1, "a", 0, 9, 11),
+		testVisit(2, "b", 0, 10, 12),
+		testVisit(3, "c", 0, 11, 13), // touches the first visit, which is not an overlap
+		testVisit(4, "a", 1, 9, 11),  // same times as the first visit, on another day
+		testVisit(5, "a", 1, 10, 11),
+	}
+
+	warnings := data.OverlapWarnings(visits, nil)
+	assert.Equal(t, len(warnings), 3)
+
+	assert.Equal(t, warnings[0].Code, data.WARNING_OVERLAP)
+	assert.Equal(t, warnings[0].ScheduledCustomerIDs[0], 1)
+	assert.Equal(t, warnings[0].ScheduledCustomerIDs[1], 2)
+
+	assert.Equal(t, warnings[1].Code, data.WARNING_OVERLAP)
+	assert.Equal(t, warnings[1].ScheduledCustomerIDs[0], 2)
+	assert.Equal(t, warnings[1].ScheduledCustomerIDs[1], 3)
+
+	assert.Equal(t, warnings[2].Code, data.WARNING_CUSTOMER_OVERLAP)
+	assert.Equal(t, warnings[2].ScheduledCustomerIDs[0], 4)
+	assert.Equal(t, warnings[2].ScheduledCustomerIDs[1], 5)
+
+	// visits that both have someone to clean them can overlap
+	assignments := []*data.ScheduledCustomerAssignment{
+		{ScheduledCustomerID: 1, CrewIDs: []int{1}, EmployeeIDs: []int{}},
+		{ScheduledCustomerID: 2, CrewIDs: []int{}, EmployeeIDs: []int{1}},
+	}
+
+	warnings = data.OverlapWarnings(visits, assignments)
+	assert.Equal(t, len(warnings), 2)
+	assert.Equal(t, warnings[0].ScheduledCustomerIDs[0], 2)
+	assert.Equal(t, warnings[0].ScheduledCustomerIDs[1], 3)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"prime-shine-api/internal/data"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type assignScheduledCustomerBody struct {
+	ScheduledCustomerID int   `json:"scheduledCustomerID"`
+	CrewIDs             []int `json:"crewIDs"`
+	EmployeeIDs         []int `json:"employeeIDs"`
+}
+
+// Route for setting the crews and employees that clean a scheduled customer.
+func (app *application) assignScheduledCustomer(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body assignScheduledCustomerBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	accountID := app.contextGetAccountID(r)
+
+	tx := app.contextGetTx(r)
+
+	before, err := data.FindOneScheduledCustomerAssignment(r.Context(), tx, accountID, body.ScheduledCustomerID)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, "Could not find scheduled customer.")
+		return
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "FindOneScheduledCustomerAssignment")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	assignment, err := data.AssignScheduledCustomer(
+		r.Context(),
+		tx,
+		accountID,
+		body.ScheduledCustomerID,
+		body.CrewIDs,
+		body.EmployeeIDs,
+		app.config.timeZone,
+	)
+
+	var doubleBooking *data.DoubleBookingError
+	if errors.As(err, &doubleBooking) {
+		app.doubleBookingResponse(w, r, doubleBooking)
+		return
+	}
+
+	var unavailable *data.EmployeeUnavailableError
+	if errors.As(err, &unavailable) {
+		app.employeeUnavailableResponse(w, r, unavailable)
+		return
+	}
+
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, "Could not find crew or employee.")
+		return
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "AssignScheduledCustomer")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = app.recordAudit(tx, r, data.AUDIT_ACTION_EDIT, data.AUDIT_ENTITY_ASSIGNMENT, assignment.ScheduledCustomerID, before, assignment)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+		return
+	}
+
+	data := jsondata{"assignment": assignment}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		err = errors.Wrap(err, "writeJSON")
+		app.serverErrorResponse(w, r, err)
+	}
+}
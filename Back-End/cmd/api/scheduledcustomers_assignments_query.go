@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"prime-shine-api/internal/data"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type queryScheduledCustomerAssignmentsBody struct {
+	ScheduleID int `json:"scheduleID"`
+}
+
+// Route for querying who cleans each scheduled customer of a schedule.
+func (app *application) queryScheduledCustomerAssignments(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body queryScheduledCustomerAssignmentsBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	accountID := app.contextGetAccountID(r)
+	assignments, err := data.QueryScheduledCustomerAssignments(r.Context(), app.db, accountID, body.ScheduleID)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, "Could not find schedule.")
+		return
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "QueryScheduledCustomerAssignments")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data := jsondata{"assignments": assignments}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		err = errors.Wrap(err, "writeJSON")
+		app.serverErrorResponse(w, r, err)
+	}
+}
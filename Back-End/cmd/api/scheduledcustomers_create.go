@@ -30,26 +30,14 @@ func (app *application) createScheduledCustomer(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// TODO: move this to middleware
-	lazyTx := db.NewLazyTx(app.db)
-	defer func() {
-		if rec := recover(); rec != nil {
-			_ = lazyTx.Rollback()
-			err = errors.Errorf("%v", rec)
-			app.serverErrorResponse(w, r, err)
-		} else if r.Context().Err() != nil {
-			// req is cancelled by client, timeout, or app ctx cancelled.
-			_ = lazyTx.Rollback()
-		} else {
-			if err := lazyTx.Commit(); err != nil {
-				err = errors.New("Transaction failed to commit")
-				app.serverErrorResponse(w, r, err)
-			}
-		}
-	}()
+	accountID := app.contextGetAccountID(r)
+
+	tx := app.contextGetTx(r)
 
 	scheduledCustomer, err := data.CreateScheduledCustomer(
-		lazyTx,
+		r.Context(),
+		tx,
+		accountID,
 		body.CustomerID,
 		db.GetTimestamptzFromTimeStruct(body.StartTime),
 		db.GetTimestamptzFromTimeStruct(body.EndTime),
@@ -57,13 +45,31 @@ func (app *application) createScheduledCustomer(w http.ResponseWriter, r *http.R
 		body.ScheduleID,
 	)
 
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, "Could not find schedule.")
+		return
+	}
+
 	if err != nil {
 		err = errors.Wrap(err, "CreateScheduledCustomer")
 		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	data := jsondata{"scheduledCustomer": scheduledCustomer}
+	err = app.recordAudit(tx, r, data.AUDIT_ACTION_CREATE, data.AUDIT_ENTITY_SCHEDULED_CUSTOMER, scheduledCustomer.ID, nil, scheduledCustomer)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+		return
+	}
+
+	// overlaps are allowed, but the front-end shows them so that they are not made by mistake
+	warnings, err := data.QueryScheduledCustomerWarnings(r.Context(), tx, accountID, scheduledCustomer)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "QueryScheduledCustomerWarnings"))
+		return
+	}
+
+	data := jsondata{"scheduledCustomer": scheduledCustomer, "warnings": warnings}
 	err = app.writeJSON(w, http.StatusOK, data, nil)
 	if err != nil {
 		err = errors.Wrap(err, "writeJSON")
@@ -25,33 +25,39 @@ func (app *application) deleteScheduledCustomer(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// TODO: move this to middleware
-	lazyTx := db.NewLazyTx(app.db)
-	defer func() {
-		if rec := recover(); rec != nil {
-			_ = lazyTx.Rollback()
-			err = errors.Errorf("%v", rec)
-			app.serverErrorResponse(w, r, err)
-		} else if r.Context().Err() != nil {
-			// req is cancelled by client, timeout, or app ctx cancelled.
-			_ = lazyTx.Rollback()
-		} else {
-			if err := lazyTx.Commit(); err != nil {
-				err = errors.New("Transaction failed to commit")
-				app.serverErrorResponse(w, r, err)
-			}
-		}
-	}()
-
-	success, err := data.DeleteScheduledCustomer(lazyTx, body.ScheduledCustomerID)
+	accountID := app.contextGetAccountID(r)
+
+	tx := app.contextGetTx(r)
+
+	query := db.Where(data.ScheduledCustomerColumns.ID.Eq(body.ScheduledCustomerID))
+	before, err := data.FindOneScheduledCustomer(r.Context(), tx, query)
 	if err != nil {
-		err = errors.Wrap(err, "EditScheduledCustomer")
+		err = errors.Wrap(err, "FindOneScheduledCustomer")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	success, err := data.DeleteScheduledCustomer(r.Context(), tx, accountID, body.ScheduledCustomerID)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, "Could not find scheduled customer.")
+		return
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "DeleteScheduledCustomer")
 		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	if !success {
 		app.errorResponse(w, r, http.StatusBadRequest, "Unable to delete scheduled customer.")
+		return
+	}
+
+	err = app.recordAudit(tx, r, data.AUDIT_ACTION_DELETE, data.AUDIT_ENTITY_SCHEDULED_CUSTOMER, body.ScheduledCustomerID, before, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+		return
 	}
 
 	data := jsondata{"success": success}
@@ -31,26 +31,22 @@ func (app *application) editScheduledCustomer(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// TODO: move this to middleware
-	lazyTx := db.NewLazyTx(app.db)
-	defer func() {
-		if rec := recover(); rec != nil {
-			_ = lazyTx.Rollback()
-			err = errors.Errorf("%v", rec)
-			app.serverErrorResponse(w, r, err)
-		} else if r.Context().Err() != nil {
-			// req is cancelled by client, timeout, or app ctx cancelled.
-			_ = lazyTx.Rollback()
-		} else {
-			if err := lazyTx.Commit(); err != nil {
-				err = errors.New("Transaction failed to commit")
-				app.serverErrorResponse(w, r, err)
-			}
-		}
-	}()
+	accountID := app.contextGetAccountID(r)
+
+	tx := app.contextGetTx(r)
+
+	query := db.Where(data.ScheduledCustomerColumns.ID.Eq(body.ScheduledCustomerID))
+	before, err := data.FindOneScheduledCustomer(r.Context(), tx, query)
+	if err != nil {
+		err = errors.Wrap(err, "FindOneScheduledCustomer")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 
 	scheduledCustomer, err := data.EditScheduledCustomer(
-		lazyTx,
+		r.Context(),
+		tx,
+		accountID,
 		body.ScheduledCustomerID,
 		body.ScheduleID,
 		body.DayOffset,
@@ -59,13 +55,37 @@ func (app *application) editScheduledCustomer(w http.ResponseWriter, r *http.Req
 		db.GetTimestamptzFromTimeStruct(body.EndTime),
 	)
 
+	var doubleBooking *data.DoubleBookingError
+	if errors.As(err, &doubleBooking) {
+		app.doubleBookingResponse(w, r, doubleBooking)
+		return
+	}
+
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, "Could not find scheduled customer.")
+		return
+	}
+
 	if err != nil {
 		err = errors.Wrap(err, "EditScheduledCustomer")
 		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	data := jsondata{"scheduledCustomer": scheduledCustomer}
+	err = app.recordAudit(tx, r, data.AUDIT_ACTION_EDIT, data.AUDIT_ENTITY_SCHEDULED_CUSTOMER, body.ScheduledCustomerID, before, scheduledCustomer)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+		return
+	}
+
+	// overlaps are allowed, but the front-end shows them so that they are not made by mistake
+	warnings, err := data.QueryScheduledCustomerWarnings(r.Context(), tx, accountID, scheduledCustomer)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "QueryScheduledCustomerWarnings"))
+		return
+	}
+
+	data := jsondata{"scheduledCustomer": scheduledCustomer, "warnings": warnings}
 	err = app.writeJSON(w, http.StatusOK, data, nil)
 	if err != nil {
 		err = errors.Wrap(err, "writeJSON")
@@ -25,7 +25,13 @@ func (app *application) queryScheduledCustomers(w http.ResponseWriter, r *http.R
 	}
 
 	// TODO: grab linked wave customers here also
-	scheduledCustomers, err := data.QueryScheduledCustomers(app.db, body.ScheduleID)
+	accountID := app.contextGetAccountID(r)
+	scheduledCustomers, err := data.QueryScheduledCustomers(r.Context(), app.db, accountID, body.ScheduleID)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, "Could not find schedule.")
+		return
+	}
+
 	if err != nil {
 		err = errors.Wrap(err, "QueryScheduledCustomers")
 		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type copyScheduleBody struct {
+	ScheduleID   int       `json:"scheduleID"`
+	StartDay     time.Time `json:"startDay"`     // of the schedule to copy into
+	ConflictMode string    `json:"conflictMode"` // defaults to failing on conflicts
+	DryRun       bool      `json:"dryRun"`
+}
+
+// Route for copying the visits of a schedule into another week's schedule.
+// A dry run returns what copying would do without changing anything.
+func (app *application) copySchedule(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body copyScheduleBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if body.ConflictMode == "" {
+		body.ConflictMode = data.COPY_CONFLICT_FAIL
+	}
+
+	accountID := app.contextGetAccountID(r)
+
+	tx := app.contextGetTx(r)
+
+	scheduleCopy, err := data.CopySchedule(
+		r.Context(),
+		tx,
+		accountID,
+		body.ScheduleID,
+		db.GetDateFromTimeStruct(body.StartDay),
+		app.config.weekStart,
+		app.config.timeZone,
+		body.ConflictMode,
+		body.DryRun,
+	)
+
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, "Could not find schedule.")
+		return
+	}
+
+	var doubleBooking *data.DoubleBookingError
+	if errors.As(err, &doubleBooking) {
+		app.doubleBookingResponse(w, r, doubleBooking)
+		return
+	}
+
+	if errors.Is(err, data.ErrScheduleExists) {
+		app.errorResponse(w, r, http.StatusConflict, data.ErrScheduleExists.Error())
+		return
+	}
+
+	if errors.Is(err, data.ErrScheduleCopyConflict) {
+		app.errorResponse(w, r, http.StatusConflict, data.ErrScheduleCopyConflict.Error())
+		return
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "CopySchedule")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !body.DryRun {
+		err = app.recordScheduleCopy(tx, r, scheduleCopy)
+		if err != nil {
+			app.serverErrorResponse(w, r, errors.Wrap(err, "recordScheduleCopy"))
+			return
+		}
+	}
+
+	data := jsondata{"scheduleCopy": scheduleCopy}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		err = errors.Wrap(err, "writeJSON")
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// Audits the schedule and visits that a copy created or overwrote.
+func (app *application) recordScheduleCopy(tx db.WriteDBExecutor, r *http.Request, scheduleCopy *data.ScheduleCopy) error {
+	if scheduleCopy.CreatedSchedule {
+		err := app.recordAudit(tx, r, data.AUDIT_ACTION_CREATE, data.AUDIT_ENTITY_SCHEDULE, scheduleCopy.Schedule.ID, nil, scheduleCopy.Schedule)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range scheduleCopy.Entries {
+		var err error
+
+		switch entry.Action {
+		case data.COPY_ACTION_CREATE:
+			err = app.recordAudit(tx, r, data.AUDIT_ACTION_CREATE, data.AUDIT_ENTITY_SCHEDULED_CUSTOMER, entry.Target.ID, nil, entry.Target)
+		case data.COPY_ACTION_OVERWRITE:
+			err = app.recordAudit(tx, r, data.AUDIT_ACTION_EDIT, data.AUDIT_ENTITY_SCHEDULED_CUSTOMER, entry.Target.ID, entry.Conflict, entry.Target)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -27,37 +27,58 @@ func (app *application) createSchedule(w http.ResponseWriter, r *http.Request, _
 		return
 	}
 
-	// TODO: move this to middleware
-	lazyTx := db.NewLazyTx(app.db)
-	defer func() {
-		if rec := recover(); rec != nil {
-			_ = lazyTx.Rollback()
-			err = errors.Errorf("%v", rec)
-			app.serverErrorResponse(w, r, err)
-		} else if r.Context().Err() != nil {
-			// req is cancelled by client, timeout, or app ctx cancelled.
-			_ = lazyTx.Rollback()
-		} else {
-			if err := lazyTx.Commit(); err != nil {
-				err = errors.New("Transaction failed to commit")
-				app.serverErrorResponse(w, r, err)
-			}
-		}
-	}()
+	// older clients send their own user ID, which is not the account's for users who joined it
+	if body.UserID != 0 && body.UserID != app.contextGetUserID(r) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	accountID := app.contextGetAccountID(r)
+
+	tx := app.contextGetTx(r)
 
 	schedule, err := data.CreateSchedule(
-		lazyTx,
+		r.Context(),
+		tx,
 		db.GetDateFromTimeStruct(body.StartDay),
-		body.UserID,
+		app.config.weekStart,
+		app.config.timeZone,
+		accountID,
 	)
 
+	if errors.Is(err, data.ErrScheduleExists) {
+		app.errorResponse(w, r, http.StatusConflict, data.ErrScheduleExists.Error())
+		return
+	}
+
 	if err != nil {
 		err = errors.Wrap(err, "CreateSchedule")
 		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	data := jsondata{"schedule": schedule}
+	err = app.recordAudit(tx, r, data.AUDIT_ACTION_CREATE, data.AUDIT_ENTITY_SCHEDULE, schedule.ID, nil, schedule)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+		return
+	}
+
+	// the schedule starts out with the user's recurring services in it
+	scheduledCustomers, err := data.QueryScheduledCustomers(r.Context(), tx, accountID, schedule.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "QueryScheduledCustomers"))
+		return
+	}
+
+	for _, scheduledCustomer := range scheduledCustomers {
+		err = app.recordAudit(tx, r, data.AUDIT_ACTION_CREATE, data.AUDIT_ENTITY_SCHEDULED_CUSTOMER, scheduledCustomer.ID, nil, scheduledCustomer)
+		if err != nil {
+			app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+			return
+		}
+	}
+
+	data := jsondata{"schedule": schedule, "scheduledCustomers": scheduledCustomers}
 	err = app.writeJSON(w, http.StatusOK, data, nil)
 	if err != nil {
 		err = errors.Wrap(err, "writeJSON")
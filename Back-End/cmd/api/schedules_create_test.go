@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/assert"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
+	"prime-shine-api/internal/mocks"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestCreateScheduleOtherUser(t *testing.T) {
+	app := application{
+		logger:           mocks.Logger(),
+		isSessionRevoked: mocks.SessionNotRevoked,
+	}
+	rr := httptest.NewRecorder()
+
+	body := strings.NewReader(`{"userID": 2, "startDay": "2025-08-11T00:00:00Z"}`)
+	r, err := http.NewRequest(http.MethodPost, "/api/schedule/create", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := internal.CreateToken("1", "1", internal.ROLE_OWNER, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Header.Add("Authorization", token)
+	app.authenticate(app.createSchedule)(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusForbidden)
+}
+
+func TestNormalizeStartDay(t *testing.T) {
+	monday := time.Date(2025, 8, 11, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		day       time.Time
+		weekStart time.Weekday
+		want      time.Time
+	}{
+		{"start of the week", monday, time.Monday, monday},
+		{"middle of the week", monday.AddDate(0, 0, 3), time.Monday, monday},
+		{"end of the week", monday.AddDate(0, 0, 6), time.Monday, monday},
+		{"time of day is dropped", monday.Add(15 * time.Hour), time.Monday, monday},
+		{"weeks starting on Sunday", monday.AddDate(0, 0, 2), time.Sunday, monday.AddDate(0, 0, -1)},
+		{"Sunday when weeks start on Monday", monday.AddDate(0, 0, -1), time.Monday, monday.AddDate(0, 0, -7)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			startDay, err := data.NormalizeStartDay(db.GetDateFromTimeStruct(tt.day), tt.weekStart)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, startDay.Time, tt.want)
+		})
+	}
+
+	_, err := data.NormalizeStartDay(pgtype.Date{}, time.Monday)
+	assert.NotEqual(t, err, nil)
+
+	_, err = data.NormalizeStartDay(db.GetDateFromTimeStruct(time.Time{}), time.Monday)
+	assert.NotEqual(t, err, nil)
+}
+
+func TestParseWeekday(t *testing.T) {
+	weekday, err := data.ParseWeekday("monday")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, weekday, time.Monday)
+
+	weekday, err = data.ParseWeekday("Sunday")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, weekday, time.Sunday)
+
+	_, err = data.ParseWeekday("someday")
+	assert.NotEqual(t, err, nil)
+}
+
+func TestMisalignedSchedules(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.login(t, "owner@example.com", internal.ROLE_OWNER)
+
+	// a Wednesday, as if it was created before weeks started on Monday
+	_, err := ts.db.ExecContext(context.Background(), `
+		INSERT INTO schedules
+		(userid, start_day)
+		VALUES ((SELECT userid FROM users WHERE email = 'owner@example.com'), '2025-08-13')
+	`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var logs strings.Builder
+	err = reportMisalignedSchedules(log.New(&logs, "", 0), ts.db, time.Monday)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, strings.Contains(logs.String(), "2025-08-13"), true)
+
+	// both weeks it covers are taken
+	status, _ := ts.post(t, "/api/schedule/create", token, jsondata{"startDay": "2025-08-11T00:00:00Z"})
+	assert.Equal(t, status, http.StatusConflict)
+
+	status, _ = ts.post(t, "/api/schedule/create", token, jsondata{"startDay": "2025-08-18T00:00:00Z"})
+	assert.Equal(t, status, http.StatusConflict)
+
+	status, _ = ts.post(t, "/api/schedule/create", token, jsondata{"startDay": "2025-08-25T00:00:00Z"})
+	assert.Equal(t, status, http.StatusOK)
+}
@@ -25,31 +25,36 @@ func (app *application) deleteSchedule(w http.ResponseWriter, r *http.Request, _
 		return
 	}
 
-	// TODO: move this to middleware
-	lazyTx := db.NewLazyTx(app.db)
-	defer func() {
-		if rec := recover(); rec != nil {
-			_ = lazyTx.Rollback()
-			err = errors.Errorf("%v", rec)
-			app.serverErrorResponse(w, r, err)
-		} else if r.Context().Err() != nil {
-			// req is cancelled by client, timeout, or app ctx cancelled.
-			_ = lazyTx.Rollback()
-		} else {
-			if err := lazyTx.Commit(); err != nil {
-				err = errors.New("Transaction failed to commit")
-				app.serverErrorResponse(w, r, err)
-			}
-		}
-	}()
-
-	success, err := data.DeleteSchedule(lazyTx, body.ScheduleID)
+	accountID := app.contextGetAccountID(r)
+
+	tx := app.contextGetTx(r)
+
+	query := db.Where(data.ScheduleColumns.ID.Eq(body.ScheduleID))
+	before, err := data.FindOneSchedule(r.Context(), tx, accountID, query)
+	if err != nil {
+		err = errors.Wrap(err, "FindOneSchedule")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	success, err := data.DeleteSchedule(r.Context(), tx, accountID, body.ScheduleID)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, "Could not find schedule.")
+		return
+	}
+
 	if err != nil {
 		err = errors.Wrap(err, "DeleteSchedule")
 		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	err = app.recordAudit(tx, r, data.AUDIT_ACTION_DELETE, data.AUDIT_ENTITY_SCHEDULE, body.ScheduleID, before, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+		return
+	}
+
 	data := jsondata{"success": success}
 	err = app.writeJSON(w, http.StatusOK, data, nil)
 	if err != nil {
@@ -27,31 +27,41 @@ func (app *application) editSchedule(w http.ResponseWriter, r *http.Request, _ h
 		return
 	}
 
-	// TODO: move this to middleware
-	lazyTx := db.NewLazyTx(app.db)
-	defer func() {
-		if rec := recover(); rec != nil {
-			_ = lazyTx.Rollback()
-			err = errors.Errorf("%v", rec)
-			app.serverErrorResponse(w, r, err)
-		} else if r.Context().Err() != nil {
-			// req is cancelled by client, timeout, or app ctx cancelled.
-			_ = lazyTx.Rollback()
-		} else {
-			if err := lazyTx.Commit(); err != nil {
-				err = errors.New("Transaction failed to commit")
-				app.serverErrorResponse(w, r, err)
-			}
-		}
-	}()
-
-	schedule, err := data.EditSchedule(lazyTx, db.GetDateFromTimeStruct(body.StartDay), body.ScheduleID)
+	accountID := app.contextGetAccountID(r)
+
+	tx := app.contextGetTx(r)
+
+	query := db.Where(data.ScheduleColumns.ID.Eq(body.ScheduleID))
+	before, err := data.FindOneSchedule(r.Context(), tx, accountID, query)
+	if err != nil {
+		err = errors.Wrap(err, "FindOneSchedule")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	schedule, err := data.EditSchedule(r.Context(), tx, accountID, db.GetDateFromTimeStruct(body.StartDay), app.config.weekStart, body.ScheduleID)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, "Could not find schedule.")
+		return
+	}
+
+	if errors.Is(err, data.ErrScheduleExists) {
+		app.errorResponse(w, r, http.StatusConflict, data.ErrScheduleExists.Error())
+		return
+	}
+
 	if err != nil {
 		err = errors.Wrap(err, "EditSchedule")
 		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	err = app.recordAudit(tx, r, data.AUDIT_ACTION_EDIT, data.AUDIT_ENTITY_SCHEDULE, schedule.ID, before, schedule)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+		return
+	}
+
 	data := jsondata{"schedule": schedule}
 	err = app.writeJSON(w, http.StatusOK, data, nil)
 	if err != nil {
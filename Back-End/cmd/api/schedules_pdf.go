@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
 	"prime-shine-api/internal/vec2"
 	"prime-shine-api/internal/wave"
 	"strings"
@@ -30,25 +31,31 @@ func (app *application) getSchedulePDF(w http.ResponseWriter, r *http.Request, _
 		return
 	}
 
-	filter := map[string]any{"scheduleid": body.ScheduleID}
-	schedule, err := data.FindOneSchedule(app.db, filter)
+	accountID := app.contextGetAccountID(r)
+	query := db.Where(data.ScheduleColumns.ID.Eq(body.ScheduleID))
+	schedule, err := data.FindOneSchedule(r.Context(), app.db, accountID, query)
 	if err != nil {
 		err = errors.Wrap(err, "FindOneSchedule")
 		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	scheduledCustomers, err := data.QueryScheduledCustomers(app.db, body.ScheduleID)
+	if schedule == nil {
+		app.notFoundResponse(w, r, "Could not find schedule.")
+		return
+	}
+
+	scheduledCustomers, err := data.QueryScheduledCustomers(r.Context(), app.db, accountID, body.ScheduleID)
 	if err != nil {
 		err = errors.Wrap(err, "QueryScheduledCustomers")
 		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	waveCustomers, err := wave.GetAllCustomersWithData(body.BusinessID)
+	waveCustomers, err := app.wave.GetAllCustomersWithData(r.Context(), body.BusinessID)
 	if err != nil {
 		err = errors.Wrap(err, "wave.GetAllCustomersWithData")
-		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		app.waveErrorResponse(w, r, err)
 		return
 	}
 
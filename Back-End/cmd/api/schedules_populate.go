@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type populateScheduleBody struct {
+	ScheduleID int `json:"scheduleID"`
+}
+
+// Route for adding recurring services to an existing schedule, e.g. after new services were set up.
+// Services that are in the schedule already are not added again.
+func (app *application) populateSchedule(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body populateScheduleBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	accountID := app.contextGetAccountID(r)
+
+	tx := app.contextGetTx(r)
+
+	query := db.Where(data.ScheduleColumns.ID.Eq(body.ScheduleID))
+	schedule, err := data.FindOneSchedule(r.Context(), tx, accountID, query)
+	if err != nil {
+		err = errors.Wrap(err, "FindOneSchedule")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if schedule == nil {
+		app.notFoundResponse(w, r, "Could not find schedule.")
+		return
+	}
+
+	scheduledCustomers, err := data.PopulateSchedule(r.Context(), tx, accountID, schedule, app.config.timeZone)
+	if err != nil {
+		err = errors.Wrap(err, "PopulateSchedule")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	for _, scheduledCustomer := range scheduledCustomers {
+		err = app.recordAudit(tx, r, data.AUDIT_ACTION_CREATE, data.AUDIT_ENTITY_SCHEDULED_CUSTOMER, scheduledCustomer.ID, nil, scheduledCustomer)
+		if err != nil {
+			app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+			return
+		}
+	}
+
+	data := jsondata{"scheduledCustomers": scheduledCustomers}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		err = errors.Wrap(err, "writeJSON")
+		app.serverErrorResponse(w, r, err)
+	}
+}
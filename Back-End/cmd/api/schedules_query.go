@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -24,7 +25,15 @@ func (app *application) querySchedules(w http.ResponseWriter, r *http.Request, _
 		return
 	}
 
-	schedules, err := data.QuerySchedules(app.db, body.UserID)
+	// older clients send their own user ID, which is not the account's for users who joined it
+	if body.UserID != 0 && body.UserID != app.contextGetUserID(r) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	accountID := app.contextGetAccountID(r)
+
+	schedules, err := data.QuerySchedules(r.Context(), app.db, accountID)
 	if err != nil {
 		err = errors.Wrap(err, "QuerySchedules")
 		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
@@ -54,8 +63,9 @@ func (app *application) querySchedule(w http.ResponseWriter, r *http.Request, _
 		return
 	}
 
-	filter := map[string]any{"scheduleid": body.ScheduleID}
-	schedule, err := data.FindOneSchedule(app.db, filter)
+	accountID := app.contextGetAccountID(r)
+	query := db.Where(data.ScheduleColumns.ID.Eq(body.ScheduleID))
+	schedule, err := data.FindOneSchedule(r.Context(), app.db, accountID, query)
 	if err != nil {
 		err = errors.Wrap(err, "FindOneSchedule")
 		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
@@ -63,8 +73,7 @@ func (app *application) querySchedule(w http.ResponseWriter, r *http.Request, _
 	}
 
 	if schedule == nil {
-		err = errors.New("could not find schedule")
-		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		app.notFoundResponse(w, r, "Could not find schedule.")
 		return
 	}
 
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/assert"
+	"prime-shine-api/internal/mocks"
+	"strings"
+	"testing"
+)
+
+func TestQuerySchedulesOtherUser(t *testing.T) {
+	app := application{
+		logger:           mocks.Logger(),
+		isSessionRevoked: mocks.SessionNotRevoked,
+	}
+	rr := httptest.NewRecorder()
+
+	body := strings.NewReader(`{"userID": 2}`)
+	r, err := http.NewRequest(http.MethodPost, "/api/schedules/query", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := internal.CreateToken("1", "1", internal.ROLE_OWNER, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Header.Add("Authorization", token)
+	app.authenticate(app.querySchedules)(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusForbidden)
+}
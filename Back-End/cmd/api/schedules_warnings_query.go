@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"prime-shine-api/internal/data"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type queryScheduleWarningsBody struct {
+	ScheduleID int `json:"scheduleID"`
+}
+
+// Route for getting the warnings about a schedule, e.g. visits that overlap.
+func (app *application) queryScheduleWarnings(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body queryScheduleWarningsBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	accountID := app.contextGetAccountID(r)
+
+	warnings, err := data.QueryScheduleWarnings(r.Context(), app.db, accountID, body.ScheduleID)
+
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, "Could not find schedule.")
+		return
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "QueryScheduleWarnings")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data := jsondata{"warnings": warnings}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		err = errors.Wrap(err, "writeJSON")
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"os"
+	"prime-shine-api/internal/testdb"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(testdb.Run(m))
+}
+
+// Connects to a new schema that only this test uses (see testdb.New).
+func newTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	return testdb.New(t)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/mailer"
+	"prime-shine-api/internal/mocks"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// The password of every user created with testServer.login.
+const TEST_PASSWORD = "correct horse battery"
+
+// The whole API, served over HTTP, backed by a schema of its own and a fake Wave.
+type testServer struct {
+	*httptest.Server
+
+	app    *application
+	db     *sqlx.DB
+	wave   *fakeWave
+	mailer *mailer.MemoryMailer
+}
+
+// Starts the API with every route, as main does.
+// Tests that use it are skipped when no test database is available (see newTestDB).
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	conn := newTestDB(t)
+	fake := newFakeWave(t)
+	memoryMailer := mailer.NewMemoryMailer()
+
+	internal.SetJWTKeys(internal.NewHMACKeySet([]byte("test-server-secret")))
+	t.Cleanup(func() { internal.SetJWTKeys(nil) })
+
+	// a zone with daylight saving time, so that calendar math is tested across DST changes
+	timeZone, err := time.LoadLocation("America/Toronto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := &application{
+		config: config{
+			frontendURL:    "http://frontend.test",
+			passwordPolicy: internal.PasswordPolicy{MinLength: 10},
+			timeZone:       timeZone,
+			weekStart:      time.Monday,
+		},
+		logger: mocks.Logger(),
+		db:     conn,
+		mailer: memoryMailer,
+		wave:   fake.Client(FAKE_WAVE_TOKEN),
+	}
+
+	app.isSessionRevoked = func(ctx context.Context, sessionID int) (bool, error) {
+		return data.IsSessionRevoked(ctx, app.db, sessionID)
+	}
+
+	server := httptest.NewServer(app.routes())
+	t.Cleanup(server.Close)
+
+	return &testServer{Server: server, app: app, db: conn, wave: fake, mailer: memoryMailer}
+}
+
+// Creates a user with the given role and logs them in, returning their JWT.
+func (ts *testServer) login(t *testing.T, email string, role string) string {
+	t.Helper()
+
+	hashedPassword, err := internal.HashPassword(TEST_PASSWORD)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ts.db.ExecContext(context.Background(), `
+		INSERT INTO users
+		(name, email, password, role, email_verified_at)
+		VALUES ('Test User', $1, $2, $3, now())
+	`, email, string(hashedPassword), role)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, body := ts.post(t, "/api/login", "", jsondata{"email": email, "password": TEST_PASSWORD})
+	if status != http.StatusOK {
+		t.Fatalf("login failed with %v: %v", status, body)
+	}
+
+	var login struct {
+		JWT string `json:"jwt"`
+	}
+
+	decodeTestBody(t, body, &login)
+
+	return login.JWT
+}
+
+// Posts a JSON body to a route, and returns the status and the response body.
+func (ts *testServer) post(t *testing.T, path string, token string, body any) (int, []byte) {
+	t.Helper()
+
+	requestBody, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := http.NewRequest(http.MethodPost, ts.URL+path, bytes.NewReader(requestBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rs, err := ts.Client().Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rs.Body.Close()
+
+	responseBody, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rs.StatusCode, responseBody
+}
+
+func decodeTestBody(t *testing.T, body []byte, dest any) {
+	t.Helper()
+
+	if err := json.Unmarshal(body, dest); err != nil {
+		t.Fatalf("could not decode %s: %v", body, err)
+	}
+}
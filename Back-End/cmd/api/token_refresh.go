@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type refreshTokenBody struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// Route for exchanging a refresh token for a new JSON Web Token.
+// The refresh token is rotated, so the client must store the one that is returned.
+func (app *application) refreshToken(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body refreshTokenBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx := app.contextGetTx(r)
+
+	session, refreshToken, err := data.RotateSession(r.Context(), tx, body.RefreshToken)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.errorResponse(w, r, http.StatusUnauthorized, "Unauthorized.")
+		return
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "RotateSession")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// The role is looked up again so that role changes apply on the next refresh.
+	query := db.Where(data.UserColumns.ID.Eq(session.UserID))
+	user, err := data.FindOneUser(r.Context(), tx, query)
+	if err != nil {
+		err = errors.Wrap(err, "FindOneUser")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if user == nil {
+		app.errorResponse(w, r, http.StatusUnauthorized, "Unauthorized.")
+		return
+	}
+
+	token, err := internal.CreateToken(strconv.Itoa(user.ID), strconv.Itoa(user.AccountID()), user.Role, session.ID)
+	if err != nil {
+		err = errors.Wrap(err, "CreateToken")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := jsondata{"jwt": token, "refreshToken": refreshToken}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		err = errors.Wrap(err, "writeJSON")
+		app.serverErrorResponse(w, r, err)
+	}
+}
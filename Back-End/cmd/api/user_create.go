@@ -5,7 +5,6 @@ import (
 	"net/http"
 	"prime-shine-api/internal"
 	"prime-shine-api/internal/data"
-	"prime-shine-api/internal/db"
 	"prime-shine-api/internal/wave"
 	"strconv"
 
@@ -14,12 +13,12 @@ import (
 )
 
 type createUserBody struct {
+	Token    string `json:"token"`
 	Name     string `json:"name"`
-	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
-// Route for creating a user.
+// Route for creating a user from an invitation.
 func (app *application) createUser(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	var body createUserBody
 	err := json.NewDecoder(r.Body).Decode(&body)
@@ -30,46 +29,63 @@ func (app *application) createUser(w http.ResponseWriter, r *http.Request, _ htt
 		return
 	}
 
-	// TODO: move this to middleware
-	lazyTx := db.NewLazyTx(app.db)
-	defer func() {
-		if rec := recover(); rec != nil {
-			_ = lazyTx.Rollback()
-			err = errors.Errorf("%v", rec)
-			app.serverErrorResponse(w, r, err)
-		} else if r.Context().Err() != nil {
-			// req is cancelled by client, timeout, or app ctx cancelled.
-			_ = lazyTx.Rollback()
-		} else {
-			if err := lazyTx.Commit(); err != nil {
-				err = errors.New("Transaction failed to commit")
-				app.serverErrorResponse(w, r, err)
-			}
-		}
-	}()
+	if body.Token == "" {
+		app.errorResponse(w, r, http.StatusBadRequest, "An invitation is required to register.")
+		return
+	}
+
+	err = app.config.passwordPolicy.Validate(body.Password)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx := app.contextGetTx(r)
+
+	user, err := data.CreateUser(r.Context(), tx, body.Token, body.Name, body.Password)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.errorResponse(w, r, http.StatusBadRequest, "Invitation is invalid or has expired.")
+		return
+	}
 
-	user, err := data.CreateUser(lazyTx, body.Name, body.Email, body.Password)
 	if err != nil {
 		err = errors.Wrap(err, "CreateUser")
 		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	businessInfo, err := wave.GetBusinessInfo()
+	businessInfo, err := app.wave.GetBusinessInfo(r.Context())
+	if errors.Is(err, wave.ErrUnavailable) {
+		app.waveUnavailableResponse(w, r)
+		return
+	}
+
 	if err != nil {
 		err = errors.Wrap(err, "GetBusinessInfo")
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	jwt, err := internal.CreateToken(strconv.Itoa(user.ID))
+	session, refreshToken, err := data.CreateSession(r.Context(), tx, user.ID)
+	if err != nil {
+		err = errors.Wrap(err, "CreateSession")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	jwt, err := internal.CreateToken(strconv.Itoa(user.ID), strconv.Itoa(user.AccountID()), user.Role, session.ID)
 	if err != nil {
 		err = errors.Wrap(err, "CreateToken")
 		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	data := jsondata{"user": user, "businessInfo": businessInfo, "jwt": jwt}
+	data := jsondata{
+		"user":         user,
+		"businessInfo": businessInfo,
+		"jwt":          jwt,
+		"refreshToken": refreshToken,
+	}
 	err = app.writeJSON(w, http.StatusOK, data, nil)
 	if err != nil {
 		err = errors.Wrap(err, "writeJSON")
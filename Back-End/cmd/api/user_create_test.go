@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"prime-shine-api/internal/assert"
+	"prime-shine-api/internal/mocks"
+	"strings"
+	"testing"
+)
+
+func TestCreateUserWithoutInvitation(t *testing.T) {
+	app := application{
+		logger: mocks.Logger(),
+	}
+	rr := httptest.NewRecorder()
+
+	body := strings.NewReader(`{"name": "Mallory", "email": "mallory@fakeuser.com", "password": "password1234"}`)
+	r, err := http.NewRequest(http.MethodPost, "/api/register", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.createUser(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusBadRequest)
+}
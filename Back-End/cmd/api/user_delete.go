@@ -25,25 +25,34 @@ func (app *application) deleteUser(w http.ResponseWriter, r *http.Request, _ htt
 		return
 	}
 
-	// TODO: move this to middleware
-	lazyTx := db.NewLazyTx(app.db)
-	defer func() {
-		if rec := recover(); rec != nil {
-			_ = lazyTx.Rollback()
-			err = errors.Errorf("%v", rec)
-			app.serverErrorResponse(w, r, err)
-		} else if r.Context().Err() != nil {
-			// req is cancelled by client, timeout, or app ctx cancelled.
-			_ = lazyTx.Rollback()
-		} else {
-			if err := lazyTx.Commit(); err != nil {
-				err = errors.New("Transaction failed to commit")
-				app.serverErrorResponse(w, r, err)
-			}
-		}
-	}()
-
-	success, err := data.DeleteUser(lazyTx, body.UserID)
+	tx := app.contextGetTx(r)
+
+	query := db.Where(data.UserColumns.ID.Eq(body.UserID))
+	before, err := data.FindOneUser(r.Context(), tx, query)
+	if err != nil {
+		err = errors.Wrap(err, "FindOneUser")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if before == nil {
+		app.notFoundResponse(w, r, "Could not find user.")
+		return
+	}
+
+	// recorded first, since users who delete themselves can not be the actor of a later entry
+	err = app.recordAudit(tx, r, data.AUDIT_ACTION_DELETE, data.AUDIT_ENTITY_USER, before.ID, before, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+		return
+	}
+
+	success, err := data.DeleteUser(r.Context(), tx, body.UserID)
+	if errors.Is(err, data.ErrAccountHasMembers) {
+		app.errorResponse(w, r, http.StatusConflict, err.Error())
+		return
+	}
+
 	if err != nil {
 		err = errors.Wrap(err, "DeleteUser")
 		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
@@ -11,13 +11,15 @@ import (
 )
 
 type editUserBody struct {
-	UserID   int    `json:"userID"`
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	UserID   int     `json:"userID"`
+	Name     *string `json:"name"`
+	Email    *string `json:"email"`
+	Password *string `json:"password"`
 }
 
-// Route for editing a user.
+// Route for editing a user's profile.
+// Only the fields present in the body are changed.
+// A new email has to be verified through the link that is sent to it.
 func (app *application) editUser(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	var body editUserBody
 	err := json.NewDecoder(r.Body).Decode(&body)
@@ -28,31 +30,55 @@ func (app *application) editUser(w http.ResponseWriter, r *http.Request, _ httpr
 		return
 	}
 
-	// TODO: move this to middleware
-	lazyTx := db.NewLazyTx(app.db)
-	defer func() {
-		if rec := recover(); rec != nil {
-			_ = lazyTx.Rollback()
-			err = errors.Errorf("%v", rec)
-			app.serverErrorResponse(w, r, err)
-		} else if r.Context().Err() != nil {
-			// req is cancelled by client, timeout, or app ctx cancelled.
-			_ = lazyTx.Rollback()
-		} else {
-			if err := lazyTx.Commit(); err != nil {
-				err = errors.New("Transaction failed to commit")
-				app.serverErrorResponse(w, r, err)
-			}
-		}
-	}()
+	userID := app.contextGetUserID(r)
+	if body.UserID != 0 && body.UserID != userID {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	if body.Password != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, "Passwords are changed through /api/users/password/edit.")
+		return
+	}
+
+	tx := app.contextGetTx(r)
+
+	query := db.Where(data.UserColumns.ID.Eq(userID))
+	before, err := data.FindOneUser(r.Context(), tx, query)
+	if err != nil {
+		err = errors.Wrap(err, "FindOneUser")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	user, err := data.EditUser(r.Context(), tx, userID, body.Name, body.Email)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, "Could not find user.")
+		return
+	}
 
-	user, err := data.EditUser(lazyTx, body.UserID, body.Email, body.Name, body.Password)
 	if err != nil {
 		err = errors.Wrap(err, "EditUser")
 		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	err = app.recordAudit(tx, r, data.AUDIT_ACTION_EDIT, data.AUDIT_ENTITY_USER, user.ID, before, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+		return
+	}
+
+	// only a new email needs verifying; other edits by unverified users do not resend the link
+	if before != nil && user.Email != before.Email {
+		err = app.sendEmailVerification(r, tx, user)
+		if err != nil {
+			err = errors.Wrap(err, "sendEmailVerification")
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
 	data := jsondata{"user": user}
 	err = app.writeJSON(w, http.StatusOK, data, nil)
 	if err != nil {
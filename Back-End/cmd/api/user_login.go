@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"prime-shine-api/internal"
 	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
 	"prime-shine-api/internal/wave"
 	"strconv"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -28,7 +31,26 @@ func (app *application) loginUser(w http.ResponseWriter, r *http.Request, _ http
 		return
 	}
 
-	user, err := data.QueryUserAndPassword(app.db, body.Email, body.Password)
+	tx := app.contextGetTx(r)
+
+	// Failed attempts have to be recorded for throttling, even though they are rejected.
+	app.commitOnClientError(r)
+
+	ipAddress := app.clientIP(r)
+
+	retryAfter, err := app.loginRetryAfter(r.Context(), tx, body.Email, ipAddress)
+	if err != nil {
+		err = errors.Wrap(err, "loginRetryAfter")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if retryAfter > 0 {
+		app.tooManyRequestsResponse(w, r, retryAfter)
+		return
+	}
+
+	user, err := data.QueryUserAndPassword(r.Context(), tx, body.Email, body.Password)
 	if err != nil {
 		err = errors.Wrap(err, "QueryUserAndPassword")
 		app.serverErrorResponse(w, r, err)
@@ -36,28 +58,134 @@ func (app *application) loginUser(w http.ResponseWriter, r *http.Request, _ http
 	}
 
 	if user == nil {
+		err = data.RecordLoginEvent(r.Context(), tx, body.Email, ipAddress, false)
+		if err != nil {
+			err = errors.Wrap(err, "RecordLoginEvent")
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
 		app.errorResponse(w, r, http.StatusBadRequest, "Email or password is invalid.")
 		return
 	}
 
-	businessInfo, err := wave.GetBusinessInfo()
+	// With two-factor authentication the login only succeeds once the code is checked.
+	// Recording the password step as a success would reset the throttle before every challenge.
+	if user.TOTPEnabled {
+		mfaToken, err := data.CreateMFAChallenge(r.Context(), tx, user.ID)
+		if err != nil {
+			err = errors.Wrap(err, "CreateMFAChallenge")
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		data := jsondata{"mfaRequired": true, "mfaToken": mfaToken}
+		err = app.writeJSON(w, http.StatusOK, data, nil)
+		if err != nil {
+			err = errors.Wrap(err, "writeJSON")
+			app.serverErrorResponse(w, r, err)
+		}
+
+		return
+	}
+
+	err = data.RecordLoginEvent(r.Context(), tx, body.Email, ipAddress, true)
+	if err != nil {
+		err = errors.Wrap(err, "RecordLoginEvent")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.startUserSession(w, r, tx, user)
+}
+
+// Starts a login session for a user that has been fully authenticated,
+// and sends the user's info alongside the session tokens to the client.
+func (app *application) startUserSession(w http.ResponseWriter, r *http.Request, tx db.WriteDBExecutor, user *data.User) {
+	businessInfo, err := app.wave.GetBusinessInfo(r.Context())
+	if errors.Is(err, wave.ErrUnavailable) {
+		app.waveUnavailableResponse(w, r)
+		return
+	}
+
 	if err != nil {
 		err = errors.Wrap(err, "GetBusinessInfo")
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	token, err := internal.CreateToken(strconv.Itoa(user.ID))
+	session, refreshToken, err := data.CreateSession(r.Context(), tx, user.ID)
+	if err != nil {
+		err = errors.Wrap(err, "CreateSession")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	token, err := internal.CreateToken(strconv.Itoa(user.ID), strconv.Itoa(user.AccountID()), user.Role, session.ID)
 	if err != nil {
 		err = errors.Wrap(err, "CreateToken")
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	data := jsondata{"user": user, "businessInfo": businessInfo, "jwt": token}
+	data := jsondata{
+		"user":         user,
+		"businessInfo": businessInfo,
+		"jwt":          token,
+		"refreshToken": refreshToken,
+	}
 	err = app.writeJSON(w, http.StatusOK, data, nil)
 	if err != nil {
 		err = errors.Wrap(err, "writeJSON")
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// Computes how long a client has to wait before attempting to log in again.
+// Both the account and the client's IP address are throttled; the longer wait wins.
+// Other attempts for the same email or IP address wait until tx ends, so the attempt has to be
+// recorded in tx for them to count it.
+func (app *application) loginRetryAfter(ctx context.Context, tx db.WriteDBExecutor, email string, ipAddress string) (time.Duration, error) {
+	err := data.LockLoginThrottle(ctx, tx, email, ipAddress)
+	if err != nil {
+		return 0, errors.Wrap(err, "LockLoginThrottle")
+	}
+
+	now := time.Now()
+
+	accountFailures, err := data.CountAccountLoginFailures(
+		ctx,
+		tx,
+		email,
+		now.Add(-internal.ACCOUNT_LOGIN_THROTTLE.LockoutDuration),
+	)
+
+	if err != nil {
+		return 0, errors.Wrap(err, "CountAccountLoginFailures")
+	}
+
+	ipFailures, err := data.CountIPLoginFailures(
+		ctx,
+		tx,
+		ipAddress,
+		now.Add(-internal.IP_LOGIN_THROTTLE.LockoutDuration),
+	)
+
+	if err != nil {
+		return 0, errors.Wrap(err, "CountIPLoginFailures")
+	}
+
+	accountWait := internal.ACCOUNT_LOGIN_THROTTLE.RetryAfter(
+		accountFailures.Count,
+		accountFailures.LastFailure.Time,
+		now,
+	)
+
+	ipWait := internal.IP_LOGIN_THROTTLE.RetryAfter(
+		ipFailures.Count,
+		ipFailures.LastFailure.Time,
+		now,
+	)
+
+	return max(accountWait, ipWait), nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type loginUserMFABody struct {
+	MFAToken     string `json:"mfaToken"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+// Route for completing a login with a TOTP code (or a recovery code).
+func (app *application) loginUserMFA(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body loginUserMFABody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if body.Code == "" && body.RecoveryCode == "" {
+		app.errorResponse(w, r, http.StatusBadRequest, "A code or recovery code is required.")
+		return
+	}
+
+	tx := app.contextGetTx(r)
+
+	// Failed attempts have to be recorded for throttling, even though they are rejected.
+	app.commitOnClientError(r)
+
+	userID, err := data.AttemptMFAChallenge(r.Context(), tx, body.MFAToken)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.errorResponse(w, r, http.StatusUnauthorized, "Login attempt has expired. Please log in again.")
+		return
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "AttemptMFAChallenge")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	query := db.Where(data.UserColumns.ID.Eq(userID))
+	user, err := data.FindOneUser(r.Context(), tx, query)
+	if err != nil {
+		err = errors.Wrap(err, "FindOneUser")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if user == nil || !user.TOTPEnabled {
+		app.errorResponse(w, r, http.StatusUnauthorized, "Login attempt has expired. Please log in again.")
+		return
+	}
+
+	// wrong codes count as failed logins, so that fresh challenges do not allow more guesses
+	ipAddress := app.clientIP(r)
+
+	retryAfter, err := app.loginRetryAfter(r.Context(), tx, user.Email, ipAddress)
+	if err != nil {
+		err = errors.Wrap(err, "loginRetryAfter")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if retryAfter > 0 {
+		app.tooManyRequestsResponse(w, r, retryAfter)
+		return
+	}
+
+	codeValid := false
+	if body.RecoveryCode != "" {
+		codeValid, err = data.UseRecoveryCode(r.Context(), tx, user.ID, body.RecoveryCode)
+		if err != nil {
+			err = errors.Wrap(err, "UseRecoveryCode")
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	} else if step, ok := internal.ValidateTOTP(user.TOTPSecret.String, body.Code, time.Now()); ok {
+		codeValid, err = data.UseTOTPStep(r.Context(), tx, user.ID, step)
+		if err != nil {
+			err = errors.Wrap(err, "UseTOTPStep")
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = data.RecordLoginEvent(r.Context(), tx, user.Email, ipAddress, codeValid)
+	if err != nil {
+		err = errors.Wrap(err, "RecordLoginEvent")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !codeValid {
+		app.errorResponse(w, r, http.StatusBadRequest, "Code is invalid.")
+		return
+	}
+
+	err = data.CompleteMFAChallenge(r.Context(), tx, body.MFAToken)
+	if err != nil {
+		err = errors.Wrap(err, "CompleteMFAChallenge")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.startUserSession(w, r, tx, user)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/assert"
+	"prime-shine-api/internal/mocks"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoginMFANoCode(t *testing.T) {
+	app := application{
+		logger: mocks.Logger(),
+	}
+	rr := httptest.NewRecorder()
+
+	body := strings.NewReader(`{"mfaToken": "abc"}`)
+	r, err := http.NewRequest(http.MethodPost, "/api/login/mfa", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.loginUserMFA(rr, r, nil)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusBadRequest)
+}
+
+func TestValidateTOTP(t *testing.T) {
+	// RFC 6238 test vector (SHA1, T = 59s), truncated to 6 digits
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	now := time.Unix(59, 0)
+
+	step, ok := internal.ValidateTOTP(secret, "287082", now)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, step, int64(1))
+
+	_, ok = internal.ValidateTOTP(secret, "287083", now)
+	assert.Equal(t, ok, false)
+
+	// codes from too far in the past are rejected
+	_, ok = internal.ValidateTOTP(secret, "287082", now.Add(5*time.Minute))
+	assert.Equal(t, ok, false)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/assert"
+	"prime-shine-api/internal/mocks"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTooManyRequestsResponse(t *testing.T) {
+	app := application{
+		logger: mocks.Logger(),
+	}
+	rr := httptest.NewRecorder()
+
+	r, err := http.NewRequest(http.MethodPost, "/api/login", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// partial seconds are rounded up, so the client never retries too early
+	app.tooManyRequestsResponse(rr, r, 1500*time.Millisecond)
+
+	rs := rr.Result()
+
+	assert.Equal(t, rs.StatusCode, http.StatusTooManyRequests)
+	assert.Equal(t, rs.Header.Get("Retry-After"), "2")
+}
+
+func TestLoginThrottled(t *testing.T) {
+	ts := newTestServer(t)
+	ts.login(t, "owner@example.com", internal.ROLE_OWNER)
+
+	for range internal.ACCOUNT_LOGIN_THROTTLE.FreeAttempts {
+		_, err := ts.db.ExecContext(context.Background(), `
+			INSERT INTO login_events
+			(email, ip_address, succeeded)
+			VALUES ('owner@example.com', '203.0.113.7', false)
+		`)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	body, err := json.Marshal(jsondata{"email": "owner@example.com", "password": TEST_PASSWORD})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := ts.Client().Post(ts.URL+"/api/login", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rs.Body.Close()
+
+	// even the right password is refused while throttled
+	assert.Equal(t, rs.StatusCode, http.StatusTooManyRequests)
+
+	retryAfter, err := strconv.Atoi(rs.Header.Get("Retry-After"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	maxWait := internal.ACCOUNT_LOGIN_THROTTLE.Delay(internal.ACCOUNT_LOGIN_THROTTLE.FreeAttempts)
+	assert.Equal(t, retryAfter > 0, true)
+	assert.Equal(t, retryAfter <= int(maxWait.Seconds()), true)
+}
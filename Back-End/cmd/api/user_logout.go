@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"prime-shine-api/internal/data"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+// Route for logging out of the current session.
+func (app *application) logoutUser(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := app.contextGetUserID(r)
+	sessionID := app.contextGetSessionID(r)
+
+	tx := app.contextGetTx(r)
+
+	err := data.RevokeSession(r.Context(), tx, userID, sessionID)
+	if err != nil {
+		err = errors.Wrap(err, "RevokeSession")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := jsondata{"success": true}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "writeJSON"))
+	}
+}
+
+// Route for logging out of every session of the user (i.e. "sign out all devices").
+func (app *application) logoutUserEverywhere(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := app.contextGetUserID(r)
+
+	tx := app.contextGetTx(r)
+
+	err := data.RevokeUserSessions(r.Context(), tx, userID, 0)
+	if err != nil {
+		err = errors.Wrap(err, "RevokeUserSessions")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := jsondata{"success": true}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "writeJSON"))
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type editUserPasswordBody struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+// Route for changing the password of the logged in user.
+// Every other login session of the user is signed out.
+func (app *application) editUserPassword(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body editUserPasswordBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = app.config.passwordPolicy.Validate(body.NewPassword)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID := app.contextGetUserID(r)
+
+	tx := app.contextGetTx(r)
+
+	query := db.Where(data.UserColumns.ID.Eq(userID))
+	user, err := data.FindOneUser(r.Context(), tx, query)
+	if err != nil {
+		err = errors.Wrap(err, "FindOneUser")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if user == nil {
+		app.notFoundResponse(w, r, "Could not find user.")
+		return
+	}
+
+	passwordValid, err := internal.ComparePasswords([]byte(user.Password), []byte(body.CurrentPassword))
+	if err != nil {
+		err = errors.Wrap(err, "ComparePasswords")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !passwordValid {
+		app.errorResponse(w, r, http.StatusBadRequest, "Current password is invalid.")
+		return
+	}
+
+	err = data.SetUserPassword(r.Context(), tx, user.ID, body.NewPassword)
+	if err != nil {
+		err = errors.Wrap(err, "SetUserPassword")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = data.RevokeUserSessions(r.Context(), tx, user.ID, app.contextGetSessionID(r))
+	if err != nil {
+		err = errors.Wrap(err, "RevokeUserSessions")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.recordAudit(tx, r, data.AUDIT_ACTION_EDIT, data.AUDIT_ENTITY_USER_PASSWORD, user.ID, nil, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+		return
+	}
+
+	data := jsondata{"success": true}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "writeJSON"))
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/assert"
+	"prime-shine-api/internal/mocks"
+	"strings"
+	"testing"
+)
+
+func TestEditUserPasswordPolicy(t *testing.T) {
+	app := application{
+		config: config{
+			passwordPolicy: internal.PasswordPolicy{MinLength: 10, RequireDigit: true},
+		},
+		logger:           mocks.Logger(),
+		isSessionRevoked: mocks.SessionNotRevoked,
+	}
+
+	tests := []struct {
+		name     string
+		password string
+	}{
+		{"empty", ""},
+		{"too short", "abc123"},
+		{"no digit", "abcdefghijkl"},
+		{"too long", strings.Repeat("a1", 40)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+
+			body := strings.NewReader(`{"currentPassword": "old", "newPassword": "` + tt.password + `"}`)
+			r, err := http.NewRequest(http.MethodPost, "/api/users/password/edit", body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := internal.CreateToken("1", "1", internal.ROLE_OWNER, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r.Header.Add("Authorization", token)
+			app.authenticate(app.editUserPassword)(rr, r, nil)
+
+			rs := rr.Result()
+
+			assert.Equal(t, rs.StatusCode, http.StatusBadRequest)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+type editUserRoleBody struct {
+	UserID int    `json:"userID"`
+	Role   string `json:"role"`
+}
+
+// Route for changing the role of a user.
+// The user is signed out everywhere, so that no token keeps the old role.
+func (app *application) editUserRole(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body editUserRoleBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil {
+		err = errors.Wrap(err, "json deserialization")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx := app.contextGetTx(r)
+
+	query := db.Where(data.UserColumns.ID.Eq(body.UserID))
+	before, err := data.FindOneUser(r.Context(), tx, query)
+	if err != nil {
+		err = errors.Wrap(err, "FindOneUser")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	user, err := data.EditUserRole(r.Context(), tx, body.UserID, body.Role)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		app.notFoundResponse(w, r, "Could not find user.")
+		return
+	}
+
+	if errors.Is(err, data.ErrLastOwner) {
+		app.errorResponse(w, r, http.StatusConflict, data.ErrLastOwner.Error())
+		return
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "EditUserRole")
+		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// the role is a claim of the user's tokens, which have to be replaced through a new login
+	if before.Role != user.Role {
+		err = data.RevokeUserSessions(r.Context(), tx, user.ID, 0)
+		if err != nil {
+			err = errors.Wrap(err, "RevokeUserSessions")
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.recordAudit(tx, r, data.AUDIT_ACTION_EDIT, data.AUDIT_ENTITY_USER, user.ID, before, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "recordAudit"))
+		return
+	}
+
+	data := jsondata{"user": user}
+	err = app.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "writeJSON"))
+	}
+}
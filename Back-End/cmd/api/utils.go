@@ -2,7 +2,14 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/wave"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -38,6 +45,23 @@ func (app *application) logError(r *http.Request, err error) {
 	app.logger.Printf("request_method: %s, request_url: %s, message: %s", r.Method, r.URL.String(), err.Error())
 }
 
+// Runs fn in a goroutine that shutdown waits for. A panic in fn is logged instead of crashing the server.
+func (app *application) background(r *http.Request, fn func()) {
+	app.backgroundTasks.Add(1)
+
+	go func() {
+		defer app.backgroundTasks.Done()
+
+		defer func() {
+			if rec := recover(); rec != nil {
+				app.logError(r, errors.Errorf("background task: %v", rec))
+			}
+		}()
+
+		fn()
+	}()
+}
+
 // Sends an error response to the client.
 func (app *application) errorResponse(
 	w http.ResponseWriter,
@@ -63,3 +87,175 @@ func (app *application) serverErrorResponse(
 	message := "The server encountered a problem and could not process your request."
 	app.errorResponse(w, r, http.StatusInternalServerError, message)
 }
+
+// Rejects a request whose token is missing or invalid.
+// The code tells the client why, e.g. so it knows to refresh an expired token.
+func (app *application) unauthorizedResponse(w http.ResponseWriter, r *http.Request, code string) {
+	headers := http.Header{}
+	headers.Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="invalid_token", error_description="%v"`, code))
+
+	data := jsondata{"error": "Unauthorized.", "code": code}
+	err := app.writeJSON(w, http.StatusUnauthorized, data, headers)
+	if err != nil {
+		app.logError(r, errors.Wrap(err, "writeJSON"))
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (app *application) forbiddenResponse(w http.ResponseWriter, r *http.Request) {
+	message := "You do not have permission to access this resource."
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (app *application) notFoundResponse(w http.ResponseWriter, r *http.Request, message string) {
+	app.errorResponse(w, r, http.StatusNotFound, message)
+}
+
+func (app *application) tooManyRequestsResponse(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", fmt.Sprint(seconds))
+
+	message := fmt.Sprintf("Too many attempts. Try again in %v seconds.", seconds)
+	app.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
+
+// Rejects an assignment that would put a crew or an employee at two visits at once.
+// The visit they are booked for already is included, so the front-end can point to it.
+func (app *application) doubleBookingResponse(w http.ResponseWriter, r *http.Request, doubleBooking *data.DoubleBookingError) {
+	data := jsondata{
+		"error":      doubleBooking.Error(),
+		"crewID":     doubleBooking.CrewID,
+		"employeeID": doubleBooking.EmployeeID,
+		"conflict":   doubleBooking.Conflict,
+	}
+
+	err := app.writeJSON(w, http.StatusConflict, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "writeJSON"))
+	}
+}
+
+// Rejects an assignment of an employee who is inactive or does not work at the time of the visit.
+func (app *application) employeeUnavailableResponse(w http.ResponseWriter, r *http.Request, unavailable *data.EmployeeUnavailableError) {
+	data := jsondata{
+		"error":      unavailable.Error(),
+		"employeeID": unavailable.EmployeeID,
+		"inactive":   unavailable.Inactive,
+	}
+
+	err := app.writeJSON(w, http.StatusConflict, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "writeJSON"))
+	}
+}
+
+type waveInputErrorResponse struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Sends the response for a failed Wave call.
+// Invalid input is a 422 listing the offending fields, so the front-end can show them next to the form fields;
+// Wave failing on its own end is a 502 or 503.
+func (app *application) waveErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	var inputError *wave.InputError
+	var statusError *wave.StatusError
+	var graphQLErrors wave.GraphQLErrors
+
+	switch {
+	case errors.Is(err, wave.ErrUnavailable):
+		app.waveUnavailableResponse(w, r)
+	case errors.As(err, &inputError):
+		app.waveInvalidInputResponse(w, r, inputError.Errors)
+	case errors.As(err, &statusError):
+		switch {
+		case statusError.StatusCode == http.StatusBadRequest && len(statusError.InputErrors) > 0:
+			app.waveInvalidInputResponse(w, r, statusError.InputErrors)
+		case statusError.StatusCode == http.StatusBadRequest:
+			app.waveBadRequestResponse(w, r, err)
+		case statusError.StatusCode == http.StatusNotFound:
+			app.notFoundResponse(w, r, "Could not find this in Wave.")
+		case statusError.StatusCode == http.StatusTooManyRequests:
+			app.waveUnavailableResponse(w, r)
+		default:
+			// includes 401 and 403, which mean that the Wave token is wrong, not the client
+			app.waveBadGatewayResponse(w, r, err)
+		}
+	case errors.As(err, &graphQLErrors):
+		messages := []jsondata{}
+		for _, graphQLError := range graphQLErrors {
+			if graphQLError.Extensions.Code == "NOT_FOUND" {
+				app.notFoundResponse(w, r, graphQLError.Message)
+				return
+			}
+
+			messages = append(messages, jsondata{"message": graphQLError.Message, "path": graphQLError.Path})
+		}
+
+		data := jsondata{"error": graphQLErrors.Error(), "graphQLErrors": messages}
+		err = app.writeJSON(w, http.StatusBadRequest, data, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, errors.Wrap(err, "writeJSON"))
+		}
+	default:
+		app.waveBadGatewayResponse(w, r, err)
+	}
+}
+
+func (app *application) waveInvalidInputResponse(w http.ResponseWriter, r *http.Request, inputErrors []wave.WaveInputError) {
+	fields := []waveInputErrorResponse{}
+	for _, inputError := range inputErrors {
+		fields = append(fields, waveInputErrorResponse{
+			Field:   inputError.Field(),
+			Code:    inputError.Code,
+			Message: inputError.Message,
+		})
+	}
+
+	data := jsondata{"error": "Wave rejected some of the fields.", "inputErrors": fields}
+	err := app.writeJSON(w, http.StatusUnprocessableEntity, data, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "writeJSON"))
+	}
+}
+
+// Wave failed in a way that the client can do nothing about; the details are only logged.
+func (app *application) waveBadGatewayResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logError(r, err)
+
+	message := "Wave could not process the request."
+	app.errorResponse(w, r, http.StatusBadGateway, message)
+}
+
+// Wave refused the request without saying which fields were wrong.
+// Its response may hold internal details, so it is only logged.
+func (app *application) waveBadRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logError(r, err)
+
+	message := "Wave rejected the request."
+	app.errorResponse(w, r, http.StatusBadRequest, message)
+}
+
+// Tells the client that Wave is down, rather than blaming the request.
+func (app *application) waveUnavailableResponse(w http.ResponseWriter, r *http.Request) {
+	message := "Wave is currently unavailable. Please try again later."
+	app.errorResponse(w, r, http.StatusServiceUnavailable, message)
+}
+
+// Grabs the IP address of the client that made the request.
+// The X-Real-IP header set by the reverse proxy is only honored if the proxy is trusted.
+func (app *application) clientIP(r *http.Request) string {
+	if app.config.trustProxy {
+		if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+			return realIP
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"prime-shine-api/internal/assert"
+	"testing"
+)
+
+func TestClientIPTrustedProxy(t *testing.T) {
+	app := application{
+		config: config{trustProxy: true},
+	}
+
+	r, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.RemoteAddr = "172.18.0.5:41234"
+	r.Header.Set("X-Real-IP", "203.0.113.7")
+
+	assert.Equal(t, app.clientIP(r), "203.0.113.7")
+}
+
+func TestClientIPUntrustedProxy(t *testing.T) {
+	app := application{
+		config: config{trustProxy: false},
+	}
+
+	r, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.RemoteAddr = "172.18.0.5:41234"
+	r.Header.Set("X-Real-IP", "203.0.113.7")
+
+	assert.Equal(t, app.clientIP(r), "172.18.0.5")
+}
@@ -3,7 +3,6 @@ package main
 import (
 	"encoding/json"
 	"net/http"
-	"prime-shine-api/internal/wave"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -24,10 +23,10 @@ func (app *application) queryWaveBusinessAccounts(w http.ResponseWriter, r *http
 		return
 	}
 
-	accounts, err := wave.GetBusinessAccounts(body.IdentityBusinessID)
+	accounts, err := app.wave.GetBusinessAccounts(r.Context(), body.IdentityBusinessID)
 	if err != nil {
 		err = errors.Wrap(err, "GetBusinessAccounts")
-		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		app.waveErrorResponse(w, r, err)
 		return
 	}
 
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"prime-shine-api/internal/assert"
+	"prime-shine-api/internal/mocks"
+	"prime-shine-api/internal/wave"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func testWaveOptions() wave.ClientOptions {
+	return wave.ClientOptions{
+		Timeout:          time.Second,
+		MaxRetries:       2,
+		RetryBaseDelay:   time.Millisecond,
+		RetryMaxDelay:    10 * time.Millisecond,
+		BreakerThreshold: 0,
+	}
+}
+
+// Serves each request with the next handler, repeating the last one once they run out.
+func newScriptedWave(t *testing.T, handlers ...http.HandlerFunc) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit := int(hits.Add(1))
+
+		// once the body is read, the server notices clients hanging up
+		io.Copy(io.Discard, r.Body)
+		handlers[min(hit, len(handlers))-1](w, r)
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server, &hits
+}
+
+func newScriptedWaveClient(server *httptest.Server, options wave.ClientOptions) *wave.Client {
+	return wave.NewClient(server.URL, server.URL, wave.StaticToken(FAKE_WAVE_TOKEN), server.Client(), mocks.Logger(), options)
+}
+
+func respondWith(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+}
+
+func TestWaveClientRetriesQueries(t *testing.T) {
+	server, hits := newScriptedWave(t,
+		respondWith(http.StatusServiceUnavailable, ""),
+		respondWith(http.StatusBadGateway, ""),
+		respondWith(http.StatusOK, `{"accounts": [{"account_name": "Cash on Hand", "active": true}]}`),
+	)
+
+	client := newScriptedWaveClient(server, testWaveOptions())
+
+	accounts, err := client.GetBusinessAccounts(context.Background(), FAKE_WAVE_IDENTITY_BUSINESS_ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(*accounts), 1)
+	assert.Equal(t, hits.Load(), int32(3))
+}
+
+func TestWaveClientGivesUpAfterMaxRetries(t *testing.T) {
+	server, hits := newScriptedWave(t, respondWith(http.StatusServiceUnavailable, ""))
+
+	client := newScriptedWaveClient(server, testWaveOptions())
+
+	_, err := client.GetBusinessAccounts(context.Background(), FAKE_WAVE_IDENTITY_BUSINESS_ID)
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, hits.Load(), int32(3))
+}
+
+func TestWaveClientDoesNotRetryMutations(t *testing.T) {
+	server, hits := newScriptedWave(t, respondWith(http.StatusServiceUnavailable, ""))
+
+	client := newScriptedWaveClient(server, testWaveOptions())
+
+	err := client.CreateCustomer(context.Background(), map[string]any{"name": "Grace Hopper"})
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, hits.Load(), int32(1))
+}
+
+func TestWaveClientRetryAfter(t *testing.T) {
+	tooManyRequests := func(retryAfter string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}
+
+	// mutations are retried on 429 too, since Wave rejected them before doing anything
+	server, hits := newScriptedWave(t,
+		tooManyRequests("0"),
+		respondWith(http.StatusOK, `{"data": {"customerCreate": {"didSucceed": true}}}`),
+	)
+
+	client := newScriptedWaveClient(server, testWaveOptions())
+
+	err := client.CreateCustomer(context.Background(), map[string]any{"name": "Grace Hopper"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, hits.Load(), int32(2))
+
+	// waits longer than RetryMaxDelay are not worth holding the request for
+	server, hits = newScriptedWave(t, tooManyRequests("120"))
+	client = newScriptedWaveClient(server, testWaveOptions())
+
+	start := time.Now()
+	_, err = client.GetBusinessAccounts(context.Background(), FAKE_WAVE_IDENTITY_BUSINESS_ID)
+
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, hits.Load(), int32(1))
+	assert.Equal(t, time.Since(start) < time.Second, true)
+}
+
+func TestWaveClientTimeout(t *testing.T) {
+	server, hits := newScriptedWave(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+
+	options := testWaveOptions()
+	options.Timeout = 20 * time.Millisecond
+	options.MaxRetries = 1
+
+	client := newScriptedWaveClient(server, options)
+
+	start := time.Now()
+	_, err := client.GetBusinessAccounts(context.Background(), FAKE_WAVE_IDENTITY_BUSINESS_ID)
+
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, hits.Load(), int32(2))
+	assert.Equal(t, time.Since(start) < time.Second, true)
+}
+
+func TestWaveClientCircuitBreaker(t *testing.T) {
+	var healthy atomic.Bool
+
+	server, hits := newScriptedWave(t, func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Write([]byte(`{"data": {"business": {"customer": {"id": "Q3VzdG9tZXI6MQ==", "name": "Ada Lovelace"}}}}`))
+	})
+
+	options := testWaveOptions()
+	options.MaxRetries = 0
+	options.BreakerThreshold = 2
+	options.BreakerCooldown = 50 * time.Millisecond
+
+	app := application{
+		logger: mocks.Logger(),
+		wave:   newScriptedWaveClient(server, options),
+	}
+
+	queryCustomer := func() int {
+		rr := httptest.NewRecorder()
+		r, err := http.NewRequest(http.MethodPost, "/api/wave/customer/query", strings.NewReader(`{"customerID": "Q3VzdG9tZXI6MQ=="}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		app.queryWaveCustomer(rr, r, nil)
+		return rr.Result().StatusCode
+	}
+
+	assert.Equal(t, queryCustomer(), http.StatusBadGateway)
+	assert.Equal(t, queryCustomer(), http.StatusBadGateway)
+
+	// open: Wave is not contacted anymore
+	assert.Equal(t, queryCustomer(), http.StatusServiceUnavailable)
+	assert.Equal(t, hits.Load(), int32(2))
+
+	_, err := app.wave.GetCustomer(context.Background(), FAKE_WAVE_BUSINESS_ID, "Q3VzdG9tZXI6MQ==")
+	assert.Equal(t, errors.Is(err, wave.ErrUnavailable), true)
+
+	// after the cooldown a request is let through, and closes the breaker when it succeeds
+	healthy.Store(true)
+	time.Sleep(options.BreakerCooldown)
+
+	assert.Equal(t, queryCustomer(), http.StatusOK)
+	assert.Equal(t, queryCustomer(), http.StatusOK)
+	assert.Equal(t, hits.Load(), int32(4))
+}
+
+func TestWaveClientErrors(t *testing.T) {
+	server, _ := newScriptedWave(t,
+		respondWith(http.StatusUnauthorized, `{"detail": "Invalid token."}`),
+		respondWith(http.StatusBadRequest, `{"amount": ["This field is required."], "detail": "Invalid payment."}`),
+		respondWith(http.StatusOK, `{"data": {"customerCreate": {"didSucceed": false, "inputErrors": [
+			{"code": "INVALID", "message": "Enter a valid email address.", "path": ["input", "email"]}
+		]}}}`),
+		respondWith(http.StatusOK, `{"errors": [{"message": "Invalid ID.", "path": ["business", "customer"]}]}`),
+	)
+
+	options := testWaveOptions()
+	options.MaxRetries = 0
+
+	client := newScriptedWaveClient(server, options)
+	ctx := context.Background()
+
+	var statusError *wave.StatusError
+	var inputError *wave.InputError
+	var graphQLErrors wave.GraphQLErrors
+
+	_, err := client.GetBusinessAccounts(ctx, FAKE_WAVE_IDENTITY_BUSINESS_ID)
+	assert.Equal(t, errors.As(err, &statusError), true)
+	assert.Equal(t, statusError.StatusCode, http.StatusUnauthorized)
+	assert.Equal(t, len(statusError.InputErrors), 0)
+
+	_, err = client.CreateInvoicePayment(ctx, FAKE_WAVE_IDENTITY_BUSINESS_ID, "1", map[string]any{})
+	assert.Equal(t, errors.As(err, &statusError), true)
+	assert.Equal(t, statusError.StatusCode, http.StatusBadRequest)
+	assert.Equal(t, len(statusError.InputErrors), 1)
+	assert.Equal(t, statusError.InputErrors[0].Field(), "amount")
+
+	err = client.CreateCustomer(ctx, map[string]any{"name": "Grace Hopper", "email": "grace"})
+	assert.Equal(t, errors.As(err, &inputError), true)
+	assert.Equal(t, inputError.Operation, "customerCreate")
+	assert.Equal(t, len(inputError.Errors), 1)
+	assert.Equal(t, inputError.Errors[0].Field(), "email")
+	assert.Equal(t, inputError.Errors[0].Code, "INVALID")
+
+	_, err = client.GetCustomer(ctx, FAKE_WAVE_BUSINESS_ID, "nope")
+	assert.Equal(t, errors.As(err, &graphQLErrors), true)
+	assert.Equal(t, graphQLErrors[0].Message, "Invalid ID.")
+}
+
+func TestWaveClientNoContent(t *testing.T) {
+	server, _ := newScriptedWave(t, respondWith(http.StatusNoContent, ""))
+
+	client := newScriptedWaveClient(server, testWaveOptions())
+
+	deleted, err := client.DeleteInvoicePayment(context.Background(), FAKE_WAVE_IDENTITY_BUSINESS_ID, "1", "2")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, deleted, true)
+}
+
+func TestWaveErrorResponse(t *testing.T) {
+	app := application{logger: mocks.Logger()}
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"unavailable", errors.Wrap(wave.ErrUnavailable, "GetCustomer"), http.StatusServiceUnavailable},
+		{"input", &wave.InputError{Operation: "customerCreate", Errors: []wave.WaveInputError{{Message: "Invalid.", Path: []string{"input", "email"}}}}, http.StatusUnprocessableEntity},
+		{"business API input", &wave.StatusError{StatusCode: http.StatusBadRequest, InputErrors: []wave.WaveInputError{{Message: "Required.", Path: []string{"amount"}}}}, http.StatusUnprocessableEntity},
+		{"bad request", &wave.StatusError{StatusCode: http.StatusBadRequest, Body: "Invalid payment."}, http.StatusBadRequest},
+		{"not found", &wave.StatusError{StatusCode: http.StatusNotFound}, http.StatusNotFound},
+		{"rate limited", &wave.StatusError{StatusCode: http.StatusTooManyRequests}, http.StatusServiceUnavailable},
+		{"wrong token", &wave.StatusError{StatusCode: http.StatusUnauthorized}, http.StatusBadGateway},
+		{"server error", &wave.StatusError{StatusCode: http.StatusInternalServerError}, http.StatusBadGateway},
+		{"graphql", wave.GraphQLErrors{{Message: "Invalid ID."}}, http.StatusBadRequest},
+		{"other", errors.New("json deserialization"), http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r, err := http.NewRequest(http.MethodPost, "/api/wave/customer/create", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			app.waveErrorResponse(rr, r, tt.err)
+
+			assert.Equal(t, rr.Result().StatusCode, tt.status)
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodPost, "/api/wave/customer/create", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.waveErrorResponse(rr, r, tests[1].err)
+
+	var body struct {
+		InputErrors []waveInputErrorResponse `json:"inputErrors"`
+	}
+
+	err = json.NewDecoder(rr.Result().Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(body.InputErrors), 1)
+	assert.Equal(t, body.InputErrors[0].Field, "email")
+	assert.Equal(t, body.InputErrors[0].Message, "Invalid.")
+
+	// what Wave said is only logged
+	rr = httptest.NewRecorder()
+	app.waveErrorResponse(rr, r, tests[3].err)
+
+	responseBody, err := io.ReadAll(rr.Result().Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, strings.Contains(string(responseBody), "Invalid payment."), false)
+}
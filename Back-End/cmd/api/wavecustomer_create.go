@@ -3,7 +3,7 @@ package main
 import (
 	"encoding/json"
 	"net/http"
-	"prime-shine-api/internal/wave"
+	"prime-shine-api/internal/data"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -24,13 +24,22 @@ func (app *application) createWaveCustomer(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = wave.CreateCustomer(body.CustomerCreateInput)
+	auditLogID, err := app.beginWaveAudit(r, data.AUDIT_ACTION_CREATE, data.AUDIT_ENTITY_WAVE_CUSTOMER, nil, nil, body.CustomerCreateInput)
 	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "beginWaveAudit"))
+		return
+	}
+
+	err = app.wave.CreateCustomer(r.Context(), body.CustomerCreateInput)
+	if err != nil {
+		app.finishWaveAudit(r, auditLogID, err, nil)
 		err = errors.Wrap(err, "CreateCustomer")
-		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		app.waveErrorResponse(w, r, err)
 		return
 	}
 
+	app.finishWaveAudit(r, auditLogID, nil, nil)
+
 	data := jsondata{"success": true}
 	err = app.writeJSON(w, http.StatusOK, data, nil)
 	if err != nil {
@@ -3,7 +3,7 @@ package main
 import (
 	"encoding/json"
 	"net/http"
-	"prime-shine-api/internal/wave"
+	"prime-shine-api/internal/data"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -24,13 +24,24 @@ func (app *application) deleteWaveCustomer(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = wave.DeleteCustomer(body.CustomerID)
+	before := app.waveCustomerSnapshot(r, body.CustomerID)
+
+	auditLogID, err := app.beginWaveAudit(r, data.AUDIT_ACTION_DELETE, data.AUDIT_ENTITY_WAVE_CUSTOMER, body.CustomerID, before, nil)
 	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "beginWaveAudit"))
+		return
+	}
+
+	err = app.wave.DeleteCustomer(r.Context(), body.CustomerID)
+	if err != nil {
+		app.finishWaveAudit(r, auditLogID, err, nil)
 		err = errors.Wrap(err, "DeleteCustomer")
-		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		app.waveErrorResponse(w, r, err)
 		return
 	}
 
+	app.finishWaveAudit(r, auditLogID, nil, nil)
+
 	data := jsondata{"success": true}
 	err = app.writeJSON(w, http.StatusOK, data, nil)
 	if err != nil {
@@ -3,7 +3,7 @@ package main
 import (
 	"encoding/json"
 	"net/http"
-	"prime-shine-api/internal/wave"
+	"prime-shine-api/internal/data"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -24,13 +24,25 @@ func (app *application) editWaveCustomer(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	err = wave.EditCustomer(body.CustomerPatchInput)
+	before := app.waveCustomerSnapshot(r, body.CustomerPatchInput["id"])
+
+	auditLogID, err := app.beginWaveAudit(r, data.AUDIT_ACTION_EDIT, data.AUDIT_ENTITY_WAVE_CUSTOMER, body.CustomerPatchInput["id"], before, body.CustomerPatchInput)
 	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "beginWaveAudit"))
+		return
+	}
+
+	err = app.wave.EditCustomer(r.Context(), body.CustomerPatchInput)
+	if err != nil {
+		app.finishWaveAudit(r, auditLogID, err, nil)
 		err = errors.Wrap(err, "EditCustomer")
-		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		app.waveErrorResponse(w, r, err)
 		return
 	}
 
+	after := app.waveCustomerSnapshot(r, body.CustomerPatchInput["id"])
+	app.finishWaveAudit(r, auditLogID, nil, after)
+
 	data := jsondata{"success": true}
 	err = app.writeJSON(w, http.StatusOK, data, nil)
 	if err != nil {
@@ -3,7 +3,6 @@ package main
 import (
 	"encoding/json"
 	"net/http"
-	"prime-shine-api/internal/wave"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -26,10 +25,10 @@ func (app *application) queryWaveCustomersPaginated(w http.ResponseWriter, r *ht
 		return
 	}
 
-	customers, pageInfo, err := wave.GetCustomers(body.BusinessID, body.PageNum, body.PageSize)
+	customers, pageInfo, err := app.wave.GetCustomers(r.Context(), body.BusinessID, body.PageNum, body.PageSize)
 	if err != nil {
 		err = errors.Wrap(err, "GetCustomers")
-		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		app.waveErrorResponse(w, r, err)
 		return
 	}
 
@@ -56,10 +55,10 @@ func (app *application) queryWaveCustomers(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	customers, err := wave.GetAllCustomers(body.BusinessID)
+	customers, err := app.wave.GetAllCustomers(r.Context(), body.BusinessID)
 	if err != nil {
 		err = errors.Wrap(err, "GetAllCustomers")
-		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		app.waveErrorResponse(w, r, err)
 		return
 	}
 
@@ -87,10 +86,10 @@ func (app *application) queryWaveCustomer(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	customer, err := wave.GetCustomer(body.BusinessID, body.CustomerID)
+	customer, err := app.wave.GetCustomer(r.Context(), body.BusinessID, body.CustomerID)
 	if err != nil {
 		err = errors.Wrap(err, "GetCustomer")
-		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		app.waveErrorResponse(w, r, err)
 		return
 	}
 
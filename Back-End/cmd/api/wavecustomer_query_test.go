@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"prime-shine-api/internal/assert"
+	"prime-shine-api/internal/mocks"
+	"prime-shine-api/internal/wave"
+	"strings"
+	"testing"
+)
+
+// Only implements the calls a test needs; anything else panics on the nil embedded API.
+type stubWave struct {
+	wave.API
+	customers map[string]wave.WaveCustomer
+}
+
+func (s stubWave) GetCustomer(ctx context.Context, businessID string, customerID string) (*wave.WaveCustomer, error) {
+	customer, ok := s.customers[customerID]
+	if !ok {
+		return nil, wave.GraphQLErrors{{
+			Message:    "Customer not found.",
+			Extensions: wave.WaveGraphQLErrorExtensions{Code: "NOT_FOUND"},
+		}}
+	}
+
+	return &customer, nil
+}
+
+func TestQueryWaveCustomer(t *testing.T) {
+	app := application{
+		logger: mocks.Logger(),
+		wave: stubWave{customers: map[string]wave.WaveCustomer{
+			"Q3VzdG9tZXI6MQ==": {ID: "Q3VzdG9tZXI6MQ==", Name: "Ada Lovelace"},
+		}},
+	}
+
+	rr := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodPost, "/api/wave/customer/query", strings.NewReader(`{"customerID": "Q3VzdG9tZXI6MQ=="}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.queryWaveCustomer(rr, r, nil)
+
+	rs := rr.Result()
+	assert.Equal(t, rs.StatusCode, http.StatusOK)
+
+	var body struct {
+		Customer wave.WaveCustomer `json:"customer"`
+	}
+
+	err = json.NewDecoder(rs.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, body.Customer.Name, "Ada Lovelace")
+
+	rr = httptest.NewRecorder()
+	r, err = http.NewRequest(http.MethodPost, "/api/wave/customer/query", strings.NewReader(`{"customerID": "missing"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.queryWaveCustomer(rr, r, nil)
+
+	assert.Equal(t, rr.Result().StatusCode, http.StatusNotFound)
+}
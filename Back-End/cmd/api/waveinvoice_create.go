@@ -3,7 +3,7 @@ package main
 import (
 	"encoding/json"
 	"net/http"
-	"prime-shine-api/internal/wave"
+	"prime-shine-api/internal/data"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -24,13 +24,22 @@ func (app *application) createWaveInvoice(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = wave.CreateInvoice(body.InvoiceCreateInput)
+	auditLogID, err := app.beginWaveAudit(r, data.AUDIT_ACTION_CREATE, data.AUDIT_ENTITY_WAVE_INVOICE, nil, nil, body.InvoiceCreateInput)
 	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "beginWaveAudit"))
+		return
+	}
+
+	err = app.wave.CreateInvoice(r.Context(), body.InvoiceCreateInput)
+	if err != nil {
+		app.finishWaveAudit(r, auditLogID, err, nil)
 		err = errors.Wrap(err, "CreateInvoice")
-		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		app.waveErrorResponse(w, r, err)
 		return
 	}
 
+	app.finishWaveAudit(r, auditLogID, nil, nil)
+
 	data := jsondata{"success": true}
 	err = app.writeJSON(w, http.StatusOK, data, nil)
 	if err != nil {
@@ -3,7 +3,7 @@ package main
 import (
 	"encoding/json"
 	"net/http"
-	"prime-shine-api/internal/wave"
+	"prime-shine-api/internal/data"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -24,13 +24,25 @@ func (app *application) editWaveInvoice(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	err = wave.EditInvoice(body.InvoicePatchInput)
+	before := app.waveInvoiceSnapshot(r, body.InvoicePatchInput["id"])
+
+	auditLogID, err := app.beginWaveAudit(r, data.AUDIT_ACTION_EDIT, data.AUDIT_ENTITY_WAVE_INVOICE, body.InvoicePatchInput["id"], before, body.InvoicePatchInput)
 	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "beginWaveAudit"))
+		return
+	}
+
+	err = app.wave.EditInvoice(r.Context(), body.InvoicePatchInput)
+	if err != nil {
+		app.finishWaveAudit(r, auditLogID, err, nil)
 		err = errors.Wrap(err, "EditInvoice")
-		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		app.waveErrorResponse(w, r, err)
 		return
 	}
 
+	after := app.waveInvoiceSnapshot(r, body.InvoicePatchInput["id"])
+	app.finishWaveAudit(r, auditLogID, nil, after)
+
 	data := jsondata{"success": true}
 	err = app.writeJSON(w, http.StatusOK, data, nil)
 	if err != nil {
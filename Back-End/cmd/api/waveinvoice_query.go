@@ -25,10 +25,10 @@ func (app *application) queryWaveInvoices(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	invoices, pageInfo, err := wave.GetInvoices(body.BusinessID, body.FilterStruct)
+	invoices, pageInfo, err := app.wave.GetInvoices(r.Context(), body.BusinessID, body.FilterStruct)
 	if err != nil {
 		err = errors.Wrap(err, "GetInvoices")
-		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		app.waveErrorResponse(w, r, err)
 		return
 	}
 
@@ -56,10 +56,10 @@ func (app *application) queryWaveInvoice(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	invoice, err := wave.GetInvoice(body.BusinessID, body.InvoiceID)
+	invoice, err := app.wave.GetInvoice(r.Context(), body.BusinessID, body.InvoiceID)
 	if err != nil {
 		err = errors.Wrap(err, "GetInvoice")
-		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		app.waveErrorResponse(w, r, err)
 		return
 	}
 
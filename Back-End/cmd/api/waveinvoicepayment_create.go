@@ -3,7 +3,7 @@ package main
 import (
 	"encoding/json"
 	"net/http"
-	"prime-shine-api/internal/wave"
+	"prime-shine-api/internal/data"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -26,18 +26,28 @@ func (app *application) createWaveInvoicePayment(w http.ResponseWriter, r *http.
 		return
 	}
 
-	_, err = wave.CreateInvoicePayment(
+	auditLogID, err := app.beginWaveAudit(r, data.AUDIT_ACTION_CREATE, data.AUDIT_ENTITY_WAVE_INVOICE_PAYMENT, nil, nil, body.InvoicePaymentData)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "beginWaveAudit"))
+		return
+	}
+
+	_, err = app.wave.CreateInvoicePayment(
+		r.Context(),
 		body.IdentityBusinessID,
 		body.InternalInvoiceID,
 		body.InvoicePaymentData,
 	)
 
 	if err != nil {
+		app.finishWaveAudit(r, auditLogID, err, nil)
 		err = errors.Wrap(err, "CreateInvoicePayment")
-		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		app.waveErrorResponse(w, r, err)
 		return
 	}
 
+	app.finishWaveAudit(r, auditLogID, nil, nil)
+
 	data := jsondata{"success": true}
 	err = app.writeJSON(w, http.StatusOK, data, nil)
 	if err != nil {
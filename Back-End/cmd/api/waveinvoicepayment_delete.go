@@ -3,7 +3,7 @@ package main
 import (
 	"encoding/json"
 	"net/http"
-	"prime-shine-api/internal/wave"
+	"prime-shine-api/internal/data"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -26,13 +26,24 @@ func (app *application) deleteWaveInvoicePayment(w http.ResponseWriter, r *http.
 		return
 	}
 
-	_, err = wave.DeleteInvoicePayment(body.IdentityBusinessID, body.InternalInvoiceID, body.InvoicePaymentID)
+	before := app.waveInvoicePaymentSnapshot(r, body.IdentityBusinessID, body.InternalInvoiceID, body.InvoicePaymentID)
+
+	auditLogID, err := app.beginWaveAudit(r, data.AUDIT_ACTION_DELETE, data.AUDIT_ENTITY_WAVE_INVOICE_PAYMENT, body.InvoicePaymentID, before, nil)
 	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "beginWaveAudit"))
+		return
+	}
+
+	_, err = app.wave.DeleteInvoicePayment(r.Context(), body.IdentityBusinessID, body.InternalInvoiceID, body.InvoicePaymentID)
+	if err != nil {
+		app.finishWaveAudit(r, auditLogID, err, nil)
 		err = errors.Wrap(err, "DeleteInvoicePayment")
-		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		app.waveErrorResponse(w, r, err)
 		return
 	}
 
+	app.finishWaveAudit(r, auditLogID, nil, nil)
+
 	data := jsondata{"success": true}
 	err = app.writeJSON(w, http.StatusOK, data, nil)
 	if err != nil {
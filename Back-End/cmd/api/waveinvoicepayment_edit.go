@@ -3,7 +3,7 @@ package main
 import (
 	"encoding/json"
 	"net/http"
-	"prime-shine-api/internal/wave"
+	"prime-shine-api/internal/data"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -27,7 +27,16 @@ func (app *application) editWaveInvoicePayment(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	_, err = wave.EditInvoicePayment(
+	before := app.waveInvoicePaymentSnapshot(r, body.IdentityBusinessID, body.InternalInvoiceID, body.InvoicePaymentID)
+
+	auditLogID, err := app.beginWaveAudit(r, data.AUDIT_ACTION_EDIT, data.AUDIT_ENTITY_WAVE_INVOICE_PAYMENT, body.InvoicePaymentID, before, body.InvoicePaymentData)
+	if err != nil {
+		app.serverErrorResponse(w, r, errors.Wrap(err, "beginWaveAudit"))
+		return
+	}
+
+	_, err = app.wave.EditInvoicePayment(
+		r.Context(),
 		body.IdentityBusinessID,
 		body.InternalInvoiceID,
 		body.InvoicePaymentID,
@@ -35,11 +44,15 @@ func (app *application) editWaveInvoicePayment(w http.ResponseWriter, r *http.Re
 	)
 
 	if err != nil {
+		app.finishWaveAudit(r, auditLogID, err, nil)
 		err = errors.Wrap(err, "EditInvoicePayment")
-		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		app.waveErrorResponse(w, r, err)
 		return
 	}
 
+	after := app.waveInvoicePaymentSnapshot(r, body.IdentityBusinessID, body.InternalInvoiceID, body.InvoicePaymentID)
+	app.finishWaveAudit(r, auditLogID, nil, after)
+
 	data := jsondata{"success": true}
 	err = app.writeJSON(w, http.StatusOK, data, nil)
 	if err != nil {
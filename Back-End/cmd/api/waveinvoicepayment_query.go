@@ -3,7 +3,6 @@ package main
 import (
 	"encoding/json"
 	"net/http"
-	"prime-shine-api/internal/wave"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -25,10 +24,10 @@ func (app *application) queryWaveInvoicePayments(w http.ResponseWriter, r *http.
 		return
 	}
 
-	invoicePayments, err := wave.GetInvoicePayments(body.IdentityBusinessID, body.InternalInvoiceID)
+	invoicePayments, err := app.wave.GetInvoicePayments(r.Context(), body.IdentityBusinessID, body.InternalInvoiceID)
 	if err != nil {
 		err = errors.Wrap(err, "GetInvoicePayments")
-		app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+		app.waveErrorResponse(w, r, err)
 		return
 	}
 
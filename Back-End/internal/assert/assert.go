@@ -9,3 +9,11 @@ func Equal[T comparable](t *testing.T, actual T, expected T) {
 		t.Errorf("got: %v, expected: %v", actual, expected)
 	}
 }
+
+func NotEqual[T comparable](t *testing.T, actual T, unexpected T) {
+	t.Helper()
+
+	if actual == unexpected {
+		t.Errorf("got: %v, expected anything else", actual)
+	}
+}
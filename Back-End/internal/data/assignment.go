@@ -0,0 +1,461 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"prime-shine-api/internal/db"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Who cleans a scheduled customer.
+type ScheduledCustomerAssignment struct {
+	ScheduledCustomerID int   `json:"scheduledCustomerID"`
+	CrewIDs             []int `json:"crewIDs"`
+	EmployeeIDs         []int `json:"employeeIDs"` // assigned on their own, not through a crew
+}
+
+// Returned when a crew or an employee would have to be at two visits at once.
+type DoubleBookingError struct {
+	CrewID     int // set when a crew is double-booked
+	EmployeeID int // set when an employee is, on their own or through a crew
+
+	// The visit the crew or employee is booked for already.
+	Conflict *ScheduledCustomer
+}
+
+func (e *DoubleBookingError) Error() string {
+	if e.CrewID != 0 {
+		return fmt.Sprintf("Crew %v is booked for another visit at that time.", e.CrewID)
+	}
+
+	if e.EmployeeID != 0 {
+		return fmt.Sprintf("Employee %v is booked for another visit at that time.", e.EmployeeID)
+	}
+
+	// the database refused the booking, which does not say who
+	return "A crew or an employee is booked for another visit at that time."
+}
+
+// Returned when an employee can not be assigned, because they are inactive or do not work at the time of the visit.
+type EmployeeUnavailableError struct {
+	EmployeeID int
+	Inactive   bool // set when the employee is inactive, rather than unavailable at that time
+}
+
+func (e *EmployeeUnavailableError) Error() string {
+	if e.Inactive {
+		return fmt.Sprintf("Employee %v is inactive.", e.EmployeeID)
+	}
+
+	return fmt.Sprintf("Employee %v is not available at that time.", e.EmployeeID)
+}
+
+type assignmentRow struct {
+	ScheduledCustomerID int `db:"scheduledcustomerid"`
+	ID                  int `db:"id"`
+}
+
+// Gets the assignments of scheduled customers, in the order of ids.
+func loadAssignments(ctx context.Context, readConn db.ReadDBExecutor, ids []int) ([]*ScheduledCustomerAssignment, error) {
+	assignments := make([]*ScheduledCustomerAssignment, 0, len(ids))
+	byID := map[int]*ScheduledCustomerAssignment{}
+
+	for _, id := range ids {
+		assignment := &ScheduledCustomerAssignment{ScheduledCustomerID: id, CrewIDs: []int{}, EmployeeIDs: []int{}}
+		assignments = append(assignments, assignment)
+		byID[id] = assignment
+	}
+
+	if len(ids) == 0 {
+		return assignments, nil
+	}
+
+	queryIDs := make([]int32, 0, len(ids))
+	for _, id := range ids {
+		queryIDs = append(queryIDs, int32(id))
+	}
+
+	crews := []assignmentRow{}
+	err := readConn.SelectContext(ctx, &crews, `
+		SELECT scheduledcustomerid, crewid AS id
+		  FROM scheduled_customer_crews
+		 WHERE scheduledcustomerid = ANY($1)
+		 ORDER BY crewid
+	`, queryIDs)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Select")
+	}
+
+	for _, row := range crews {
+		byID[row.ScheduledCustomerID].CrewIDs = append(byID[row.ScheduledCustomerID].CrewIDs, row.ID)
+	}
+
+	employees := []assignmentRow{}
+	err = readConn.SelectContext(ctx, &employees, `
+		SELECT scheduledcustomerid, employeeid AS id
+		  FROM scheduled_customer_employees
+		 WHERE scheduledcustomerid = ANY($1)
+		 ORDER BY employeeid
+	`, queryIDs)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Select")
+	}
+
+	for _, row := range employees {
+		byID[row.ScheduledCustomerID].EmployeeIDs = append(byID[row.ScheduledCustomerID].EmployeeIDs, row.ID)
+	}
+
+	return assignments, nil
+}
+
+// Ensures that crews exist and belong to a user.
+func findUserCrews(ctx context.Context, readConn db.ReadDBExecutor, userID int, crewIDs []int) error {
+	if len(crewIDs) == 0 {
+		return nil
+	}
+
+	ids := make([]int32, 0, len(crewIDs))
+	for _, crewID := range crewIDs {
+		ids = append(ids, int32(crewID))
+	}
+
+	var found int
+	err := readConn.GetContext(ctx, &found, `
+		SELECT count(*)
+		  FROM crews
+		 WHERE userid = $1
+		   AND crewid = ANY($2)
+	`, userID, ids)
+
+	if err != nil {
+		return errors.Wrap(err, "Get")
+	}
+
+	if found != len(crewIDs) {
+		return errors.Wrap(ErrRecordNotFound, "crew")
+	}
+
+	return nil
+}
+
+// Finds a scheduled customer in one of a user's schedules.
+func findUserScheduledCustomer(ctx context.Context, readConn db.ReadDBExecutor, userID int, scheduledCustomerID int) (*ScheduledCustomer, error) {
+	query := db.Where(ScheduledCustomerColumns.ID.Eq(scheduledCustomerID))
+	scheduledCustomer, err := FindOneScheduledCustomer(ctx, readConn, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "FindOneScheduledCustomer")
+	}
+
+	if scheduledCustomer == nil {
+		return nil, ErrRecordNotFound
+	}
+
+	err = findUserSchedule(ctx, readConn, userID, scheduledCustomer.ScheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scheduledCustomer, nil
+}
+
+// Ensures that none of the crews and employees assigned to a visit are at another visit at the same time.
+// Employees are checked along with the members of the crews, since they can not be in two places either.
+func checkDoubleBooking(ctx context.Context, readConn db.ReadDBExecutor, visit *ScheduledCustomer, crewIDs []int, employeeIDs []int) error {
+	for _, crewID := range crewIDs {
+		conflict := &ScheduledCustomer{}
+		err := readConn.GetContext(ctx, conflict, `
+			SELECT `+scheduledCustomersTable.Columns()+`
+			  FROM scheduled_customers
+			 WHERE scheduledcustomerid <> $1
+			   AND start_time < $2
+			   AND end_time > $3
+			   AND scheduledcustomerid IN (
+			           SELECT scheduledcustomerid
+			             FROM scheduled_customer_crews
+			            WHERE crewid = $4
+			       )
+			 ORDER BY start_time
+			 LIMIT 1
+		`, visit.ID, visit.EndTime, visit.StartTime, crewID)
+
+		if err == nil {
+			return &DoubleBookingError{CrewID: crewID, Conflict: conflict}
+		} else if err != sql.ErrNoRows {
+			return errors.Wrap(err, "Get")
+		}
+	}
+
+	crewQueryIDs := make([]int32, 0, len(crewIDs))
+	for _, crewID := range crewIDs {
+		crewQueryIDs = append(crewQueryIDs, int32(crewID))
+	}
+
+	members := []int{}
+	err := readConn.SelectContext(ctx, &members, `
+		SELECT employeeid
+		  FROM crew_members
+		 WHERE crewid = ANY($1)
+	`, crewQueryIDs)
+
+	if err != nil {
+		return errors.Wrap(err, "Select")
+	}
+
+	for _, employeeID := range uniqueIDs(append(members, employeeIDs...)) {
+		conflict := &ScheduledCustomer{}
+		err := readConn.GetContext(ctx, conflict, `
+			SELECT `+scheduledCustomersTable.Columns()+`
+			  FROM scheduled_customers
+			 WHERE scheduledcustomerid <> $1
+			   AND start_time < $2
+			   AND end_time > $3
+			   AND (
+			           scheduledcustomerid IN (
+			               SELECT scheduledcustomerid
+			                 FROM scheduled_customer_employees
+			                WHERE employeeid = $4
+			           )
+			        OR scheduledcustomerid IN (
+			               SELECT scheduled_customer_crews.scheduledcustomerid
+			                 FROM scheduled_customer_crews
+			                 JOIN crew_members ON crew_members.crewid = scheduled_customer_crews.crewid
+			                WHERE crew_members.employeeid = $4
+			           )
+			       )
+			 ORDER BY start_time
+			 LIMIT 1
+		`, visit.ID, visit.EndTime, visit.StartTime, employeeID)
+
+		if err == nil {
+			return &DoubleBookingError{EmployeeID: employeeID, Conflict: conflict}
+		} else if err != sql.ErrNoRows {
+			return errors.Wrap(err, "Get")
+		}
+	}
+
+	return nil
+}
+
+// Ensures that the employees assigned to a visit on their own work at the time of the visit.
+// Crews are assigned as a whole, so their members' availability is left to whoever put the crew together.
+func checkEmployeeAvailability(ctx context.Context, readConn db.ReadDBExecutor, visit *ScheduledCustomer, employeeIDs []int, location *time.Location) error {
+	if len(employeeIDs) == 0 {
+		return nil
+	}
+
+	ids := make([]int32, 0, len(employeeIDs))
+	for _, employeeID := range employeeIDs {
+		ids = append(ids, int32(employeeID))
+	}
+
+	employees := []*Employee{}
+	err := readConn.SelectContext(ctx, &employees, `
+		SELECT `+employeesTable.Columns()+`
+		  FROM employees
+		 WHERE employeeid = ANY($1)
+		 ORDER BY employeeid
+	`, ids)
+
+	if err != nil {
+		return errors.Wrap(err, "Select")
+	}
+
+	err = loadEmployeeAvailability(ctx, readConn, employees)
+	if err != nil {
+		return errors.Wrap(err, "loadEmployeeAvailability")
+	}
+
+	start := visit.StartTime.Time.In(location)
+	end := visit.EndTime.Time.In(location)
+
+	for _, employee := range employees {
+		if !employee.isAvailable(start, end) {
+			return &EmployeeUnavailableError{EmployeeID: employee.ID}
+		}
+	}
+
+	return nil
+}
+
+// Checks the crews and employees a visit has already, e.g. after it moved or a crew changed.
+func recheckDoubleBooking(ctx context.Context, readConn db.ReadDBExecutor, visit *ScheduledCustomer) error {
+	assignments, err := loadAssignments(ctx, readConn, []int{visit.ID})
+	if err != nil {
+		return errors.Wrap(err, "loadAssignments")
+	}
+
+	return checkDoubleBooking(ctx, readConn, visit, assignments[0].CrewIDs, assignments[0].EmployeeIDs)
+}
+
+// Gets who cleans each scheduled customer in a user's schedule.
+func QueryScheduledCustomerAssignments(ctx context.Context, readConn db.ReadDBExecutor, userID int, scheduleID int) ([]*ScheduledCustomerAssignment, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "QueryScheduledCustomerAssignments")
+	defer cancel()
+
+	scheduledCustomers, err := QueryScheduledCustomers(ctx, readConn, userID, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(scheduledCustomers))
+	for _, scheduledCustomer := range scheduledCustomers {
+		ids = append(ids, scheduledCustomer.ID)
+	}
+
+	assignments, err := loadAssignments(ctx, readConn, ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "loadAssignments")
+	}
+
+	return assignments, nil
+}
+
+// Finds one scheduled customer's assignment, if it is in one of a user's schedules.
+func FindOneScheduledCustomerAssignment(ctx context.Context, readConn db.ReadDBExecutor, userID int, scheduledCustomerID int) (*ScheduledCustomerAssignment, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "FindOneScheduledCustomerAssignment")
+	defer cancel()
+
+	_, err := findUserScheduledCustomer(ctx, readConn, userID, scheduledCustomerID)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments, err := loadAssignments(ctx, readConn, []int{scheduledCustomerID})
+	if err != nil {
+		return nil, errors.Wrap(err, "loadAssignments")
+	}
+
+	return assignments[0], nil
+}
+
+// Sets the crews and employees that clean a scheduled customer, replacing the ones it had.
+// A *DoubleBookingError is returned if any of them are at another visit at the same time,
+// and an *EmployeeUnavailableError if an employee is inactive or does not work at the time of the visit.
+// Availability is in the business time zone, given by location.
+func AssignScheduledCustomer(
+	ctx context.Context,
+	tx db.WriteDBExecutor,
+	userID int,
+	scheduledCustomerID int,
+	crewIDs []int,
+	employeeIDs []int,
+	location *time.Location,
+) (*ScheduledCustomerAssignment, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "AssignScheduledCustomer")
+	defer cancel()
+
+	visit, err := findUserScheduledCustomer(ctx, tx, userID, scheduledCustomerID)
+	if err != nil {
+		return nil, err
+	}
+
+	crewIDs = uniqueIDs(crewIDs)
+	err = findUserCrews(ctx, tx, userID, crewIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	employeeIDs = uniqueIDs(employeeIDs)
+	err = findUserEmployees(ctx, tx, userID, employeeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkEmployeeAvailability(ctx, tx, visit, employeeIDs, location)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkDoubleBooking(ctx, tx, visit, crewIDs, employeeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM scheduled_customer_crews
+		WHERE scheduledcustomerid = $1
+	`, visit.ID)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "tx.Exec")
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM scheduled_customer_employees
+		WHERE scheduledcustomerid = $1
+	`, visit.ID)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "tx.Exec")
+	}
+
+	for _, crewID := range crewIDs {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO scheduled_customer_crews
+			(scheduledcustomerid, crewid)
+			VALUES ($1, $2)
+		`, visit.ID, crewID)
+
+		if isConstraintViolation(err, PG_EXCLUSION_VIOLATION) {
+			return nil, &DoubleBookingError{CrewID: crewID}
+		} else if err != nil {
+			return nil, errors.Wrap(err, "tx.Exec")
+		}
+	}
+
+	for _, employeeID := range employeeIDs {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO scheduled_customer_employees
+			(scheduledcustomerid, employeeid)
+			VALUES ($1, $2)
+		`, visit.ID, employeeID)
+
+		if isConstraintViolation(err, PG_EXCLUSION_VIOLATION) {
+			return nil, &DoubleBookingError{EmployeeID: employeeID}
+		} else if err != nil {
+			return nil, errors.Wrap(err, "tx.Exec")
+		}
+	}
+
+	return &ScheduledCustomerAssignment{ScheduledCustomerID: visit.ID, CrewIDs: crewIDs, EmployeeIDs: employeeIDs}, nil
+}
+
+// Gets what a crew is doing during one of a user's schedules, i.e. the visits it is assigned to.
+func QueryCrewScheduledCustomers(ctx context.Context, readConn db.ReadDBExecutor, userID int, crewID int, scheduleID int) ([]*ScheduledCustomer, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "QueryCrewScheduledCustomers")
+	defer cancel()
+
+	err := findUserCrews(ctx, readConn, userID, []int{crewID})
+	if err != nil {
+		return nil, err
+	}
+
+	err = findUserSchedule(ctx, readConn, userID, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*ScheduledCustomer{}
+	err = readConn.SelectContext(ctx, &entries, `
+		SELECT `+scheduledCustomersTable.Columns()+`
+		  FROM scheduled_customers
+		 WHERE scheduleid = $1
+		   AND scheduledcustomerid IN (
+		           SELECT scheduledcustomerid
+		             FROM scheduled_customer_crews
+		            WHERE crewid = $2
+		       )
+		 ORDER BY day_offset, start_time
+	`, scheduleID, crewID)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Select")
+	}
+
+	return entries, nil
+}
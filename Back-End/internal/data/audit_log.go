@@ -0,0 +1,266 @@
+package data
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"prime-shine-api/internal/db"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pkg/errors"
+)
+
+// Kinds of changes recorded in the audit log.
+const (
+	AUDIT_ACTION_CREATE = "create"
+	AUDIT_ACTION_EDIT   = "edit"
+	AUDIT_ACTION_DELETE = "delete"
+)
+
+// Outcomes of the changes recorded in the audit log.
+// Changes to our own tables are recorded in the same transaction, so they always succeeded.
+// Wave changes are recorded as pending before Wave is called (see BeginAuditLog).
+const (
+	AUDIT_OUTCOME_PENDING   = "pending"
+	AUDIT_OUTCOME_SUCCEEDED = "succeeded"
+	AUDIT_OUTCOME_FAILED    = "failed"
+)
+
+// Kinds of entities recorded in the audit log.
+const (
+	AUDIT_ENTITY_SCHEDULE             = "schedule"
+	AUDIT_ENTITY_SCHEDULED_CUSTOMER   = "scheduled_customer"
+	AUDIT_ENTITY_RECURRING_SERVICE    = "recurring_service"
+	AUDIT_ENTITY_EMPLOYEE             = "employee"
+	AUDIT_ENTITY_CREW                 = "crew"
+	AUDIT_ENTITY_ASSIGNMENT           = "scheduled_customer_assignment"
+	AUDIT_ENTITY_WAVE_CUSTOMER        = "wave_customer"
+	AUDIT_ENTITY_WAVE_INVOICE         = "wave_invoice"
+	AUDIT_ENTITY_WAVE_INVOICE_PAYMENT = "wave_invoice_payment"
+	AUDIT_ENTITY_USER                 = "user"
+	AUDIT_ENTITY_USER_PASSWORD        = "user_password" // recorded without snapshots, so that no hash ends up in the log
+	AUDIT_ENTITY_INVITATION           = "invitation"
+)
+
+// A JSON document stored in a jsonb column. Empty snapshots are stored as NULL.
+type JSONSnapshot []byte
+
+func (s JSONSnapshot) Value() (driver.Value, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	return string(s), nil
+}
+
+func (s *JSONSnapshot) Scan(src any) error {
+	switch src := src.(type) {
+	case nil:
+		*s = nil
+	case []byte:
+		*s = append((*s)[:0], src...)
+	case string:
+		*s = JSONSnapshot(src)
+	default:
+		return errors.Errorf("cannot scan %T into JSONSnapshot", src)
+	}
+
+	return nil
+}
+
+func (s JSONSnapshot) MarshalJSON() ([]byte, error) {
+	if len(s) == 0 {
+		return []byte("null"), nil
+	}
+
+	return s, nil
+}
+
+type AuditLogEntry struct {
+	ID         int                `db:"auditlogid" json:"auditLogID"`
+	UserID     pgtype.Int4        `db:"userid" json:"userID"`
+	Action     string             `db:"action" json:"action"`
+	EntityType string             `db:"entity_type" json:"entityType"`
+	EntityID   pgtype.Text        `db:"entity_id" json:"entityID"`
+	Before     JSONSnapshot       `db:"before" json:"before"`
+	After      JSONSnapshot       `db:"after" json:"after"`
+	CreatedAt  pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+	Outcome    string             `db:"outcome" json:"outcome"`
+}
+
+// Narrows down audit log queries. Zero values match everything.
+type AuditLogFilter struct {
+	UserID     int        `json:"userID"`
+	Action     string     `json:"action"`
+	EntityType string     `json:"entityType"`
+	EntityID   string     `json:"entityID"`
+	Since      *time.Time `json:"since"`
+	Until      *time.Time `json:"until"`
+}
+
+func snapshot(value any) (JSONSnapshot, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	snapshot, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "json serialization")
+	}
+
+	if string(snapshot) == "null" {
+		return nil, nil
+	}
+
+	return snapshot, nil
+}
+
+// Records a change made by a user.
+// The before and after states are stored as JSON; pass nil when there is no such state.
+func RecordAuditLog(
+	ctx context.Context,
+	tx db.WriteDBExecutor,
+	userID int,
+	action string,
+	entityType string,
+	entityID string,
+	before any,
+	after any,
+) error {
+	ctx, cancel := db.WithQueryTimeout(ctx, "RecordAuditLog")
+	defer cancel()
+
+	_, err := insertAuditLog(ctx, tx, userID, action, entityType, entityID, before, after, AUDIT_OUTCOME_SUCCEEDED)
+	if err != nil {
+		return errors.Wrap(err, "insertAuditLog")
+	}
+
+	return nil
+}
+
+// Records a change that is about to be made outside of our database, i.e. in Wave, as pending.
+// conn should not be the request's transaction: the entry has to outlive a rollback, since the
+// change can not be rolled back with it. Returns the ID of the entry for FinishAuditLog.
+func BeginAuditLog(
+	ctx context.Context,
+	conn db.WriteDBExecutor,
+	userID int,
+	action string,
+	entityType string,
+	entityID string,
+	before any,
+	after any,
+) (int, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "BeginAuditLog")
+	defer cancel()
+
+	id, err := insertAuditLog(ctx, conn, userID, action, entityType, entityID, before, after, AUDIT_OUTCOME_PENDING)
+	if err != nil {
+		return 0, errors.Wrap(err, "insertAuditLog")
+	}
+
+	return id, nil
+}
+
+// Records the outcome of a change begun with BeginAuditLog.
+// A non-nil after replaces the state recorded when the change began, e.g. with what Wave returned.
+func FinishAuditLog(ctx context.Context, conn db.WriteDBExecutor, auditLogID int, outcome string, after any) error {
+	ctx, cancel := db.WithQueryTimeout(ctx, "FinishAuditLog")
+	defer cancel()
+
+	afterSnapshot, err := snapshot(after)
+	if err != nil {
+		return errors.Wrap(err, "snapshot after")
+	}
+
+	_, err = conn.ExecContext(ctx, `
+		UPDATE audit_log
+		SET    outcome = $1
+		     , after   = coalesce($2, after)
+		WHERE auditlogid = $3
+		  AND outcome = $4
+	`, outcome, afterSnapshot, auditLogID, AUDIT_OUTCOME_PENDING)
+
+	if err != nil {
+		return errors.Wrap(err, "Exec")
+	}
+
+	return nil
+}
+
+func insertAuditLog(
+	ctx context.Context,
+	conn db.WriteDBExecutor,
+	userID int,
+	action string,
+	entityType string,
+	entityID string,
+	before any,
+	after any,
+	outcome string,
+) (int, error) {
+	beforeSnapshot, err := snapshot(before)
+	if err != nil {
+		return 0, errors.Wrap(err, "snapshot before")
+	}
+
+	afterSnapshot, err := snapshot(after)
+	if err != nil {
+		return 0, errors.Wrap(err, "snapshot after")
+	}
+
+	var id int
+	err = conn.GetContext(ctx, &id, `
+		INSERT INTO audit_log
+		(userid, action, entity_type, entity_id, before, after, outcome)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING auditlogid
+	`, userID, action, entityType, pgtype.Text{String: entityID, Valid: entityID != ""}, beforeSnapshot, afterSnapshot, outcome)
+
+	if err != nil {
+		return 0, errors.Wrap(err, "Get")
+	}
+
+	return id, nil
+}
+
+// Grabs audit log entries matching a filter, most recent first.
+func QueryAuditLog(ctx context.Context, readConn db.ReadDBExecutor, filter AuditLogFilter, pageNum int, pageSize int) ([]*AuditLogEntry, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "QueryAuditLog")
+	defer cancel()
+
+	entries := []*AuditLogEntry{}
+	query := `
+		SELECT *
+		  FROM audit_log
+		 WHERE ($1 = 0  OR userid = $1)
+		   AND ($2 = '' OR action = $2)
+		   AND ($3 = '' OR entity_type = $3)
+		   AND ($4 = '' OR entity_id = $4)
+		   AND ($5::timestamptz IS NULL OR created_at >= $5)
+		   AND ($6::timestamptz IS NULL OR created_at < $6)
+		 ORDER BY created_at DESC
+		 LIMIT $7
+		OFFSET $8
+	`
+
+	err := readConn.SelectContext(ctx,
+		&entries,
+		query,
+		filter.UserID,
+		filter.Action,
+		filter.EntityType,
+		filter.EntityID,
+		filter.Since,
+		filter.Until,
+		pageSize,
+		(pageNum-1)*pageSize,
+	)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Select")
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,361 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"prime-shine-api/internal/db"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Employees who clean together.
+type Crew struct {
+	ID     int    `db:"crewid" json:"crewID"`
+	UserID int    `db:"userid" json:"-"`
+	Name   string `db:"name" json:"name"`
+
+	MemberIDs []int `db:"-" json:"memberIDs"` // employees in the crew
+}
+
+var crewsTable = db.NewTable[Crew]("crews")
+
+// Columns that crews can be looked up by.
+// Crews are always looked up within a user's own crews, so there is no column for the user.
+var CrewColumns = struct {
+	ID   db.Column[Crew]
+	Name db.Column[Crew]
+}{
+	ID:   crewsTable.Column("crewid"),
+	Name: crewsTable.Column("name"),
+}
+
+var crewUserID = crewsTable.Column("userid")
+
+type crewMember struct {
+	CrewID     int `db:"crewid"`
+	EmployeeID int `db:"employeeid"`
+}
+
+// Fills in the members of crews.
+func loadCrewMembers(ctx context.Context, readConn db.ReadDBExecutor, crews []*Crew) error {
+	if len(crews) == 0 {
+		return nil
+	}
+
+	ids := make([]int32, 0, len(crews))
+	for _, crew := range crews {
+		ids = append(ids, int32(crew.ID))
+	}
+
+	members := []crewMember{}
+	err := readConn.SelectContext(ctx, &members, `
+		SELECT crewid, employeeid
+		  FROM crew_members
+		 WHERE crewid = ANY($1)
+		 ORDER BY employeeid
+	`, ids)
+
+	if err != nil {
+		return errors.Wrap(err, "Select")
+	}
+
+	for _, crew := range crews {
+		crew.MemberIDs = []int{}
+
+		for _, member := range members {
+			if member.CrewID == crew.ID {
+				crew.MemberIDs = append(crew.MemberIDs, member.EmployeeID)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Replaces the members stored for a crew with the ones it has.
+func saveCrewMembers(ctx context.Context, tx db.WriteDBExecutor, crew *Crew) error {
+	_, err := tx.ExecContext(ctx, `
+		DELETE FROM crew_members
+		WHERE crewid = $1
+	`, crew.ID)
+
+	if err != nil {
+		return errors.Wrap(err, "tx.Exec")
+	}
+
+	for _, employeeID := range crew.MemberIDs {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO crew_members
+			(crewid, employeeid)
+			VALUES ($1, $2)
+		`, crew.ID, employeeID)
+
+		if err != nil {
+			return errors.Wrap(err, "tx.Exec")
+		}
+	}
+
+	return nil
+}
+
+// Ensures that employees exist, belong to a user and are active.
+// An *EmployeeUnavailableError is returned for an inactive employee.
+func findUserEmployees(ctx context.Context, readConn db.ReadDBExecutor, userID int, employeeIDs []int) error {
+	if len(employeeIDs) == 0 {
+		return nil
+	}
+
+	ids := make([]int32, 0, len(employeeIDs))
+	for _, employeeID := range employeeIDs {
+		ids = append(ids, int32(employeeID))
+	}
+
+	var found int
+	err := readConn.GetContext(ctx, &found, `
+		SELECT count(*)
+		  FROM employees
+		 WHERE userid = $1
+		   AND employeeid = ANY($2)
+	`, userID, ids)
+
+	if err != nil {
+		return errors.Wrap(err, "Get")
+	}
+
+	if found != len(employeeIDs) {
+		return errors.Wrap(ErrRecordNotFound, "employee")
+	}
+
+	var inactiveID int
+	err = readConn.GetContext(ctx, &inactiveID, `
+		SELECT employeeid
+		  FROM employees
+		 WHERE employeeid = ANY($1)
+		   AND NOT active
+		 ORDER BY employeeid
+		 LIMIT 1
+	`, ids)
+
+	if err == nil {
+		return &EmployeeUnavailableError{EmployeeID: inactiveID, Inactive: true}
+	} else if err != sql.ErrNoRows {
+		return errors.Wrap(err, "Get")
+	}
+
+	return nil
+}
+
+// Sorts IDs and drops duplicates, so that sets of IDs can be stored and compared.
+func uniqueIDs(ids []int) []int {
+	ids = append([]int{}, ids...)
+	slices.Sort(ids)
+
+	return slices.Compact(ids)
+}
+
+// Finds one crew that belongs to a user, along with its members.
+// If runtime errors occur, an error is returned.
+// Otherwise, a crew and nil error is returned.
+func FindOneCrew(ctx context.Context, readConn db.ReadDBExecutor, userID int, query db.Query[Crew]) (*Crew, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "FindOneCrew")
+	defer cancel()
+
+	crew := &Crew{}
+
+	query = query.And(crewUserID.Eq(userID)).Limit(1)
+	statement, args := crewsTable.Select(query)
+
+	err := readConn.GetContext(ctx, crew, statement, args...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "Get")
+	}
+
+	err = loadCrewMembers(ctx, readConn, []*Crew{crew})
+	if err != nil {
+		return nil, errors.Wrap(err, "loadCrewMembers")
+	}
+
+	return crew, nil
+}
+
+// Gets the crews of a user, along with their members, by name.
+func QueryCrews(ctx context.Context, readConn db.ReadDBExecutor, userID int) ([]*Crew, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "QueryCrews")
+	defer cancel()
+
+	entries := []*Crew{}
+
+	query := db.Where(crewUserID.Eq(userID)).OrderBy(CrewColumns.Name)
+	statement, args := crewsTable.Select(query)
+
+	err := readConn.SelectContext(ctx, &entries, statement, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Select")
+	}
+
+	err = loadCrewMembers(ctx, readConn, entries)
+	if err != nil {
+		return nil, errors.Wrap(err, "loadCrewMembers")
+	}
+
+	return entries, nil
+}
+
+// Creates a crew for a user.
+func CreateCrew(ctx context.Context, tx db.WriteDBExecutor, userID int, name string, memberIDs []int) (*Crew, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "CreateCrew")
+	defer cancel()
+
+	if strings.TrimSpace(name) == "" {
+		return nil, errors.New("A crew needs a name.")
+	}
+
+	memberIDs = uniqueIDs(memberIDs)
+	err := findUserEmployees(ctx, tx, userID, memberIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	query := db.Where(CrewColumns.Name.Eq(name))
+	crew, err := FindOneCrew(ctx, tx, userID, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "FindOneCrew")
+	}
+
+	if crew != nil {
+		return nil, errors.New("A crew with this name exists already.")
+	}
+
+	newCrew := &Crew{}
+	err = tx.GetContext(ctx, newCrew, `
+		INSERT INTO crews
+		(userid, name)
+		VALUES ($1, $2)
+		RETURNING `+crewsTable.Columns()+`
+	`, userID, name)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "tx.Get")
+	}
+
+	newCrew.MemberIDs = memberIDs
+
+	err = saveCrewMembers(ctx, tx, newCrew)
+	if err != nil {
+		return nil, errors.Wrap(err, "saveCrewMembers")
+	}
+
+	return newCrew, nil
+}
+
+// Edits a crew that belongs to a user, replacing its members.
+// A *DoubleBookingError is returned if a new member is busy elsewhere during one of the crew's visits.
+func EditCrew(ctx context.Context, tx db.WriteDBExecutor, userID int, crewID int, name string, memberIDs []int) (*Crew, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "EditCrew")
+	defer cancel()
+
+	if strings.TrimSpace(name) == "" {
+		return nil, errors.New("A crew needs a name.")
+	}
+
+	query := db.Where(CrewColumns.ID.Eq(crewID))
+	crew, err := FindOneCrew(ctx, tx, userID, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "FindOneCrew")
+	}
+
+	if crew == nil {
+		return nil, ErrRecordNotFound
+	}
+
+	memberIDs = uniqueIDs(memberIDs)
+	err = findUserEmployees(ctx, tx, userID, memberIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	query = db.Where(CrewColumns.Name.Eq(name), CrewColumns.ID.NotEq(crewID))
+	foundCrew, err := FindOneCrew(ctx, tx, userID, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "FindOneCrew")
+	}
+
+	if foundCrew != nil {
+		return nil, errors.New("A crew with this name exists already.")
+	}
+
+	editedCrew := &Crew{}
+	err = tx.GetContext(ctx, editedCrew, `
+		UPDATE crews
+		SET   name = $1
+		WHERE crewid = $2
+		RETURNING `+crewsTable.Columns()+`
+	`, name, crewID)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "tx.Get")
+	}
+
+	editedCrew.MemberIDs = memberIDs
+
+	err = saveCrewMembers(ctx, tx, editedCrew)
+	if err != nil {
+		return nil, errors.Wrap(err, "saveCrewMembers")
+	}
+
+	// new members may be busy elsewhere during the crew's visits
+	visits := []*ScheduledCustomer{}
+	err = tx.SelectContext(ctx, &visits, `
+		SELECT `+scheduledCustomersTable.Columns()+`
+		  FROM scheduled_customers
+		 WHERE scheduledcustomerid IN (
+		           SELECT scheduledcustomerid
+		             FROM scheduled_customer_crews
+		            WHERE crewid = $1
+		       )
+	`, crewID)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Select")
+	}
+
+	for _, visit := range visits {
+		err = recheckDoubleBooking(ctx, tx, visit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return editedCrew, nil
+}
+
+// Deletes a crew for a user, along with its assignments.
+func DeleteCrew(ctx context.Context, tx db.WriteDBExecutor, userID int, crewID int) (bool, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "DeleteCrew")
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM crews
+		WHERE crewid = $1
+		  AND userid = $2
+	`, crewID, userID)
+
+	if err != nil {
+		return false, errors.Wrap(err, "tx.Exec")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "RowsAffected")
+	}
+
+	if rowsAffected == 0 {
+		return false, ErrRecordNotFound
+	}
+
+	return true, nil
+}
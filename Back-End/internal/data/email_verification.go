@@ -0,0 +1,113 @@
+package data
+
+import (
+	"context"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/db"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// How long an email verification link stays valid.
+const EMAIL_VERIFICATION_TOKEN_DURATION = 24 * time.Hour
+
+// Creates a single-use token for verifying the current email of a user.
+// Any previously issued, unused tokens of the user are invalidated.
+// The plaintext token is returned; only its hash is stored.
+func CreateEmailVerificationToken(ctx context.Context, tx db.WriteDBExecutor, userID int, email string) (string, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "CreateEmailVerificationToken")
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, `
+		UPDATE email_verification_tokens
+		SET    used_at = now()
+		WHERE userid = $1
+		  AND used_at IS NULL
+	`, userID)
+
+	if err != nil {
+		return "", errors.Wrap(err, "tx.Exec")
+	}
+
+	token, tokenHash, err := internal.GenerateOpaqueToken()
+	if err != nil {
+		return "", errors.Wrap(err, "GenerateOpaqueToken")
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO email_verification_tokens
+		(userid, email, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`, userID, email, tokenHash, time.Now().Add(EMAIL_VERIFICATION_TOKEN_DURATION))
+
+	if err != nil {
+		return "", errors.Wrap(err, "tx.Exec")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return "", errors.Wrap(err, "RowsAffected")
+	}
+
+	if rowsAffected != 1 {
+		return "", errors.New("failed to insert email_verification_tokens entry")
+	}
+
+	return token, nil
+}
+
+// Marks the email of a user as verified with an email verification token.
+// If the token does not exist, has expired, was already used,
+// or the user has changed their email since, ErrRecordNotFound is returned.
+func VerifyUserEmail(ctx context.Context, tx db.WriteDBExecutor, token string) error {
+	ctx, cancel := db.WithQueryTimeout(ctx, "VerifyUserEmail")
+	defer cancel()
+
+	tokenHash := internal.HashOpaqueToken(token)
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE email_verification_tokens
+		SET    used_at = now()
+		WHERE token_hash = $1
+		  AND used_at IS NULL
+		  AND expires_at > now()
+	`, tokenHash)
+
+	if err != nil {
+		return errors.Wrap(err, "tx.Exec")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "RowsAffected")
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	result, err = tx.ExecContext(ctx, `
+		UPDATE users
+		SET    email_verified_at = now()
+		  FROM email_verification_tokens
+		WHERE email_verification_tokens.token_hash = $1
+		  AND users.userid = email_verification_tokens.userid
+		  AND users.email = email_verification_tokens.email
+	`, tokenHash)
+
+	if err != nil {
+		return errors.Wrap(err, "tx.Exec")
+	}
+
+	rowsAffected, err = result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "RowsAffected")
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
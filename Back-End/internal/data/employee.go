@@ -0,0 +1,320 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"prime-shine-api/internal/db"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pkg/errors"
+)
+
+// Someone who cleans. Employees do not need a user account.
+type Employee struct {
+	ID     int         `db:"employeeid" json:"employeeID"`
+	UserID int         `db:"userid" json:"-"`
+	Name   string      `db:"name" json:"name"`
+	Email  pgtype.Text `db:"email" json:"email"`
+	Phone  pgtype.Text `db:"phone" json:"phone"`
+	Active bool        `db:"active" json:"active"` // inactive employees are kept for the visits they had
+
+	// Weekly windows the employee can work in, in the business time zone.
+	// Employees without any are available at all times.
+	Availability []EmployeeAvailability `db:"-" json:"availability"`
+}
+
+type EmployeeAvailability struct {
+	EmployeeID int       `db:"employeeid" json:"-"`
+	Weekday    int       `db:"weekday" json:"weekday"` // 0 is Sunday, as in time.Weekday
+	StartTime  TimeOfDay `db:"start_time" json:"startTime"`
+	EndTime    TimeOfDay `db:"end_time" json:"endTime"`
+}
+
+var employeesTable = db.NewTable[Employee]("employees")
+
+// Columns that employees can be looked up by.
+// Employees are always looked up within a user's own employees, so there is no column for the user.
+var EmployeeColumns = struct {
+	ID     db.Column[Employee]
+	Name   db.Column[Employee]
+	Active db.Column[Employee]
+}{
+	ID:     employeesTable.Column("employeeid"),
+	Name:   employeesTable.Column("name"),
+	Active: employeesTable.Column("active"),
+}
+
+var employeeUserID = employeesTable.Column("userid")
+
+// Checks an employee before it is stored.
+func (e *Employee) Validate() error {
+	if strings.TrimSpace(e.Name) == "" {
+		return errors.New("An employee needs a name.")
+	}
+
+	for _, availability := range e.Availability {
+		if availability.Weekday < int(time.Sunday) || availability.Weekday > int(time.Saturday) {
+			return errors.New("Weekday must be between 0 (Sunday) and 6 (Saturday).")
+		}
+
+		if !availability.StartTime.Valid || !availability.EndTime.Valid {
+			return errors.New("Availability needs a start and an end time.")
+		}
+
+		if availability.StartTime.Microseconds >= availability.EndTime.Microseconds {
+			return errors.New("Availability has to end after it starts.")
+		}
+	}
+
+	return nil
+}
+
+// Reports whether the employee works from start to end, which are in the business time zone.
+// A visit has to fit within one window, so visits that run past midnight only suit employees without any.
+func (e *Employee) isAvailable(start time.Time, end time.Time) bool {
+	if len(e.Availability) == 0 {
+		return true
+	}
+
+	startYear, startMonth, startDay := start.Date()
+	endYear, endMonth, endDay := end.Date()
+	if startYear != endYear || startMonth != endMonth || startDay != endDay {
+		return false
+	}
+
+	startTime := wallClockMicroseconds(start)
+	endTime := wallClockMicroseconds(end)
+
+	for _, window := range e.Availability {
+		if window.Weekday == int(start.Weekday()) &&
+			window.StartTime.Microseconds <= startTime &&
+			window.EndTime.Microseconds >= endTime {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Gets the wall clock time of t as a time of day is stored, i.e. in microseconds since midnight.
+func wallClockMicroseconds(t time.Time) int64 {
+	hour, minute, second := t.Clock()
+	clock := time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute + time.Duration(second)*time.Second
+
+	return clock.Microseconds()
+}
+
+// Fills in the availability of employees.
+func loadEmployeeAvailability(ctx context.Context, readConn db.ReadDBExecutor, employees []*Employee) error {
+	if len(employees) == 0 {
+		return nil
+	}
+
+	ids := make([]int32, 0, len(employees))
+	for _, employee := range employees {
+		ids = append(ids, int32(employee.ID))
+	}
+
+	availability := []EmployeeAvailability{}
+	err := readConn.SelectContext(ctx, &availability, `
+		SELECT employeeid, weekday, start_time, end_time
+		  FROM employee_availability
+		 WHERE employeeid = ANY($1)
+		 ORDER BY weekday, start_time
+	`, ids)
+
+	if err != nil {
+		return errors.Wrap(err, "Select")
+	}
+
+	for _, employee := range employees {
+		employee.Availability = []EmployeeAvailability{}
+
+		for _, window := range availability {
+			if window.EmployeeID == employee.ID {
+				employee.Availability = append(employee.Availability, window)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Replaces the availability stored for an employee with the one it has.
+func saveEmployeeAvailability(ctx context.Context, tx db.WriteDBExecutor, employee *Employee) error {
+	_, err := tx.ExecContext(ctx, `
+		DELETE FROM employee_availability
+		WHERE employeeid = $1
+	`, employee.ID)
+
+	if err != nil {
+		return errors.Wrap(err, "tx.Exec")
+	}
+
+	for i := range employee.Availability {
+		employee.Availability[i].EmployeeID = employee.ID
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO employee_availability
+			(employeeid, weekday, start_time, end_time)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT DO NOTHING
+		`, employee.ID, employee.Availability[i].Weekday, employee.Availability[i].StartTime, employee.Availability[i].EndTime)
+
+		if err != nil {
+			return errors.Wrap(err, "tx.Exec")
+		}
+	}
+
+	return nil
+}
+
+// Finds one employee that belongs to a user, along with their availability.
+// If runtime errors occur, an error is returned.
+// Otherwise, an employee and nil error is returned.
+func FindOneEmployee(ctx context.Context, readConn db.ReadDBExecutor, userID int, query db.Query[Employee]) (*Employee, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "FindOneEmployee")
+	defer cancel()
+
+	employee := &Employee{}
+
+	query = query.And(employeeUserID.Eq(userID)).Limit(1)
+	statement, args := employeesTable.Select(query)
+
+	err := readConn.GetContext(ctx, employee, statement, args...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "Get")
+	}
+
+	err = loadEmployeeAvailability(ctx, readConn, []*Employee{employee})
+	if err != nil {
+		return nil, errors.Wrap(err, "loadEmployeeAvailability")
+	}
+
+	return employee, nil
+}
+
+// Gets the employees of a user, along with their availability, by name.
+func QueryEmployees(ctx context.Context, readConn db.ReadDBExecutor, userID int) ([]*Employee, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "QueryEmployees")
+	defer cancel()
+
+	entries := []*Employee{}
+
+	query := db.Where(employeeUserID.Eq(userID)).OrderBy(EmployeeColumns.Name).OrderBy(EmployeeColumns.ID)
+	statement, args := employeesTable.Select(query)
+
+	err := readConn.SelectContext(ctx, &entries, statement, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Select")
+	}
+
+	err = loadEmployeeAvailability(ctx, readConn, entries)
+	if err != nil {
+		return nil, errors.Wrap(err, "loadEmployeeAvailability")
+	}
+
+	return entries, nil
+}
+
+// Creates an employee for a user.
+func CreateEmployee(ctx context.Context, tx db.WriteDBExecutor, userID int, employee Employee) (*Employee, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "CreateEmployee")
+	defer cancel()
+
+	err := employee.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	newEmployee := &Employee{}
+	err = tx.GetContext(ctx, newEmployee, `
+		INSERT INTO employees
+		(userid, name, email, phone, active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+employeesTable.Columns()+`
+	`, userID, employee.Name, employee.Email, employee.Phone, employee.Active)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "tx.Get")
+	}
+
+	newEmployee.Availability = append([]EmployeeAvailability{}, employee.Availability...)
+
+	err = saveEmployeeAvailability(ctx, tx, newEmployee)
+	if err != nil {
+		return nil, errors.Wrap(err, "saveEmployeeAvailability")
+	}
+
+	return newEmployee, nil
+}
+
+// Edits an employee that belongs to a user, replacing their availability.
+func EditEmployee(ctx context.Context, tx db.WriteDBExecutor, userID int, employee Employee) (*Employee, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "EditEmployee")
+	defer cancel()
+
+	err := employee.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	editedEmployee := &Employee{}
+	err = tx.GetContext(ctx, editedEmployee, `
+		UPDATE employees
+		SET   name   = $1
+		    , email  = $2
+		    , phone  = $3
+		    , active = $4
+		WHERE employeeid = $5
+		  AND userid = $6
+		RETURNING `+employeesTable.Columns()+`
+	`, employee.Name, employee.Email, employee.Phone, employee.Active, employee.ID, userID)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrRecordNotFound
+	} else if err != nil {
+		return nil, errors.Wrap(err, "tx.Get")
+	}
+
+	editedEmployee.Availability = append([]EmployeeAvailability{}, employee.Availability...)
+
+	err = saveEmployeeAvailability(ctx, tx, editedEmployee)
+	if err != nil {
+		return nil, errors.Wrap(err, "saveEmployeeAvailability")
+	}
+
+	return editedEmployee, nil
+}
+
+// Deletes an employee for a user, along with their crew memberships and assignments.
+// Employees who left but whose past visits should still show them are better made inactive.
+func DeleteEmployee(ctx context.Context, tx db.WriteDBExecutor, userID int, employeeID int) (bool, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "DeleteEmployee")
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM employees
+		WHERE employeeid = $1
+		  AND userid = $2
+	`, employeeID, userID)
+
+	if err != nil {
+		return false, errors.Wrap(err, "tx.Exec")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "RowsAffected")
+	}
+
+	if rowsAffected == 0 {
+		return false, ErrRecordNotFound
+	}
+
+	return true, nil
+}
@@ -0,0 +1,48 @@
+package data
+
+import (
+	"prime-shine-api/internal/assert"
+	"testing"
+	"time"
+)
+
+func TestEmployeeIsAvailable(t *testing.T) {
+	toronto, err := time.LoadLocation("America/Toronto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Monday and Wednesday mornings
+	employee := &Employee{
+		Availability: []EmployeeAvailability{
+			{Weekday: int(time.Monday), StartTime: NewTimeOfDay(8, 0), EndTime: NewTimeOfDay(12, 0)},
+			{Weekday: int(time.Wednesday), StartTime: NewTimeOfDay(8, 0), EndTime: NewTimeOfDay(12, 0)},
+		},
+	}
+
+	monday := func(hour int, minute int) time.Time {
+		return time.Date(2025, 8, 11, hour, minute, 0, 0, toronto)
+	}
+
+	tests := []struct {
+		name     string
+		employee *Employee
+		start    time.Time
+		end      time.Time
+		want     bool
+	}{
+		{"within a window", employee, monday(9, 0), monday(11, 0), true},
+		{"fills a window", employee, monday(8, 0), monday(12, 0), true},
+		{"starts too early", employee, monday(7, 30), monday(9, 0), false},
+		{"ends too late", employee, monday(11, 0), monday(12, 30), false},
+		{"another weekday", employee, monday(9, 0).AddDate(0, 0, 1), monday(11, 0).AddDate(0, 0, 1), false},
+		{"runs past midnight", employee, monday(23, 0), monday(1, 0).AddDate(0, 0, 1), false},
+		{"no availability at all", &Employee{}, monday(23, 0), monday(1, 0).AddDate(0, 0, 1), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.employee.isAvailable(tt.start, tt.end), tt.want)
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package data
+
+import (
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+)
+
+// Returned when a record does not exist or does not belong to the requesting user.
+var ErrRecordNotFound = errors.New("record not found")
+
+// Codes of the Postgres errors raised when a constraint is violated.
+const (
+	PG_UNIQUE_VIOLATION    = "23505"
+	PG_EXCLUSION_VIOLATION = "23P01"
+)
+
+// Reports whether err is Postgres refusing a write because of a constraint, e.g. PG_UNIQUE_VIOLATION.
+func isConstraintViolation(err error, code string) bool {
+	var pgError *pgconn.PgError
+	return errors.As(err, &pgError) && pgError.Code == code
+}
@@ -0,0 +1,131 @@
+package data
+
+import (
+	"context"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/db"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pkg/errors"
+)
+
+// How long an invitation link stays valid.
+const INVITATION_DURATION = 7 * 24 * time.Hour
+
+type Invitation struct {
+	ID         int                `db:"invitationid" json:"invitationID"`
+	Email      string             `db:"email" json:"email"`
+	Role       string             `db:"role" json:"role"`
+	TokenHash  string             `db:"token_hash" json:"-"`
+	InvitedBy  pgtype.Int4        `db:"invited_by" json:"invitedBy"`
+	AccountID  int                `db:"accountid" json:"-"`
+	CreatedAt  pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+	ExpiresAt  pgtype.Timestamptz `db:"expires_at" json:"expiresAt"`
+	AcceptedAt pgtype.Timestamptz `db:"accepted_at" json:"acceptedAt"`
+}
+
+func findInvitationByTokenHash(ctx context.Context, readConn db.ReadDBExecutor, tokenHash string) (*Invitation, error) {
+	invitation := &Invitation{}
+	err := readConn.GetContext(ctx, invitation, `
+		SELECT *
+		  FROM invitations
+		 WHERE token_hash = $1
+	`, tokenHash)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Get")
+	}
+
+	return invitation, nil
+}
+
+// Invites someone to create an account with the given role, working in the inviter's account.
+// Any previous, unaccepted invitations for the same email are invalidated.
+// The plaintext token is returned alongside the invitation; only its hash is stored.
+func CreateInvitation(ctx context.Context, tx db.WriteDBExecutor, email string, role string, accountID int, invitedBy int) (*Invitation, string, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "CreateInvitation")
+	defer cancel()
+
+	if !internal.IsValidRole(role) {
+		return nil, "", errors.Errorf("Invalid role: %v", role)
+	}
+
+	query := db.Where(UserColumns.Email.Eq(email))
+	foundUser, err := FindOneUser(ctx, tx, query)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "FindOneUser")
+	}
+
+	if foundUser != nil {
+		return nil, "", errors.New("User with that email exists.")
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE invitations
+		SET    expires_at = now()
+		WHERE email = $1
+		  AND accepted_at IS NULL
+		  AND expires_at > now()
+	`, email)
+
+	if err != nil {
+		return nil, "", errors.Wrap(err, "tx.Exec")
+	}
+
+	token, tokenHash, err := internal.GenerateOpaqueToken()
+	if err != nil {
+		return nil, "", errors.Wrap(err, "GenerateOpaqueToken")
+	}
+
+	invitation := &Invitation{}
+	err = tx.GetContext(ctx, invitation, `
+		INSERT INTO invitations
+		(email, role, token_hash, invited_by, accountid, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING *
+	`, email, role, tokenHash, invitedBy, accountID, time.Now().Add(INVITATION_DURATION))
+
+	if err != nil {
+		return nil, "", errors.Wrap(err, "tx.Get")
+	}
+
+	return invitation, token, nil
+}
+
+// Marks an invitation as accepted and returns it.
+// If the invitation does not exist, has expired, or was already accepted, ErrRecordNotFound is returned.
+func AcceptInvitation(ctx context.Context, tx db.WriteDBExecutor, token string) (*Invitation, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "AcceptInvitation")
+	defer cancel()
+
+	tokenHash := internal.HashOpaqueToken(token)
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE invitations
+		SET    accepted_at = now()
+		WHERE token_hash = $1
+		  AND accepted_at IS NULL
+		  AND expires_at > now()
+	`, tokenHash)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "tx.Exec")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, errors.Wrap(err, "RowsAffected")
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	invitation, err := findInvitationByTokenHash(ctx, tx, tokenHash)
+	if err != nil {
+		return nil, errors.Wrap(err, "findInvitationByTokenHash")
+	}
+
+	return invitation, nil
+}
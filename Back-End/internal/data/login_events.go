@@ -0,0 +1,141 @@
+package data
+
+import (
+	"context"
+	"prime-shine-api/internal/db"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pkg/errors"
+)
+
+type LoginEvent struct {
+	ID        int                `db:"logineventid" json:"loginEventID"`
+	UserID    pgtype.Int4        `db:"userid" json:"userID"`
+	Email     string             `db:"email" json:"email"`
+	IPAddress string             `db:"ip_address" json:"ipAddress"`
+	Succeeded bool               `db:"succeeded" json:"succeeded"`
+	CreatedAt pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+}
+
+// Summarizes the failed login attempts of a client since its last successful login.
+type LoginFailures struct {
+	Count       int                `db:"count"`
+	LastFailure pgtype.Timestamptz `db:"last_failure"`
+}
+
+// Arbitrary, but fixed: the first key of the advisory locks that serialize login attempts.
+// Two-key advisory locks do not share a key space with MIGRATION_LOCK_ID.
+const (
+	LOGIN_EMAIL_LOCK_CLASS      = 7_245
+	LOGIN_IP_ADDRESS_LOCK_CLASS = 7_246
+)
+
+// Makes other login attempts for the same email or from the same IP address wait until tx ends.
+// Otherwise parallel attempts could all pass the throttle before any of their failures is recorded.
+// The email is locked first in every attempt, so that two attempts can not deadlock.
+func LockLoginThrottle(ctx context.Context, tx db.WriteDBExecutor, email string, ipAddress string) error {
+	ctx, cancel := db.WithQueryTimeout(ctx, "LockLoginThrottle")
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, `
+		SELECT pg_advisory_xact_lock($1, hashtext($2))
+	`, LOGIN_EMAIL_LOCK_CLASS, email)
+
+	if err != nil {
+		return errors.Wrap(err, "tx.Exec")
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		SELECT pg_advisory_xact_lock($1, hashtext($2))
+	`, LOGIN_IP_ADDRESS_LOCK_CLASS, ipAddress)
+
+	if err != nil {
+		return errors.Wrap(err, "tx.Exec")
+	}
+
+	return nil
+}
+
+// Records a login attempt.
+func RecordLoginEvent(ctx context.Context, tx db.WriteDBExecutor, email string, ipAddress string, succeeded bool) error {
+	ctx, cancel := db.WithQueryTimeout(ctx, "RecordLoginEvent")
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO login_events
+		(userid, email, ip_address, succeeded)
+		VALUES ((SELECT userid FROM users WHERE email = $1), $1, $2, $3)
+	`, email, ipAddress, succeeded)
+
+	if err != nil {
+		return errors.Wrap(err, "tx.Exec")
+	}
+
+	return nil
+}
+
+// Counts failed logins matching a column since the later of `since` and the last successful login.
+// The column is never user supplied.
+func countLoginFailures(ctx context.Context, readConn db.ReadDBExecutor, column string, value string, since time.Time) (*LoginFailures, error) {
+	failures := &LoginFailures{}
+	err := readConn.GetContext(ctx, failures, `
+		SELECT count(*)        AS count
+		     , max(created_at) AS last_failure
+		  FROM login_events
+		 WHERE `+column+` = $1
+		   AND NOT succeeded
+		   AND created_at > GREATEST($2, (
+		           SELECT max(created_at)
+		             FROM login_events
+		            WHERE `+column+` = $1
+		              AND succeeded
+		       ))
+	`, value, since)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Get")
+	}
+
+	return failures, nil
+}
+
+// Counts recent failed logins against an account.
+func CountAccountLoginFailures(ctx context.Context, readConn db.ReadDBExecutor, email string, since time.Time) (*LoginFailures, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "CountAccountLoginFailures")
+	defer cancel()
+
+	return countLoginFailures(ctx, readConn, "email", email, since)
+}
+
+// Counts recent failed logins from an IP address.
+func CountIPLoginFailures(ctx context.Context, readConn db.ReadDBExecutor, ipAddress string, since time.Time) (*LoginFailures, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "CountIPLoginFailures")
+	defer cancel()
+
+	return countLoginFailures(ctx, readConn, "ip_address", ipAddress, since)
+}
+
+// Grabs login events, most recent first.
+// If email is not empty, only events for that email are returned.
+func QueryLoginEvents(ctx context.Context, readConn db.ReadDBExecutor, email string, pageNum int, pageSize int) ([]*LoginEvent, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "QueryLoginEvents")
+	defer cancel()
+
+	entries := []*LoginEvent{}
+	query := `
+		SELECT *
+		  FROM login_events
+		 WHERE ($1 = '' OR email = $1)
+		 ORDER BY created_at DESC
+		 LIMIT $2
+		OFFSET $3
+	`
+
+	err := readConn.SelectContext(ctx, &entries, query, email, pageSize, (pageNum-1)*pageSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "Select")
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,263 @@
+package data
+
+import (
+	"context"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/db"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// How long a user has to enter their TOTP code after entering their password.
+	MFA_CHALLENGE_DURATION = 5 * time.Minute
+
+	// How many codes can be tried against a single MFA challenge.
+	MFA_CHALLENGE_MAX_ATTEMPTS = 5
+)
+
+// Stores a new TOTP secret for a user that has not finished enrolling yet.
+// The secret is not used for logins until EnableTOTP is called.
+func BeginTOTPEnrollment(ctx context.Context, tx db.WriteDBExecutor, userID int, secret string) error {
+	ctx, cancel := db.WithQueryTimeout(ctx, "BeginTOTPEnrollment")
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET   totp_secret    = $1
+		    , totp_last_step = NULL
+		WHERE userid = $2
+		  AND NOT totp_enabled
+	`, secret, userID)
+
+	if err != nil {
+		return errors.Wrap(err, "tx.Exec")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "RowsAffected")
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("Two-factor authentication is already enabled.")
+	}
+
+	return nil
+}
+
+// Turns on TOTP for a user and replaces their recovery codes.
+func EnableTOTP(ctx context.Context, tx db.WriteDBExecutor, userID int, recoveryCodes []string) error {
+	ctx, cancel := db.WithQueryTimeout(ctx, "EnableTOTP")
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET    totp_enabled = true
+		WHERE userid = $1
+		  AND totp_secret IS NOT NULL
+	`, userID)
+
+	if err != nil {
+		return errors.Wrap(err, "tx.Exec")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "RowsAffected")
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("Two-factor authentication enrollment was not started.")
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM recovery_codes
+		WHERE userid = $1
+	`, userID)
+
+	if err != nil {
+		return errors.Wrap(err, "tx.Exec")
+	}
+
+	for _, code := range recoveryCodes {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO recovery_codes
+			(userid, code_hash)
+			VALUES ($1, $2)
+		`, userID, internal.HashRecoveryCode(code))
+
+		if err != nil {
+			return errors.Wrap(err, "tx.Exec")
+		}
+	}
+
+	return nil
+}
+
+// Turns off TOTP for a user, discarding the secret and recovery codes.
+func DisableTOTP(ctx context.Context, tx db.WriteDBExecutor, userID int) error {
+	ctx, cancel := db.WithQueryTimeout(ctx, "DisableTOTP")
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET   totp_secret    = NULL
+		    , totp_enabled   = false
+		    , totp_last_step = NULL
+		WHERE userid = $1
+	`, userID)
+
+	if err != nil {
+		return errors.Wrap(err, "tx.Exec")
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM recovery_codes
+		WHERE userid = $1
+	`, userID)
+
+	if err != nil {
+		return errors.Wrap(err, "tx.Exec")
+	}
+
+	return nil
+}
+
+// Records that a TOTP time step was used by a user.
+// Returns false if that step (or a later one) was already used, i.e. the code is being replayed.
+func UseTOTPStep(ctx context.Context, tx db.WriteDBExecutor, userID int, step int64) (bool, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "UseTOTPStep")
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET    totp_last_step = $1
+		WHERE userid = $2
+		  AND (totp_last_step IS NULL OR totp_last_step < $1)
+	`, step, userID)
+
+	if err != nil {
+		return false, errors.Wrap(err, "tx.Exec")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "RowsAffected")
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// Marks one of a user's recovery codes as used.
+// Returns false if the code does not exist or was already used.
+func UseRecoveryCode(ctx context.Context, tx db.WriteDBExecutor, userID int, code string) (bool, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "UseRecoveryCode")
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE recovery_codes
+		SET    used_at = now()
+		WHERE userid = $1
+		  AND code_hash = $2
+		  AND used_at IS NULL
+	`, userID, internal.HashRecoveryCode(code))
+
+	if err != nil {
+		return false, errors.Wrap(err, "tx.Exec")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "RowsAffected")
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// Creates a challenge that must be completed with a second factor before a login session is started.
+// The plaintext challenge token is returned; only its hash is stored.
+func CreateMFAChallenge(ctx context.Context, tx db.WriteDBExecutor, userID int) (string, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "CreateMFAChallenge")
+	defer cancel()
+
+	token, tokenHash, err := internal.GenerateOpaqueToken()
+	if err != nil {
+		return "", errors.Wrap(err, "GenerateOpaqueToken")
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO mfa_challenges
+		(userid, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`, userID, tokenHash, time.Now().Add(MFA_CHALLENGE_DURATION))
+
+	if err != nil {
+		return "", errors.Wrap(err, "tx.Exec")
+	}
+
+	return token, nil
+}
+
+// Counts an attempt against an MFA challenge and returns the ID of the user it belongs to.
+// If the challenge does not exist, has expired, was completed, or ran out of attempts, ErrRecordNotFound is returned.
+func AttemptMFAChallenge(ctx context.Context, tx db.WriteDBExecutor, token string) (int, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "AttemptMFAChallenge")
+	defer cancel()
+
+	tokenHash := internal.HashOpaqueToken(token)
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE mfa_challenges
+		SET    attempts = attempts + 1
+		WHERE token_hash = $1
+		  AND used_at IS NULL
+		  AND expires_at > now()
+		  AND attempts < $2
+	`, tokenHash, MFA_CHALLENGE_MAX_ATTEMPTS)
+
+	if err != nil {
+		return 0, errors.Wrap(err, "tx.Exec")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, "RowsAffected")
+	}
+
+	if rowsAffected == 0 {
+		return 0, ErrRecordNotFound
+	}
+
+	var userID int
+	err = tx.GetContext(ctx, &userID, `
+		SELECT userid
+		  FROM mfa_challenges
+		 WHERE token_hash = $1
+	`, tokenHash)
+
+	if err != nil {
+		return 0, errors.Wrap(err, "Get")
+	}
+
+	return userID, nil
+}
+
+// Marks an MFA challenge as completed so it can not be used again.
+func CompleteMFAChallenge(ctx context.Context, tx db.WriteDBExecutor, token string) error {
+	ctx, cancel := db.WithQueryTimeout(ctx, "CompleteMFAChallenge")
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, `
+		UPDATE mfa_challenges
+		SET    used_at = now()
+		WHERE token_hash = $1
+	`, internal.HashOpaqueToken(token))
+
+	if err != nil {
+		return errors.Wrap(err, "tx.Exec")
+	}
+
+	return nil
+}
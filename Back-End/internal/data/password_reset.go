@@ -0,0 +1,101 @@
+package data
+
+import (
+	"context"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/db"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// How long a password reset link stays valid.
+const PASSWORD_RESET_TOKEN_DURATION = time.Hour
+
+// Creates a single-use password reset token for a user.
+// Any previously issued, unused tokens of the user are invalidated.
+// The plaintext token is returned; only its hash is stored.
+func CreatePasswordResetToken(ctx context.Context, tx db.WriteDBExecutor, userID int) (string, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "CreatePasswordResetToken")
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, `
+		UPDATE password_reset_tokens
+		SET    used_at = now()
+		WHERE userid = $1
+		  AND used_at IS NULL
+	`, userID)
+
+	if err != nil {
+		return "", errors.Wrap(err, "tx.Exec")
+	}
+
+	token, tokenHash, err := internal.GenerateOpaqueToken()
+	if err != nil {
+		return "", errors.Wrap(err, "GenerateOpaqueToken")
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO password_reset_tokens
+		(userid, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`, userID, tokenHash, time.Now().Add(PASSWORD_RESET_TOKEN_DURATION))
+
+	if err != nil {
+		return "", errors.Wrap(err, "tx.Exec")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return "", errors.Wrap(err, "RowsAffected")
+	}
+
+	if rowsAffected != 1 {
+		return "", errors.New("failed to insert password_reset_tokens entry")
+	}
+
+	return token, nil
+}
+
+// Marks a password reset token as used and returns the ID of the user it was issued for.
+// If the token does not exist, has expired, or was already used, ErrRecordNotFound is returned.
+func ConsumePasswordResetToken(ctx context.Context, tx db.WriteDBExecutor, token string) (int, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "ConsumePasswordResetToken")
+	defer cancel()
+
+	tokenHash := internal.HashOpaqueToken(token)
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE password_reset_tokens
+		SET    used_at = now()
+		WHERE token_hash = $1
+		  AND used_at IS NULL
+		  AND expires_at > now()
+	`, tokenHash)
+
+	if err != nil {
+		return 0, errors.Wrap(err, "tx.Exec")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, "RowsAffected")
+	}
+
+	if rowsAffected == 0 {
+		return 0, ErrRecordNotFound
+	}
+
+	var userID int
+	err = tx.GetContext(ctx, &userID, `
+		SELECT userid
+		  FROM password_reset_tokens
+		 WHERE token_hash = $1
+	`, tokenHash)
+
+	if err != nil {
+		return 0, errors.Wrap(err, "Get")
+	}
+
+	return userID, nil
+}
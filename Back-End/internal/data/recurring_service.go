@@ -0,0 +1,522 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"prime-shine-api/internal/db"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pkg/errors"
+)
+
+// How often a recurring service takes place.
+const (
+	FREQUENCY_WEEKLY   = "weekly"
+	FREQUENCY_BIWEEKLY = "biweekly" // every other week, counted from the first occurrence on or after the start date
+	FREQUENCY_MONTHLY  = "monthly"  // the nth weekday of every month, see WeekOfMonth
+)
+
+// Week of the month for monthly services that take place in the last week, however many weeks the month has.
+const LAST_WEEK_OF_MONTH = -1
+
+// Schedules cover a week, starting on their start day.
+const SCHEDULE_LENGTH_DAYS = 7
+
+// A wall clock time, such as the start of a service. It is sent as "15:04" in JSON.
+type TimeOfDay struct {
+	pgtype.Time
+}
+
+func NewTimeOfDay(hour int, minute int) TimeOfDay {
+	microseconds := (time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute).Microseconds()
+	return TimeOfDay{pgtype.Time{Microseconds: microseconds, Valid: true}}
+}
+
+func (t TimeOfDay) Clock() (hour int, minute int) {
+	minutes := t.Microseconds / time.Minute.Microseconds()
+	return int(minutes / 60), int(minutes % 60)
+}
+
+func (t TimeOfDay) MarshalJSON() ([]byte, error) {
+	if !t.Valid {
+		return []byte("null"), nil
+	}
+
+	hour, minute := t.Clock()
+	return json.Marshal(fmt.Sprintf("%02d:%02d", hour, minute))
+}
+
+func (t *TimeOfDay) UnmarshalJSON(b []byte) error {
+	var s *string
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+
+	if s == nil {
+		*t = TimeOfDay{}
+		return nil
+	}
+
+	clock, err := time.Parse("15:04", *s)
+	if err != nil {
+		return errors.Errorf("%q is not a time of day like 15:04", *s)
+	}
+
+	*t = NewTimeOfDay(clock.Hour(), clock.Minute())
+	return nil
+}
+
+// A customer that is serviced on a regular basis, e.g. every other Tuesday from 9:00 to 11:00.
+// Recurring services are turned into scheduled customers whenever a schedule is created.
+type RecurringService struct {
+	ID          int         `db:"recurringserviceid" json:"recurringServiceID"`
+	UserID      int         `db:"userid" json:"-"`
+	CustomerID  string      `db:"wave_customerid" json:"waveCustomerID"`
+	Weekday     int         `db:"weekday" json:"weekday"` // 0 is Sunday, as in time.Weekday
+	StartTime   TimeOfDay   `db:"start_time" json:"startTime"`
+	EndTime     TimeOfDay   `db:"end_time" json:"endTime"`
+	Frequency   string      `db:"frequency" json:"frequency"`
+	WeekOfMonth pgtype.Int2 `db:"week_of_month" json:"weekOfMonth"` // 1 to 4 or LAST_WEEK_OF_MONTH, for monthly services
+	StartDate   pgtype.Date `db:"start_date" json:"startDate"`
+	EndDate     pgtype.Date `db:"end_date" json:"endDate"` // inclusive; NULL if the service never ends
+
+	// Dates the service does not take place on, e.g. holidays.
+	SkipDates []pgtype.Date `db:"-" json:"skipDates"`
+}
+
+var recurringServicesTable = db.NewTable[RecurringService]("recurring_services")
+
+// Columns that recurring services can be looked up by.
+// Recurring services are always looked up within a user's own services, so there is no column for the user.
+var RecurringServiceColumns = struct {
+	ID         db.Column[RecurringService]
+	CustomerID db.Column[RecurringService]
+	Weekday    db.Column[RecurringService]
+	StartTime  db.Column[RecurringService]
+	StartDate  db.Column[RecurringService]
+	EndDate    db.Column[RecurringService]
+}{
+	ID:         recurringServicesTable.Column("recurringserviceid"),
+	CustomerID: recurringServicesTable.Column("wave_customerid"),
+	Weekday:    recurringServicesTable.Column("weekday"),
+	StartTime:  recurringServicesTable.Column("start_time"),
+	StartDate:  recurringServicesTable.Column("start_date"),
+	EndDate:    recurringServicesTable.Column("end_date"),
+}
+
+var recurringServiceUserID = recurringServicesTable.Column("userid")
+
+type recurringServiceSkip struct {
+	RecurringServiceID int         `db:"recurringserviceid"`
+	SkipDate           pgtype.Date `db:"skip_date"`
+}
+
+// Checks a recurring service before it is stored.
+// The database enforces most of this too, but with messages nobody can act on.
+func (s *RecurringService) Validate() error {
+	if s.CustomerID == "" {
+		return errors.New("A recurring service needs a customer.")
+	}
+
+	if s.Weekday < int(time.Sunday) || s.Weekday > int(time.Saturday) {
+		return errors.New("Weekday must be between 0 (Sunday) and 6 (Saturday).")
+	}
+
+	if !s.StartTime.Valid || !s.EndTime.Valid {
+		return errors.New("A recurring service needs a start and an end time.")
+	}
+
+	if s.StartTime.Microseconds >= s.EndTime.Microseconds {
+		return errors.New("A recurring service has to end after it starts.")
+	}
+
+	switch s.Frequency {
+	case FREQUENCY_WEEKLY, FREQUENCY_BIWEEKLY:
+		if s.WeekOfMonth.Valid {
+			return errors.New("Only monthly services have a week of the month.")
+		}
+	case FREQUENCY_MONTHLY:
+		if !s.WeekOfMonth.Valid || !slices.Contains([]int16{1, 2, 3, 4, LAST_WEEK_OF_MONTH}, s.WeekOfMonth.Int16) {
+			return errors.New("Monthly services need a week of the month between 1 and 4, or -1 for the last week.")
+		}
+	default:
+		return errors.Errorf("Frequency must be %v, %v or %v.", FREQUENCY_WEEKLY, FREQUENCY_BIWEEKLY, FREQUENCY_MONTHLY)
+	}
+
+	if !s.StartDate.Valid {
+		return errors.New("A recurring service needs a start date.")
+	}
+
+	if s.EndDate.Valid && s.EndDate.Time.Before(s.StartDate.Time) {
+		return errors.New("A recurring service can not end before it starts.")
+	}
+
+	return nil
+}
+
+// Reports whether the service takes place on a date. Only the year, month and day of date are used.
+func (s *RecurringService) OccursOn(date time.Time) bool {
+	day := calendarDay(date)
+
+	if int(day.Weekday()) != s.Weekday {
+		return false
+	}
+
+	if day.Before(calendarDay(s.StartDate.Time)) {
+		return false
+	}
+
+	if s.EndDate.Valid && day.After(calendarDay(s.EndDate.Time)) {
+		return false
+	}
+
+	for _, skipDate := range s.SkipDates {
+		if calendarDay(skipDate.Time).Equal(day) {
+			return false
+		}
+	}
+
+	switch s.Frequency {
+	case FREQUENCY_WEEKLY:
+		return true
+	case FREQUENCY_BIWEEKLY:
+		start := calendarDay(s.StartDate.Time)
+		daysUntilWeekday := (s.Weekday - int(start.Weekday()) + 7) % 7
+		first := start.AddDate(0, 0, daysUntilWeekday)
+
+		weeks := int(day.Sub(first).Hours()) / 24 / 7
+		return weeks%2 == 0
+	case FREQUENCY_MONTHLY:
+		if s.WeekOfMonth.Int16 == LAST_WEEK_OF_MONTH {
+			return day.AddDate(0, 0, 7).Month() != day.Month()
+		}
+
+		return (day.Day()-1)/7+1 == int(s.WeekOfMonth.Int16)
+	}
+
+	return false
+}
+
+// The start and end of the service on a date, in the business time zone given by location.
+func (s *RecurringService) TimesOn(date time.Time, location *time.Location) (time.Time, time.Time) {
+	at := func(clock TimeOfDay) time.Time {
+		hour, minute := clock.Clock()
+		return time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, location)
+	}
+
+	return at(s.StartTime), at(s.EndTime)
+}
+
+// Midnight UTC of a date, so that dates can be compared and counted in whole days.
+func calendarDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Fills in the skip dates of recurring services.
+func loadRecurringServiceSkips(ctx context.Context, readConn db.ReadDBExecutor, services []*RecurringService) error {
+	if len(services) == 0 {
+		return nil
+	}
+
+	ids := make([]int32, 0, len(services))
+	for _, service := range services {
+		ids = append(ids, int32(service.ID))
+	}
+
+	skips := []recurringServiceSkip{}
+	err := readConn.SelectContext(ctx, &skips, `
+		SELECT recurringserviceid, skip_date
+		  FROM recurring_service_skips
+		 WHERE recurringserviceid = ANY($1)
+		 ORDER BY skip_date
+	`, ids)
+
+	if err != nil {
+		return errors.Wrap(err, "Select")
+	}
+
+	for _, service := range services {
+		service.SkipDates = []pgtype.Date{}
+
+		for _, skip := range skips {
+			if skip.RecurringServiceID == service.ID {
+				service.SkipDates = append(service.SkipDates, skip.SkipDate)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Replaces the skip dates stored for a recurring service with the ones it has.
+func saveRecurringServiceSkips(ctx context.Context, tx db.WriteDBExecutor, service *RecurringService) error {
+	_, err := tx.ExecContext(ctx, `
+		DELETE FROM recurring_service_skips
+		WHERE recurringserviceid = $1
+	`, service.ID)
+
+	if err != nil {
+		return errors.Wrap(err, "tx.Exec")
+	}
+
+	for _, skipDate := range service.SkipDates {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO recurring_service_skips
+			(recurringserviceid, skip_date)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, service.ID, skipDate)
+
+		if err != nil {
+			return errors.Wrap(err, "tx.Exec")
+		}
+	}
+
+	return nil
+}
+
+// Finds one recurring service that belongs to a user, along with its skip dates.
+// If runtime errors occur, an error is returned.
+// Otherwise, a recurring service and nil error is returned.
+func FindOneRecurringService(ctx context.Context, readConn db.ReadDBExecutor, userID int, query db.Query[RecurringService]) (*RecurringService, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "FindOneRecurringService")
+	defer cancel()
+
+	service := &RecurringService{}
+
+	query = query.And(recurringServiceUserID.Eq(userID)).Limit(1)
+	statement, args := recurringServicesTable.Select(query)
+
+	err := readConn.GetContext(ctx, service, statement, args...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "Get")
+	}
+
+	err = loadRecurringServiceSkips(ctx, readConn, []*RecurringService{service})
+	if err != nil {
+		return nil, errors.Wrap(err, "loadRecurringServiceSkips")
+	}
+
+	return service, nil
+}
+
+// Gets the recurring services of a user, along with their skip dates, ordered by weekday and start time.
+func QueryRecurringServices(ctx context.Context, readConn db.ReadDBExecutor, userID int) ([]*RecurringService, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "QueryRecurringServices")
+	defer cancel()
+
+	query := db.Where(recurringServiceUserID.Eq(userID))
+	return queryRecurringServices(ctx, readConn, query)
+}
+
+func queryRecurringServices(ctx context.Context, readConn db.ReadDBExecutor, query db.Query[RecurringService]) ([]*RecurringService, error) {
+	entries := []*RecurringService{}
+
+	query = query.OrderBy(RecurringServiceColumns.Weekday).
+		OrderBy(RecurringServiceColumns.StartTime).
+		OrderBy(RecurringServiceColumns.ID)
+	statement, args := recurringServicesTable.Select(query)
+
+	err := readConn.SelectContext(ctx, &entries, statement, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Select")
+	}
+
+	err = loadRecurringServiceSkips(ctx, readConn, entries)
+	if err != nil {
+		return nil, errors.Wrap(err, "loadRecurringServiceSkips")
+	}
+
+	return entries, nil
+}
+
+// Creates a recurring service for a user.
+func CreateRecurringService(ctx context.Context, tx db.WriteDBExecutor, userID int, service RecurringService) (*RecurringService, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "CreateRecurringService")
+	defer cancel()
+
+	err := service.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	newService := &RecurringService{}
+	err = tx.GetContext(ctx, newService, `
+		INSERT INTO recurring_services
+		(userid, wave_customerid, weekday, start_time, end_time, frequency, week_of_month, start_date, end_date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+recurringServicesTable.Columns()+`
+	`, userID, service.CustomerID, service.Weekday, service.StartTime, service.EndTime,
+		service.Frequency, service.WeekOfMonth, service.StartDate, service.EndDate)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "tx.Get")
+	}
+
+	newService.SkipDates = service.SkipDates
+	if newService.SkipDates == nil {
+		newService.SkipDates = []pgtype.Date{}
+	}
+
+	err = saveRecurringServiceSkips(ctx, tx, newService)
+	if err != nil {
+		return nil, errors.Wrap(err, "saveRecurringServiceSkips")
+	}
+
+	return newService, nil
+}
+
+// Edits a recurring service that belongs to a user, replacing its skip dates.
+// Scheduled customers that were generated from it already are left alone.
+func EditRecurringService(ctx context.Context, tx db.WriteDBExecutor, userID int, service RecurringService) (*RecurringService, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "EditRecurringService")
+	defer cancel()
+
+	err := service.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	editedService := &RecurringService{}
+	err = tx.GetContext(ctx, editedService, `
+		UPDATE recurring_services
+		SET   wave_customerid = $1
+		    , weekday         = $2
+		    , start_time      = $3
+		    , end_time        = $4
+		    , frequency       = $5
+		    , week_of_month   = $6
+		    , start_date      = $7
+		    , end_date        = $8
+		WHERE recurringserviceid = $9
+		  AND userid = $10
+		RETURNING `+recurringServicesTable.Columns()+`
+	`, service.CustomerID, service.Weekday, service.StartTime, service.EndTime,
+		service.Frequency, service.WeekOfMonth, service.StartDate, service.EndDate, service.ID, userID)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrRecordNotFound
+	} else if err != nil {
+		return nil, errors.Wrap(err, "tx.Get")
+	}
+
+	editedService.SkipDates = service.SkipDates
+	if editedService.SkipDates == nil {
+		editedService.SkipDates = []pgtype.Date{}
+	}
+
+	err = saveRecurringServiceSkips(ctx, tx, editedService)
+	if err != nil {
+		return nil, errors.Wrap(err, "saveRecurringServiceSkips")
+	}
+
+	return editedService, nil
+}
+
+// Deletes a recurring service for a user.
+// Scheduled customers that were generated from it stay in their schedules.
+func DeleteRecurringService(ctx context.Context, tx db.WriteDBExecutor, userID int, recurringServiceID int) (bool, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "DeleteRecurringService")
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM recurring_services
+		WHERE recurringserviceid = $1
+		  AND userid = $2
+	`, recurringServiceID, userID)
+
+	if err != nil {
+		return false, errors.Wrap(err, "tx.Exec")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "RowsAffected")
+	}
+
+	if rowsAffected == 0 {
+		return false, ErrRecordNotFound
+	}
+
+	return true, nil
+}
+
+// Adds the recurring services of a user that take place during a schedule's week to the schedule.
+// Services that are in the schedule already, either generated earlier or entered by hand for the same
+// customer on the same day, are not added again, so populating a schedule twice changes nothing.
+// Services take place at their wall clock times in the business time zone, given by location.
+// The scheduled customers that were added are returned.
+func PopulateSchedule(ctx context.Context, tx db.WriteDBExecutor, userID int, schedule *Schedule, location *time.Location) ([]*ScheduledCustomer, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "PopulateSchedule")
+	defer cancel()
+
+	weekStart := calendarDay(schedule.StartDay.Time)
+	weekEnd := weekStart.AddDate(0, 0, SCHEDULE_LENGTH_DAYS-1)
+
+	query := db.Where(
+		recurringServiceUserID.Eq(userID),
+		RecurringServiceColumns.StartDate.Lte(db.GetDateFromTimeStruct(weekEnd)),
+	)
+
+	services, err := queryRecurringServices(ctx, tx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "queryRecurringServices")
+	}
+
+	existing, err := QueryScheduledCustomers(ctx, tx, userID, schedule.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "QueryScheduledCustomers")
+	}
+
+	added := []*ScheduledCustomer{}
+
+	for dayOffset := 0; dayOffset < SCHEDULE_LENGTH_DAYS; dayOffset++ {
+		date := weekStart.AddDate(0, 0, dayOffset)
+
+		for _, service := range services {
+			if !service.OccursOn(date) {
+				continue
+			}
+
+			scheduled := slices.ContainsFunc(existing, func(scheduledCustomer *ScheduledCustomer) bool {
+				if scheduledCustomer.DayOffset != dayOffset {
+					return false
+				}
+
+				generated := scheduledCustomer.RecurringServiceID.Valid && int(scheduledCustomer.RecurringServiceID.Int32) == service.ID
+				return generated || scheduledCustomer.CustomerID == service.CustomerID
+			})
+
+			if scheduled {
+				continue
+			}
+
+			startTime, endTime := service.TimesOn(date, location)
+
+			scheduledCustomer := &ScheduledCustomer{}
+			err := tx.GetContext(ctx, scheduledCustomer, `
+				INSERT INTO scheduled_customers
+				(wave_customerid, start_time, end_time, day_offset, scheduleid, recurringserviceid)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				RETURNING `+scheduledCustomersTable.Columns()+`
+			`, service.CustomerID, db.GetTimestamptzFromTimeStruct(startTime), db.GetTimestamptzFromTimeStruct(endTime),
+				dayOffset, schedule.ID, service.ID)
+
+			if err != nil {
+				return nil, errors.Wrap(err, "tx.Get")
+			}
+
+			existing = append(existing, scheduledCustomer)
+			added = append(added, scheduledCustomer)
+		}
+	}
+
+	return added, nil
+}
@@ -0,0 +1,530 @@
+package data_test
+
+import (
+	"context"
+	"os"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/assert"
+	"prime-shine-api/internal/data"
+	"prime-shine-api/internal/db"
+	"prime-shine-api/internal/testdb"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(testdb.Run(m))
+}
+
+// Starts a transaction the way the API does for each request.
+// It is rolled back when the test ends unless the test did so itself.
+func newTestTx(t *testing.T, conn *sqlx.DB) *db.LazyTx {
+	t.Helper()
+
+	tx := db.NewLazyTx(context.Background(), conn)
+	t.Cleanup(func() {
+		tx.Rollback()
+	})
+
+	return tx
+}
+
+// Rolls the transaction back and checks that none of its writes are left, i.e. that query counts 0 rows.
+func assertRolledBack(t *testing.T, tx *db.LazyTx, conn *sqlx.DB, query string, args ...interface{}) {
+	t.Helper()
+
+	err := tx.Rollback()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	err = conn.Get(&count, query, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, count, 0)
+}
+
+func TestRepositoryUser(t *testing.T) {
+	conn := testdb.New(t)
+	ctx := context.Background()
+	tx := newTestTx(t, conn)
+
+	ownerID := testdb.CreateUser(t, conn, "owner@example.com", internal.ROLE_OWNER)
+
+	_, token, err := data.CreateInvitation(ctx, tx, "cleaner@example.com", internal.ROLE_CLEANER, ownerID, ownerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := data.CreateUser(ctx, tx, token, "Cleaner", "correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotEqual(t, created.ID, 0)
+	assert.Equal(t, created.Email, "cleaner@example.com")
+	assert.Equal(t, created.Role, internal.ROLE_CLEANER)
+	assert.Equal(t, created.EmailVerifiedAt.Valid, true)
+
+	// invited users work in the inviter's account, which can not be deleted while they do
+	assert.Equal(t, created.AccountID(), ownerID)
+
+	_, err = data.DeleteUser(ctx, tx, ownerID)
+	assert.Equal(t, errors.Is(err, data.ErrAccountHasMembers), true)
+
+	found, err := data.FindOneUser(ctx, tx, db.Where(data.UserColumns.ID.Eq(created.ID)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, found.ID, created.ID)
+	assert.Equal(t, found.Name, created.Name)
+	assert.Equal(t, found.Email, created.Email)
+	assert.Equal(t, found.Password, created.Password)
+	assert.Equal(t, found.Role, created.Role)
+
+	missing, err := data.FindOneUser(ctx, tx, db.Where(data.UserColumns.Email.Eq("nobody@example.com")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, missing, nil)
+
+	assertRolledBack(t, tx, conn, `SELECT count(*) FROM users WHERE email = $1`, "cleaner@example.com")
+}
+
+func TestRepositorySchedule(t *testing.T) {
+	conn := testdb.New(t)
+	ctx := context.Background()
+	tx := newTestTx(t, conn)
+
+	userID := testdb.CreateUser(t, conn, "owner@example.com", internal.ROLE_OWNER)
+	otherUserID := testdb.CreateUser(t, conn, "other@example.com", internal.ROLE_OWNER)
+
+	week := time.Date(2025, 8, 11, 0, 0, 0, 0, time.UTC)
+
+	created, err := data.CreateSchedule(ctx, tx, db.GetDateFromTimeStruct(week), time.Monday, time.UTC, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotEqual(t, created.ID, 0)
+	assert.Equal(t, created.UserID, userID)
+	assert.Equal(t, created.StartDay.Time.Equal(week), true)
+
+	found, err := data.FindOneSchedule(ctx, tx, userID, db.Where(data.ScheduleColumns.ID.Eq(created.ID)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, found.ID, created.ID)
+	assert.Equal(t, found.UserID, userID)
+
+	// schedules of other users are never found
+	found, err = data.FindOneSchedule(ctx, tx, otherUserID, db.Where(data.ScheduleColumns.ID.Eq(created.ID)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, found, nil)
+
+	// a day later in the same week belongs to the same schedule
+	_, err = data.CreateSchedule(ctx, tx, db.GetDateFromTimeStruct(week.AddDate(0, 0, 2)), time.Monday, time.UTC, userID)
+	assert.Equal(t, errors.Is(err, data.ErrScheduleExists), true)
+
+	nextWeek := week.AddDate(0, 0, 7)
+	edited, err := data.EditSchedule(ctx, tx, userID, db.GetDateFromTimeStruct(nextWeek.AddDate(0, 0, 4)), time.Monday, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, edited.ID, created.ID)
+	assert.Equal(t, edited.UserID, userID)
+	assert.Equal(t, edited.StartDay.Time.Equal(nextWeek), true)
+
+	schedules, err := data.QuerySchedules(ctx, tx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(schedules), 1)
+	assert.Equal(t, schedules[0].ID, created.ID)
+
+	assertRolledBack(t, tx, conn, `SELECT count(*) FROM schedules`)
+}
+
+func TestRepositoryScheduledCustomer(t *testing.T) {
+	conn := testdb.New(t)
+	ctx := context.Background()
+	tx := newTestTx(t, conn)
+
+	userID := testdb.CreateUser(t, conn, "owner@example.com", internal.ROLE_OWNER)
+
+	week := time.Date(2025, 8, 11, 0, 0, 0, 0, time.UTC)
+	schedule, err := data.CreateSchedule(ctx, tx, db.GetDateFromTimeStruct(week), time.Monday, time.UTC, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	startTime := week.Add(9 * time.Hour)
+	endTime := week.Add(11 * time.Hour)
+
+	created, err := data.CreateScheduledCustomer(
+		ctx,
+		tx,
+		userID,
+		"QnVzaW5lc3M6Y3VzdG9tZXI=",
+		db.GetTimestamptzFromTimeStruct(startTime),
+		db.GetTimestamptzFromTimeStruct(endTime),
+		0,
+		schedule.ID,
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotEqual(t, created.ID, 0)
+	assert.Equal(t, created.ScheduleID, schedule.ID)
+	assert.Equal(t, created.CustomerID, "QnVzaW5lc3M6Y3VzdG9tZXI=")
+	assert.Equal(t, created.StartTime.Time.Equal(startTime), true)
+	assert.Equal(t, created.EndTime.Time.Equal(endTime), true)
+
+	found, err := data.FindOneScheduledCustomer(ctx, tx, db.Where(data.ScheduledCustomerColumns.ID.Eq(created.ID)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, found.ID, created.ID)
+	assert.Equal(t, found.ScheduleID, schedule.ID)
+	assert.Equal(t, found.CustomerID, created.CustomerID)
+	assert.Equal(t, found.DayOffset, 0)
+
+	newEndTime := endTime.Add(time.Hour)
+	edited, err := data.EditScheduledCustomer(
+		ctx,
+		tx,
+		userID,
+		created.ID,
+		schedule.ID,
+		0,
+		"QnVzaW5lc3M6b3RoZXI=",
+		db.GetTimestamptzFromTimeStruct(startTime),
+		db.GetTimestamptzFromTimeStruct(newEndTime),
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, edited.ID, created.ID)
+	assert.Equal(t, edited.ScheduleID, schedule.ID)
+	assert.Equal(t, edited.CustomerID, "QnVzaW5lc3M6b3RoZXI=")
+	assert.Equal(t, edited.EndTime.Time.Equal(newEndTime), true)
+
+	scheduledCustomers, err := data.QueryScheduledCustomers(ctx, tx, userID, schedule.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(scheduledCustomers), 1)
+	assert.Equal(t, scheduledCustomers[0].ID, created.ID)
+	assert.Equal(t, scheduledCustomers[0].CustomerID, edited.CustomerID)
+
+	assertRolledBack(t, tx, conn, `SELECT count(*) FROM scheduled_customers`)
+}
+
+func TestRepositoryRecurringService(t *testing.T) {
+	conn := testdb.New(t)
+	ctx := context.Background()
+	tx := newTestTx(t, conn)
+
+	userID := testdb.CreateUser(t, conn, "owner@example.com", internal.ROLE_OWNER)
+	otherUserID := testdb.CreateUser(t, conn, "other@example.com", internal.ROLE_OWNER)
+
+	week := time.Date(2025, 8, 11, 0, 0, 0, 0, time.UTC)
+
+	service := data.RecurringService{
+		CustomerID: "QnVzaW5lc3M6Y3VzdG9tZXI=",
+		Weekday:    int(time.Tuesday),
+		StartTime:  data.NewTimeOfDay(9, 0),
+		EndTime:    data.NewTimeOfDay(11, 0),
+		Frequency:  data.FREQUENCY_WEEKLY,
+		StartDate:  db.GetDateFromTimeStruct(week),
+		SkipDates:  []pgtype.Date{db.GetDateFromTimeStruct(week.AddDate(0, 0, 8))},
+	}
+
+	created, err := data.CreateRecurringService(ctx, tx, userID, service)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotEqual(t, created.ID, 0)
+	assert.Equal(t, created.UserID, userID)
+	assert.Equal(t, created.StartTime, service.StartTime)
+	assert.Equal(t, len(created.SkipDates), 1)
+
+	found, err := data.FindOneRecurringService(ctx, tx, userID, db.Where(data.RecurringServiceColumns.ID.Eq(created.ID)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, found.CustomerID, service.CustomerID)
+	assert.Equal(t, found.EndTime, service.EndTime)
+	assert.Equal(t, found.StartDate.Time.Equal(week), true)
+	assert.Equal(t, found.EndDate.Valid, false)
+	assert.Equal(t, len(found.SkipDates), 1)
+	assert.Equal(t, found.SkipDates[0].Time.Equal(week.AddDate(0, 0, 8)), true)
+
+	// recurring services of other users are never found
+	found, err = data.FindOneRecurringService(ctx, tx, otherUserID, db.Where(data.RecurringServiceColumns.ID.Eq(created.ID)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, found, nil)
+
+	// the first week is populated when the schedule is created
+	schedule, err := data.CreateSchedule(ctx, tx, db.GetDateFromTimeStruct(week), time.Monday, time.UTC, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scheduledCustomers, err := data.QueryScheduledCustomers(ctx, tx, userID, schedule.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(scheduledCustomers), 1)
+	assert.Equal(t, scheduledCustomers[0].DayOffset, 1)
+	assert.Equal(t, scheduledCustomers[0].CustomerID, service.CustomerID)
+	assert.Equal(t, scheduledCustomers[0].StartTime.Time.Equal(week.Add(33*time.Hour)), true)
+	assert.Equal(t, scheduledCustomers[0].RecurringServiceID.Int32, int32(created.ID))
+
+	// populating again adds nothing
+	added, err := data.PopulateSchedule(ctx, tx, userID, schedule, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(added), 0)
+
+	// the second week falls on the skip date
+	nextWeek, err := data.CreateSchedule(ctx, tx, db.GetDateFromTimeStruct(week.AddDate(0, 0, 7)), time.Monday, time.UTC, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scheduledCustomers, err = data.QueryScheduledCustomers(ctx, tx, userID, nextWeek.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(scheduledCustomers), 0)
+
+	thirdWeek := week.AddDate(0, 0, 14)
+	schedule, err = data.CreateSchedule(ctx, tx, db.GetDateFromTimeStruct(thirdWeek), time.Monday, time.UTC, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = data.DeleteRecurringService(ctx, tx, otherUserID, created.ID)
+	assert.Equal(t, err, data.ErrRecordNotFound)
+
+	_, err = data.DeleteRecurringService(ctx, tx, userID, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// generated entries outlive their recurring service
+	scheduledCustomers, err = data.QueryScheduledCustomers(ctx, tx, userID, schedule.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(scheduledCustomers), 1)
+	assert.Equal(t, scheduledCustomers[0].RecurringServiceID.Valid, false)
+
+	assertRolledBack(t, tx, conn, `SELECT count(*) FROM scheduled_customers`)
+}
+
+func TestRepositoryEmployeeAndCrew(t *testing.T) {
+	conn := testdb.New(t)
+	ctx := context.Background()
+	tx := newTestTx(t, conn)
+
+	userID := testdb.CreateUser(t, conn, "owner@example.com", internal.ROLE_OWNER)
+	otherUserID := testdb.CreateUser(t, conn, "other@example.com", internal.ROLE_OWNER)
+
+	employee, err := data.CreateEmployee(ctx, tx, userID, data.Employee{
+		Name:   "Marie Curie",
+		Email:  pgtype.Text{String: "marie@example.com", Valid: true},
+		Active: true,
+		Availability: []data.EmployeeAvailability{
+			{Weekday: int(time.Monday), StartTime: data.NewTimeOfDay(8, 0), EndTime: data.NewTimeOfDay(16, 0)},
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotEqual(t, employee.ID, 0)
+
+	found, err := data.FindOneEmployee(ctx, tx, userID, db.Where(data.EmployeeColumns.ID.Eq(employee.ID)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, found.Name, "Marie Curie")
+	assert.Equal(t, found.Email.String, "marie@example.com")
+	assert.Equal(t, found.Phone.Valid, false)
+	assert.Equal(t, found.Active, true)
+	assert.Equal(t, len(found.Availability), 1)
+	assert.Equal(t, found.Availability[0].EndTime, data.NewTimeOfDay(16, 0))
+
+	// employees of other users are never found, nor can they join a crew
+	found, err = data.FindOneEmployee(ctx, tx, otherUserID, db.Where(data.EmployeeColumns.ID.Eq(employee.ID)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, found, nil)
+
+	_, err = data.CreateCrew(ctx, tx, otherUserID, "Night shift", []int{employee.ID})
+	assert.Equal(t, errors.Is(err, data.ErrRecordNotFound), true)
+
+	crew, err := data.CreateCrew(ctx, tx, userID, "Day shift", []int{employee.ID, employee.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(crew.MemberIDs), 1)
+
+	_, err = data.CreateCrew(ctx, tx, userID, "Day shift", nil)
+	assert.NotEqual(t, err, nil)
+
+	crews, err := data.QueryCrews(ctx, tx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(crews), 1)
+	assert.Equal(t, crews[0].MemberIDs[0], employee.ID)
+
+	edited, err := data.EditCrew(ctx, tx, userID, crew.ID, "Morning shift", []int{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, edited.Name, "Morning shift")
+	assert.Equal(t, len(edited.MemberIDs), 0)
+
+	_, err = data.DeleteEmployee(ctx, tx, otherUserID, employee.ID)
+	assert.Equal(t, err, data.ErrRecordNotFound)
+
+	_, err = data.DeleteEmployee(ctx, tx, userID, employee.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertRolledBack(t, tx, conn, `SELECT count(*) FROM crews`)
+}
+
+func TestRepositoryLazyTxRollback(t *testing.T) {
+	conn := testdb.New(t)
+	ctx := context.Background()
+	tx := newTestTx(t, conn)
+
+	userID := testdb.CreateUser(t, conn, "owner@example.com", internal.ROLE_OWNER)
+
+	// the schedule is inserted with RETURNING, i.e. through GetContext
+	week := time.Date(2025, 8, 11, 0, 0, 0, 0, time.UTC)
+	created, err := data.CreateSchedule(ctx, tx, db.GetDateFromTimeStruct(week), time.Monday, time.UTC, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := data.FindOneSchedule(ctx, conn, userID, db.Where(data.ScheduleColumns.ID.Eq(created.ID)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// not visible outside the transaction before it commits
+	assert.Equal(t, found, nil)
+
+	assertRolledBack(t, tx, conn, `SELECT count(*) FROM schedules WHERE id = $1`, created.ID)
+}
+
+func TestRepositoryEditUserRoleConcurrentDemotion(t *testing.T) {
+	conn := testdb.New(t)
+	ctx := context.Background()
+
+	firstID := testdb.CreateUser(t, conn, "first@example.com", internal.ROLE_OWNER)
+	secondID := testdb.CreateUser(t, conn, "second@example.com", internal.ROLE_OWNER)
+
+	first := newTestTx(t, conn)
+	_, err := data.EditUserRole(ctx, first, firstID, internal.ROLE_OFFICE_MANAGER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the second demotion waits for the owners locked by the first one
+	second := newTestTx(t, conn)
+	done := make(chan error, 1)
+	go func() {
+		_, err := data.EditUserRole(ctx, second, secondID, internal.ROLE_OFFICE_MANAGER)
+		done <- err
+	}()
+
+	waitForLock(t, conn)
+
+	err = first.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = <-done
+	assert.Equal(t, errors.Is(err, data.ErrLastOwner), true)
+
+	var owners int
+	err = conn.Get(&owners, `SELECT count(*) FROM users WHERE role = $1`, internal.ROLE_OWNER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, owners, 1)
+}
+
+// Waits until a query of another connection is blocked by a row lock.
+func waitForLock(t *testing.T, conn *sqlx.DB) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		var waiting int
+		err := conn.Get(&waiting, `
+			SELECT count(*)
+			  FROM pg_stat_activity
+			 WHERE datname = current_database()
+			   AND wait_event_type = 'Lock'
+			   AND query LIKE '%FOR UPDATE%'
+		`)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if waiting > 0 {
+			return
+		}
+	}
+
+	t.Fatal("no query is waiting for a lock")
+}
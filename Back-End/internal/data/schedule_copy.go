@@ -0,0 +1,226 @@
+package data
+
+import (
+	"context"
+	"prime-shine-api/internal/db"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pkg/errors"
+)
+
+// What to do when a copied visit meets one the target schedule has already, i.e. the same customer on the same day.
+const (
+	COPY_CONFLICT_SKIP      = "skip"      // keep the existing visit
+	COPY_CONFLICT_OVERWRITE = "overwrite" // move the existing visit to the copied times
+	COPY_CONFLICT_FAIL      = "fail"      // copy nothing
+)
+
+// What copying a visit does, or would do in a dry run.
+const (
+	COPY_ACTION_CREATE    = "create"
+	COPY_ACTION_SKIP      = "skip"
+	COPY_ACTION_OVERWRITE = "overwrite"
+	COPY_ACTION_FAIL      = "fail"
+)
+
+// Returned when a copy in COPY_CONFLICT_FAIL mode meets visits in the target schedule.
+var ErrScheduleCopyConflict = errors.New("The target schedule has some of the same visits already.")
+
+// One visit of the source schedule and what copying it does.
+type ScheduleCopyEntry struct {
+	Action string             `json:"action"`
+	Source *ScheduledCustomer `json:"source"`
+
+	// The visit in the target schedule. It is the conflicting visit for skipped ones,
+	// and has no ID in a dry run unless it exists already.
+	Target *ScheduledCustomer `json:"target"`
+
+	// The visit the target schedule had before it was overwritten or skipped.
+	Conflict *ScheduledCustomer `json:"conflict"`
+}
+
+type ScheduleCopy struct {
+	Schedule        *Schedule            `json:"schedule"` // has no ID in a dry run that would create it
+	CreatedSchedule bool                 `json:"createdSchedule"`
+	Entries         []*ScheduleCopyEntry `json:"entries"`
+}
+
+// Copies the visits of a user's schedule into the schedule of another week, which is created if the user has none.
+// The target schedule starts on the first day of the week that targetStartDay is in.
+// Visits keep their day and wall clock times in location, so a 9:00 visit stays at 9:00 across a DST change.
+// Recurring services are not added to a schedule created this way; the copied visits include them already.
+// In a dry run nothing is written, and conflicts are reported even in COPY_CONFLICT_FAIL mode.
+// Overwriting a visit to a time its crews or employees are busy at returns a *DoubleBookingError.
+func CopySchedule(
+	ctx context.Context,
+	tx db.WriteDBExecutor,
+	userID int,
+	scheduleID int,
+	targetStartDay pgtype.Date,
+	weekStart time.Weekday,
+	location *time.Location,
+	conflictMode string,
+	dryRun bool,
+) (*ScheduleCopy, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "CopySchedule")
+	defer cancel()
+
+	if !slices.Contains([]string{COPY_CONFLICT_SKIP, COPY_CONFLICT_OVERWRITE, COPY_CONFLICT_FAIL}, conflictMode) {
+		return nil, errors.Errorf("Conflict mode must be %v, %v or %v.", COPY_CONFLICT_SKIP, COPY_CONFLICT_OVERWRITE, COPY_CONFLICT_FAIL)
+	}
+
+	targetStartDay, err := NormalizeStartDay(targetStartDay, weekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	query := db.Where(ScheduleColumns.ID.Eq(scheduleID))
+	source, err := FindOneSchedule(ctx, tx, userID, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "FindOneSchedule")
+	}
+
+	if source == nil {
+		return nil, ErrRecordNotFound
+	}
+
+	days := int(calendarDay(targetStartDay.Time).Sub(calendarDay(source.StartDay.Time)).Hours() / 24)
+	if days == 0 {
+		return nil, errors.New("A schedule can not be copied onto itself.")
+	}
+
+	sourceEntries, err := QueryScheduledCustomers(ctx, tx, userID, source.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "QueryScheduledCustomers")
+	}
+
+	target, err := findScheduleInWeek(ctx, tx, userID, targetStartDay, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "findScheduleInWeek")
+	}
+
+	// a schedule from before weeks were aligned covers part of the week; copying next to it would duplicate days
+	if target != nil && !calendarDay(target.StartDay.Time).Equal(calendarDay(targetStartDay.Time)) {
+		return nil, ErrScheduleExists
+	}
+
+	result := &ScheduleCopy{Schedule: target, Entries: []*ScheduleCopyEntry{}}
+
+	existing := []*ScheduledCustomer{}
+	if target == nil {
+		result.Schedule = &Schedule{UserID: userID, StartDay: targetStartDay}
+		result.CreatedSchedule = true
+	} else {
+		existing, err = QueryScheduledCustomers(ctx, tx, userID, target.ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "QueryScheduledCustomers")
+		}
+	}
+
+	conflicts := 0
+	for _, sourceEntry := range sourceEntries {
+		entry := &ScheduleCopyEntry{
+			Action: COPY_ACTION_CREATE,
+			Source: sourceEntry,
+			Target: &ScheduledCustomer{
+				CustomerID:         sourceEntry.CustomerID,
+				StartTime:          shiftTimestamptz(sourceEntry.StartTime, days, location),
+				EndTime:            shiftTimestamptz(sourceEntry.EndTime, days, location),
+				DayOffset:          sourceEntry.DayOffset,
+				ScheduleID:         result.Schedule.ID,
+				RecurringServiceID: sourceEntry.RecurringServiceID,
+			},
+		}
+
+		// every visit of the target schedule conflicts with one copied visit at most
+		i := slices.IndexFunc(existing, func(scheduledCustomer *ScheduledCustomer) bool {
+			return scheduledCustomer.DayOffset == sourceEntry.DayOffset && scheduledCustomer.CustomerID == sourceEntry.CustomerID
+		})
+
+		if i >= 0 {
+			conflicts++
+			entry.Conflict = existing[i]
+			existing = slices.Delete(existing, i, i+1)
+
+			switch conflictMode {
+			case COPY_CONFLICT_SKIP:
+				entry.Action = COPY_ACTION_SKIP
+				entry.Target = entry.Conflict
+			case COPY_CONFLICT_OVERWRITE:
+				entry.Action = COPY_ACTION_OVERWRITE
+				entry.Target.ID = entry.Conflict.ID
+			case COPY_CONFLICT_FAIL:
+				entry.Action = COPY_ACTION_FAIL
+			}
+		}
+
+		result.Entries = append(result.Entries, entry)
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	if conflicts > 0 && conflictMode == COPY_CONFLICT_FAIL {
+		return nil, errors.Wrapf(ErrScheduleCopyConflict, "%v conflicting visits", conflicts)
+	}
+
+	if result.CreatedSchedule {
+		result.Schedule, err = insertSchedule(ctx, tx, targetStartDay, userID)
+		if err != nil {
+			return nil, errors.Wrap(err, "insertSchedule")
+		}
+	}
+
+	for _, entry := range result.Entries {
+		switch entry.Action {
+		case COPY_ACTION_CREATE:
+			err = tx.GetContext(ctx, entry.Target, `
+				INSERT INTO scheduled_customers
+				(wave_customerid, start_time, end_time, day_offset, scheduleid, recurringserviceid)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				RETURNING `+scheduledCustomersTable.Columns()+`
+			`, entry.Target.CustomerID, entry.Target.StartTime, entry.Target.EndTime,
+				entry.Target.DayOffset, result.Schedule.ID, entry.Target.RecurringServiceID)
+		case COPY_ACTION_OVERWRITE:
+			err = tx.GetContext(ctx, entry.Target, `
+				UPDATE scheduled_customers
+				SET   start_time         = $1
+				    , end_time           = $2
+				    , recurringserviceid = $3
+				WHERE scheduledcustomerid = $4
+				RETURNING `+scheduledCustomersTable.Columns()+`
+			`, entry.Target.StartTime, entry.Target.EndTime, entry.Target.RecurringServiceID, entry.Target.ID)
+		}
+
+		if isConstraintViolation(err, PG_EXCLUSION_VIOLATION) {
+			return nil, &DoubleBookingError{}
+		} else if err != nil {
+			return nil, errors.Wrap(err, "tx.Get")
+		}
+
+		// overwritten visits keep their crews and employees, who have to be free at the new time
+		if entry.Action == COPY_ACTION_OVERWRITE {
+			err = recheckDoubleBooking(ctx, tx, entry.Target)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Moves a point in time by a number of days, keeping its wall clock time in location.
+func shiftTimestamptz(timestamp pgtype.Timestamptz, days int, location *time.Location) pgtype.Timestamptz {
+	if !timestamp.Valid {
+		return timestamp
+	}
+
+	t := timestamp.Time.In(location)
+	shifted := time.Date(t.Year(), t.Month(), t.Day()+days, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), location)
+
+	return db.GetTimestamptzFromTimeStruct(shifted)
+}
@@ -0,0 +1,50 @@
+package data
+
+import (
+	"prime-shine-api/internal/assert"
+	"prime-shine-api/internal/db"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestShiftTimestamptz(t *testing.T) {
+	toronto, err := time.LoadLocation("America/Toronto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 9:00 in Toronto, which is UTC-5 in winter and UTC-4 in summer
+	winter := func(month time.Month, day int) time.Time {
+		return time.Date(2025, month, day, 14, 0, 0, 0, time.UTC)
+	}
+
+	summer := func(month time.Month, day int) time.Time {
+		return time.Date(2025, month, day, 13, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		from time.Time
+		days int
+		want time.Time
+	}{
+		{"into daylight time", winter(time.March, 3), 7, summer(time.March, 10)},
+		{"back out of daylight time", summer(time.March, 10), -7, winter(time.March, 3)},
+		{"out of daylight time", summer(time.October, 27), 7, winter(time.November, 3)},
+		{"back into daylight time", winter(time.November, 3), -7, summer(time.October, 27)},
+		{"no change", summer(time.August, 11), 7, summer(time.August, 18)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shifted := shiftTimestamptz(db.GetTimestamptzFromTimeStruct(tt.from), tt.days, toronto)
+
+			assert.Equal(t, shifted.Valid, true)
+			assert.Equal(t, shifted.Time.UTC(), tt.want)
+		})
+	}
+
+	assert.Equal(t, shiftTimestamptz(pgtype.Timestamptz{}, 7, toronto).Valid, false)
+}
@@ -0,0 +1,123 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"prime-shine-api/internal/db"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pkg/errors"
+)
+
+// Kinds of warnings about a schedule.
+const (
+	WARNING_OVERLAP          = "overlap"          // visits at the same time, of which at least one has nobody to clean it yet
+	WARNING_CUSTOMER_OVERLAP = "customer_overlap" // the same customer twice at the same time
+)
+
+// Something about a schedule that is allowed but likely a mistake, for the front-end to point out.
+type ScheduleWarning struct {
+	Code                 string `json:"code"`
+	Message              string `json:"message"`
+	ScheduledCustomerIDs []int  `json:"scheduledCustomerIDs"` // the visits involved
+}
+
+// Checks the time window and day of a visit.
+func ValidateScheduledCustomer(startTime pgtype.Timestamptz, endTime pgtype.Timestamptz, dayOffset int) error {
+	if !startTime.Valid || !endTime.Valid {
+		return errors.New("A scheduled customer needs a start and an end time.")
+	}
+
+	if !endTime.Time.After(startTime.Time) {
+		return errors.New("A scheduled customer has to end after it starts.")
+	}
+
+	if dayOffset < 0 || dayOffset >= SCHEDULE_LENGTH_DAYS {
+		return errors.Errorf("Day offset must be between 0 and %v.", SCHEDULE_LENGTH_DAYS-1)
+	}
+
+	return nil
+}
+
+// Finds the visits of a schedule that overlap on the same day.
+// Overlapping visits that both have someone assigned are fine, since a crew or an employee can not be double-booked.
+// Assignments may be nil, in which case no visit counts as having someone assigned.
+func OverlapWarnings(visits []*ScheduledCustomer, assignments []*ScheduledCustomerAssignment) []*ScheduleWarning {
+	staffed := map[int]bool{}
+	for _, assignment := range assignments {
+		staffed[assignment.ScheduledCustomerID] = len(assignment.CrewIDs) > 0 || len(assignment.EmployeeIDs) > 0
+	}
+
+	warnings := []*ScheduleWarning{}
+
+	for i, visit := range visits {
+		for _, other := range visits[i+1:] {
+			if visit.DayOffset != other.DayOffset || !overlaps(visit, other) {
+				continue
+			}
+
+			ids := []int{visit.ID, other.ID}
+
+			if visit.CustomerID == other.CustomerID {
+				warnings = append(warnings, &ScheduleWarning{
+					Code:                 WARNING_CUSTOMER_OVERLAP,
+					Message:              "The same customer is scheduled twice at the same time.",
+					ScheduledCustomerIDs: ids,
+				})
+			} else if !staffed[visit.ID] || !staffed[other.ID] {
+				warnings = append(warnings, &ScheduleWarning{
+					Code:                 WARNING_OVERLAP,
+					Message:              fmt.Sprintf("These visits overlap on day %v; make sure different people clean them.", visit.DayOffset),
+					ScheduledCustomerIDs: ids,
+				})
+			}
+		}
+	}
+
+	return warnings
+}
+
+func overlaps(a *ScheduledCustomer, b *ScheduledCustomer) bool {
+	return a.StartTime.Time.Before(b.EndTime.Time) && b.StartTime.Time.Before(a.EndTime.Time)
+}
+
+// Gets the warnings about a user's schedule.
+func QueryScheduleWarnings(ctx context.Context, readConn db.ReadDBExecutor, userID int, scheduleID int) ([]*ScheduleWarning, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "QueryScheduleWarnings")
+	defer cancel()
+
+	visits, err := QueryScheduledCustomers(ctx, readConn, userID, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(visits))
+	for _, visit := range visits {
+		ids = append(ids, visit.ID)
+	}
+
+	assignments, err := loadAssignments(ctx, readConn, ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "loadAssignments")
+	}
+
+	return OverlapWarnings(visits, assignments), nil
+}
+
+// Gets the warnings about a user's schedule that involve one of its visits, e.g. after it was created.
+func QueryScheduledCustomerWarnings(ctx context.Context, readConn db.ReadDBExecutor, userID int, scheduledCustomer *ScheduledCustomer) ([]*ScheduleWarning, error) {
+	warnings, err := QueryScheduleWarnings(ctx, readConn, userID, scheduledCustomer.ScheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	involved := []*ScheduleWarning{}
+	for _, warning := range warnings {
+		if slices.Contains(warning.ScheduledCustomerIDs, scheduledCustomer.ID) {
+			involved = append(involved, warning)
+		}
+	}
+
+	return involved, nil
+}
@@ -1,10 +1,9 @@
 package data
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
 	"prime-shine-api/internal/db"
-	"strings"
 
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/pkg/errors"
@@ -17,35 +16,42 @@ type ScheduledCustomer struct {
 	EndTime    pgtype.Timestamptz `db:"end_time" json:"endTime"`
 	DayOffset  int                `db:"day_offset" json:"dayOffset"`
 	ScheduleID int                `db:"scheduleid" json:"-"`
+
+	// The recurring service the entry was generated from; NULL for entries made by hand.
+	RecurringServiceID pgtype.Int4 `db:"recurringserviceid" json:"recurringServiceID"`
+}
+
+var scheduledCustomersTable = db.NewTable[ScheduledCustomer]("scheduled_customers")
+
+// Columns that scheduled customers can be looked up by.
+var ScheduledCustomerColumns = struct {
+	ID         db.Column[ScheduledCustomer]
+	CustomerID db.Column[ScheduledCustomer]
+	StartTime  db.Column[ScheduledCustomer]
+	EndTime    db.Column[ScheduledCustomer]
+	DayOffset  db.Column[ScheduledCustomer]
+	ScheduleID db.Column[ScheduledCustomer]
+}{
+	ID:         scheduledCustomersTable.Column("scheduledcustomerid"),
+	CustomerID: scheduledCustomersTable.Column("wave_customerid"),
+	StartTime:  scheduledCustomersTable.Column("start_time"),
+	EndTime:    scheduledCustomersTable.Column("end_time"),
+	DayOffset:  scheduledCustomersTable.Column("day_offset"),
+	ScheduleID: scheduledCustomersTable.Column("scheduleid"),
 }
 
 // Finds one scheduled customer.
 // If runtime errors occur, an error is returned.
 // Otherwise, a scheduled customer and nil error is returned.
-func FindOneScheduledCustomer(readConn db.ReadDBExecutor, filter map[string]any) (*ScheduledCustomer, error) {
-	scheduledCustomer := &ScheduledCustomer{}
-
-	var args []any
-	var whereClauses []string
+func FindOneScheduledCustomer(ctx context.Context, readConn db.ReadDBExecutor, query db.Query[ScheduledCustomer]) (*ScheduledCustomer, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "FindOneScheduledCustomer")
+	defer cancel()
 
-	for k, v := range filter {
-		argNum := len(args) + 1
-		whereClause := fmt.Sprintf("%v = $%v", k, argNum)
-
-		whereClauses = append(whereClauses, whereClause)
-		args = append(args, v)
-	}
+	scheduledCustomer := &ScheduledCustomer{}
 
-	query := fmt.Sprintf(`
-		SELECT   wave_customerid
-		       , start_time
-			   , end_time
-			   , day_offset
-		  FROM scheduled_customers
-		 WHERE %v
-	`, strings.Join(whereClauses, " AND "))
+	statement, args := scheduledCustomersTable.Select(query.Limit(1))
 
-	err := readConn.Get(scheduledCustomer, query, args...)
+	err := readConn.GetContext(ctx, scheduledCustomer, statement, args...)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	} else if err != nil {
@@ -55,15 +61,39 @@ func FindOneScheduledCustomer(readConn db.ReadDBExecutor, filter map[string]any)
 	return scheduledCustomer, nil
 }
 
-// Grabs scheduled customers that are included in a schedule.
-func QueryScheduledCustomers(readConn db.ReadDBExecutor, scheduleID int) ([]*ScheduledCustomer, error) {
+// Ensures that a schedule exists and belongs to a user.
+func findUserSchedule(ctx context.Context, readConn db.ReadDBExecutor, userID int, scheduleID int) error {
+	query := db.Where(ScheduleColumns.ID.Eq(scheduleID))
+	schedule, err := FindOneSchedule(ctx, readConn, userID, query)
+	if err != nil {
+		return errors.Wrap(err, "FindOneSchedule")
+	}
+
+	if schedule == nil {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Grabs scheduled customers that are included in a user's schedule.
+func QueryScheduledCustomers(ctx context.Context, readConn db.ReadDBExecutor, userID int, scheduleID int) ([]*ScheduledCustomer, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "QueryScheduledCustomers")
+	defer cancel()
+
+	err := findUserSchedule(ctx, readConn, userID, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+
 	entries := []*ScheduledCustomer{}
-	query := `
-		SELECT *
-		  FROM scheduled_customers
-		 WHERE scheduleid = $1
-	`
-	err := readConn.Select(&entries, query, scheduleID)
+
+	query := db.Where(ScheduledCustomerColumns.ScheduleID.Eq(scheduleID)).
+		OrderBy(ScheduledCustomerColumns.DayOffset).
+		OrderBy(ScheduledCustomerColumns.StartTime)
+	statement, args := scheduledCustomersTable.Select(query)
+
+	err = readConn.SelectContext(ctx, &entries, statement, args...)
 	if err != nil {
 		return nil, errors.Wrap(err, "Select")
 	}
@@ -71,24 +101,39 @@ func QueryScheduledCustomers(readConn db.ReadDBExecutor, scheduleID int) ([]*Sch
 	return entries, nil
 }
 
-// Creates a scheduled customer.
+// Creates a scheduled customer in a user's schedule.
 func CreateScheduledCustomer(
+	ctx context.Context,
 	tx db.WriteDBExecutor,
+	userID int,
 	newCustomerID string,
 	newServiceStartTime pgtype.Timestamptz,
 	newServiceEndTime pgtype.Timestamptz,
 	dayOffset int,
 	scheduleID int,
 ) (*ScheduledCustomer, error) {
-	filter := map[string]any{
-		"wave_customerid": newCustomerID,
-		"start_time":      newServiceStartTime,
-		"end_time":        newServiceEndTime,
-		"day_offset":      dayOffset,
-		"scheduleid":      scheduleID,
+	ctx, cancel := db.WithQueryTimeout(ctx, "CreateScheduledCustomer")
+	defer cancel()
+
+	err := ValidateScheduledCustomer(newServiceStartTime, newServiceEndTime, dayOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	err = findUserSchedule(ctx, tx, userID, scheduleID)
+	if err != nil {
+		return nil, err
 	}
 
-	foundScheduledCustomer, err := FindOneScheduledCustomer(tx, filter)
+	query := db.Where(
+		ScheduledCustomerColumns.CustomerID.Eq(newCustomerID),
+		ScheduledCustomerColumns.StartTime.Eq(newServiceStartTime),
+		ScheduledCustomerColumns.EndTime.Eq(newServiceEndTime),
+		ScheduledCustomerColumns.DayOffset.Eq(dayOffset),
+		ScheduledCustomerColumns.ScheduleID.Eq(scheduleID),
+	)
+
+	foundScheduledCustomer, err := FindOneScheduledCustomer(ctx, tx, query)
 	if err != nil {
 		return nil, errors.Wrap(err, "FindOneScheduledCustomer")
 	}
@@ -97,37 +142,27 @@ func CreateScheduledCustomer(
 		return nil, errors.New("Scheduled Customer already exists.")
 	}
 
-	result, err := tx.Exec(`
+	newScheduledCustomer := &ScheduledCustomer{}
+	err = tx.GetContext(ctx, newScheduledCustomer, `
 		INSERT INTO scheduled_customers
 		(wave_customerid, start_time, end_time, day_offset, scheduleid)
 		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+scheduledCustomersTable.Columns()+`
 	`, newCustomerID, newServiceStartTime, newServiceEndTime, dayOffset, scheduleID)
 
 	if err != nil {
-		return nil, errors.Wrap(err, "tx.Exec")
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return nil, errors.Wrap(err, "RowsAffected")
-	}
-
-	if rowsAffected != 1 {
-		return nil, errors.New("failed to insert scheduled_customers entry")
-	}
-
-	// Grab the newly created scheduled customer
-	newScheduledCustomer, err := FindOneScheduledCustomer(tx, filter)
-	if err != nil {
-		return nil, errors.Wrap(err, "FindOneScheduledCustomer")
+		return nil, errors.Wrap(err, "tx.Get")
 	}
 
 	return newScheduledCustomer, nil
 }
 
-// Edits a scheduled customer.
+// Edits a scheduled customer in a user's schedule.
+// A *DoubleBookingError is returned if the crews or employees assigned to it are busy at the new time.
 func EditScheduledCustomer(
+	ctx context.Context,
 	tx db.WriteDBExecutor,
+	userID int,
 	scheduledCustomerID int,
 	scheduleID int,
 	dayOffset int,
@@ -135,30 +170,43 @@ func EditScheduledCustomer(
 	newServiceStartTime pgtype.Timestamptz,
 	newServiceEndTime pgtype.Timestamptz,
 ) (*ScheduledCustomer, error) {
-	filter := map[string]any{
-		"scheduledcustomerid": scheduledCustomerID,
-		"scheduleid":          scheduleID,
-		"day_offset":          dayOffset,
+	ctx, cancel := db.WithQueryTimeout(ctx, "EditScheduledCustomer")
+	defer cancel()
+
+	err := ValidateScheduledCustomer(newServiceStartTime, newServiceEndTime, dayOffset)
+	if err != nil {
+		return nil, err
 	}
 
-	scheduledCustomer, err := FindOneScheduledCustomer(tx, filter)
+	err = findUserSchedule(ctx, tx, userID, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := db.Where(
+		ScheduledCustomerColumns.ID.Eq(scheduledCustomerID),
+		ScheduledCustomerColumns.ScheduleID.Eq(scheduleID),
+		ScheduledCustomerColumns.DayOffset.Eq(dayOffset),
+	)
+
+	scheduledCustomer, err := FindOneScheduledCustomer(ctx, tx, query)
 	if err != nil {
 		return nil, errors.Wrap(err, "FindOneScheduledCustomer")
 	}
 
 	if scheduledCustomer == nil {
-		return nil, errors.New("Scheduled customer does not exist.")
+		return nil, ErrRecordNotFound
 	}
 
-	filter = map[string]any{
-		"wave_customerid": newCustomerID,
-		"start_time":      newServiceStartTime,
-		"end_time":        newServiceEndTime,
-		"day_offset":      dayOffset,
-		"scheduleid":      scheduleID,
-	}
+	query = db.Where(
+		ScheduledCustomerColumns.CustomerID.Eq(newCustomerID),
+		ScheduledCustomerColumns.StartTime.Eq(newServiceStartTime),
+		ScheduledCustomerColumns.EndTime.Eq(newServiceEndTime),
+		ScheduledCustomerColumns.DayOffset.Eq(dayOffset),
+		ScheduledCustomerColumns.ScheduleID.Eq(scheduleID),
+	)
 
-	foundScheduledCustomer, err := FindOneScheduledCustomer(tx, filter)
+	foundScheduledCustomer, err := FindOneScheduledCustomer(ctx, tx, query)
 	if err != nil {
 		return nil, errors.Wrap(err, "FindOneScheduledCustomer")
 	}
@@ -167,54 +215,59 @@ func EditScheduledCustomer(
 		return nil, errors.New("Scheduled customer exists.")
 	}
 
-	scheduledCustomer.CustomerID = newCustomerID
-	scheduledCustomer.StartTime = newServiceStartTime
-	scheduledCustomer.EndTime = newServiceEndTime
-
-	result, err := tx.Exec(`
+	editedScheduledCustomer := &ScheduledCustomer{}
+	err = tx.GetContext(ctx, editedScheduledCustomer, `
 		UPDATE scheduled_customers
-		SET   wave_customerid 	= $1
-		    , start_time   		= $2
-			, end_time 			= $3
+		SET   wave_customerid = $1
+		    , start_time      = $2
+		    , end_time        = $3
 		WHERE scheduledcustomerid = $4
-	`, scheduledCustomer.CustomerID, scheduledCustomer.StartTime, scheduledCustomer.EndTime, scheduledCustomerID)
+		RETURNING `+scheduledCustomersTable.Columns()+`
+	`, newCustomerID, newServiceStartTime, newServiceEndTime, scheduledCustomer.ID)
 
-	if err != nil {
-		return nil, errors.Wrap(err, "tx.Exec")
+	if err == sql.ErrNoRows {
+		return nil, errors.New("scheduled customer was not mutated")
+	} else if isConstraintViolation(err, PG_EXCLUSION_VIOLATION) {
+		// another request booked the same people in the meantime
+		return nil, &DoubleBookingError{}
+	} else if err != nil {
+		return nil, errors.Wrap(err, "tx.Get")
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	// the crews and employees of the visit have to be free at its new time as well
+	err = recheckDoubleBooking(ctx, tx, editedScheduledCustomer)
 	if err != nil {
-		return nil, errors.Wrap(err, "RowsAffected")
+		return nil, err
 	}
 
-	if rowsAffected == 0 {
-		return nil, errors.New("scheduled customer was not mutated")
-	}
-
-	return scheduledCustomer, nil
+	return editedScheduledCustomer, nil
 }
 
-// Deletes a scheduled customer.
-func DeleteScheduledCustomer(tx db.WriteDBExecutor, scheduledCustomerID int) (bool, error) {
-	filter := map[string]any{"scheduledcustomerid": scheduledCustomerID}
-	user, err := FindOneScheduledCustomer(tx, filter)
-	if err != nil {
-		return false, errors.Wrap(err, "FindOneScheduledCustomer")
-	}
-
-	if user == nil {
-		return false, errors.New("Scheduled customer not found.")
-	}
+// Deletes a scheduled customer from a user's schedule.
+func DeleteScheduledCustomer(ctx context.Context, tx db.WriteDBExecutor, userID int, scheduledCustomerID int) (bool, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "DeleteScheduledCustomer")
+	defer cancel()
 
-	_, err = tx.Exec(`
+	result, err := tx.ExecContext(ctx, `
 		DELETE FROM scheduled_customers
-		WHERE scheduledcustomerid = $1
-	`, scheduledCustomerID)
+		USING schedules
+		WHERE scheduled_customers.scheduleid = schedules.scheduleid
+		  AND scheduled_customers.scheduledcustomerid = $1
+		  AND schedules.userid = $2
+	`, scheduledCustomerID, userID)
 
 	if err != nil {
 		return false, errors.Wrap(err, "tx.Exec")
 	}
 
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "RowsAffected")
+	}
+
+	if rowsAffected == 0 {
+		return false, ErrRecordNotFound
+	}
+
 	return true, nil
 }
@@ -1,10 +1,11 @@
 package data
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
 	"prime-shine-api/internal/db"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/pkg/errors"
@@ -16,32 +17,97 @@ type Schedule struct {
 	StartDay pgtype.Date `db:"start_day" json:"startDay"`
 }
 
-// Finds one schedule.
-// If runtime errors occur, an error is returned.
-// Otherwise, a schedule and nil error is returned.
-func FindOneSchedule(readConn db.ReadDBExecutor, filter map[string]any) (*Schedule, error) {
-	schedule := &Schedule{}
+var schedulesTable = db.NewTable[Schedule]("schedules")
+
+// Columns that schedules can be looked up by.
+// Schedules are always looked up within a user's own schedules, so there is no column for the user.
+var ScheduleColumns = struct {
+	ID       db.Column[Schedule]
+	StartDay db.Column[Schedule]
+}{
+	ID:       schedulesTable.Column("scheduleid"),
+	StartDay: schedulesTable.Column("start_day"),
+}
 
-	var args []any
-	var whereClauses []string
+var scheduleUserID = schedulesTable.Column("userid")
 
-	for k, v := range filter {
-		argNum := len(args) + 1
-		whereClause := fmt.Sprintf("%v = $%v", k, argNum)
+// Returned when a user has a schedule for the week already.
+var ErrScheduleExists = errors.New("Schedule exists already.")
+
+// Parses the name of a weekday, e.g. "monday".
+func ParseWeekday(name string) (time.Weekday, error) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(name, weekday.String()) {
+			return weekday, nil
+		}
+	}
+
+	return 0, errors.Errorf("unknown weekday %q", name)
+}
 
-		whereClauses = append(whereClauses, whereClause)
-		args = append(args, v)
+// Moves a day back to the start of its week, e.g. a Wednesday to the Monday before it when weeks start on Monday.
+// Schedules always start on the first day of a week, so that a user has one schedule per week at most.
+func NormalizeStartDay(startDay pgtype.Date, weekStart time.Weekday) (pgtype.Date, error) {
+	if !startDay.Valid || startDay.Time.IsZero() {
+		return pgtype.Date{}, errors.New("A schedule needs a start day.")
 	}
 
-	query := fmt.Sprintf(`
-		SELECT   scheduleid
-			   , start_day
-			   , userid
+	day := calendarDay(startDay.Time)
+	days := (int(day.Weekday()) - int(weekStart) + 7) % 7
+
+	return db.GetDateFromTimeStruct(day.AddDate(0, 0, -days)), nil
+}
+
+// Gets the schedules of every user that do not start on weekStart, e.g. from before the week start was configured.
+// Nothing stops such a schedule and an aligned one from covering the same week.
+func QueryMisalignedSchedules(ctx context.Context, readConn db.ReadDBExecutor, weekStart time.Weekday) ([]*Schedule, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "QueryMisalignedSchedules")
+	defer cancel()
+
+	entries := []*Schedule{}
+	err := readConn.SelectContext(ctx, &entries, `
+		SELECT `+schedulesTable.Columns()+`
 		  FROM schedules
-		 WHERE %v
-	`, strings.Join(whereClauses, " AND "))
+		 WHERE extract(dow FROM start_day) <> $1
+		 ORDER BY userid, start_day
+	`, int(weekStart))
 
-	err := readConn.Get(schedule, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Select")
+	}
+
+	return entries, nil
+}
+
+// Finds a schedule of a user that shares a day with the week starting on startDay, other than exceptScheduleID.
+// Schedules from before weeks were aligned may start on any day, so looking for startDay alone could miss them.
+func findScheduleInWeek(ctx context.Context, readConn db.ReadDBExecutor, userID int, startDay pgtype.Date, exceptScheduleID int) (*Schedule, error) {
+	day := calendarDay(startDay.Time)
+
+	query := db.Where(
+		ScheduleColumns.StartDay.Range(
+			db.GetDateFromTimeStruct(day.AddDate(0, 0, -6)),
+			db.GetDateFromTimeStruct(day.AddDate(0, 0, 7)),
+		),
+		ScheduleColumns.ID.NotEq(exceptScheduleID),
+	).OrderBy(ScheduleColumns.StartDay)
+
+	return FindOneSchedule(ctx, readConn, userID, query)
+}
+
+// Finds one schedule that belongs to a user.
+// If runtime errors occur, an error is returned.
+// Otherwise, a schedule and nil error is returned.
+func FindOneSchedule(ctx context.Context, readConn db.ReadDBExecutor, userID int, query db.Query[Schedule]) (*Schedule, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "FindOneSchedule")
+	defer cancel()
+
+	schedule := &Schedule{}
+
+	query = query.And(scheduleUserID.Eq(userID)).Limit(1)
+	statement, args := schedulesTable.Select(query)
+
+	err := readConn.GetContext(ctx, schedule, statement, args...)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	} else if err != nil {
@@ -51,16 +117,17 @@ func FindOneSchedule(readConn db.ReadDBExecutor, filter map[string]any) (*Schedu
 	return schedule, nil
 }
 
-// Gets schedules for a user.
-func QuerySchedules(readConn db.ReadDBExecutor, userID int) ([]*Schedule, error) {
+// Gets schedules for a user, earliest first.
+func QuerySchedules(ctx context.Context, readConn db.ReadDBExecutor, userID int) ([]*Schedule, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "QuerySchedules")
+	defer cancel()
+
 	entries := []*Schedule{}
-	query := `
-		SELECT *
-		  FROM schedules
-		 WHERE userid = $1
-	`
 
-	err := readConn.Select(&entries, query, userID)
+	query := db.Where(scheduleUserID.Eq(userID)).OrderBy(ScheduleColumns.StartDay)
+	statement, args := schedulesTable.Select(query)
+
+	err := readConn.SelectContext(ctx, &entries, statement, args...)
 	if err != nil {
 		return nil, errors.Wrap(err, "Select")
 	}
@@ -68,77 +135,107 @@ func QuerySchedules(readConn db.ReadDBExecutor, userID int) ([]*Schedule, error)
 	return entries, nil
 }
 
-// Creates a schedule for a user.
-func CreateSchedule(tx db.WriteDBExecutor, startDay pgtype.Date, userID int) (*Schedule, error) {
-	filter := map[string]any{"start_day": startDay, "userid": userID}
-	schedule, err := FindOneSchedule(tx, filter)
+// Creates a schedule for a user, populated with the user's recurring services.
+// The schedule starts on the first day of the week that startDay is in.
+func CreateSchedule(
+	ctx context.Context,
+	tx db.WriteDBExecutor,
+	startDay pgtype.Date,
+	weekStart time.Weekday,
+	location *time.Location,
+	userID int,
+) (*Schedule, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "CreateSchedule")
+	defer cancel()
+
+	startDay, err := NormalizeStartDay(startDay, weekStart)
 	if err != nil {
-		return nil, errors.Wrap(err, "FindOneSchedule")
+		return nil, err
 	}
 
-	if schedule != nil {
-		return nil, errors.New("Schedule exists already.")
+	schedule, err := findScheduleInWeek(ctx, tx, userID, startDay, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "findScheduleInWeek")
 	}
 
-	result, err := tx.Exec(`
-		INSERT INTO schedules
-		(userid, start_day)
-		VALUES ($1, $2)
-	`, userID, startDay)
+	if schedule != nil {
+		return nil, ErrScheduleExists
+	}
 
+	newSchedule, err := insertSchedule(ctx, tx, startDay, userID)
 	if err != nil {
-		return nil, errors.Wrap(err, "tx.Exec")
+		return nil, errors.Wrap(err, "insertSchedule")
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	_, err = PopulateSchedule(ctx, tx, userID, newSchedule, location)
 	if err != nil {
-		return nil, errors.Wrap(err, "RowsAffected")
+		return nil, errors.Wrap(err, "PopulateSchedule")
 	}
 
-	if rowsAffected != 1 {
-		return nil, errors.New("failed to insert users entry")
-	}
+	return newSchedule, nil
+}
 
-	// Grab the newly created schedule
-	newSchedule, err := FindOneSchedule(tx, filter)
-	if err != nil {
-		return nil, errors.Wrap(err, "FindOneSchedule")
+// Inserts an empty schedule.
+func insertSchedule(ctx context.Context, tx db.WriteDBExecutor, startDay pgtype.Date, userID int) (*Schedule, error) {
+	newSchedule := &Schedule{}
+	err := tx.GetContext(ctx, newSchedule, `
+		INSERT INTO schedules
+		(userid, start_day)
+		VALUES ($1, $2)
+		RETURNING `+schedulesTable.Columns()+`
+	`, userID, startDay)
+
+	// another request created the schedule since it was looked up
+	if isConstraintViolation(err, PG_UNIQUE_VIOLATION) {
+		return nil, ErrScheduleExists
+	} else if err != nil {
+		return nil, errors.Wrap(err, "tx.Get")
 	}
 
 	return newSchedule, nil
 }
 
-// Edits a schedule.
-func EditSchedule(tx db.WriteDBExecutor, newStartDay pgtype.Date, scheduleID int) (*Schedule, error) {
-	filter := map[string]any{"scheduleid": scheduleID}
-	schedule, err := FindOneSchedule(tx, filter)
+// Edits a schedule that belongs to a user.
+// The schedule is moved to the first day of the week that newStartDay is in.
+func EditSchedule(ctx context.Context, tx db.WriteDBExecutor, userID int, newStartDay pgtype.Date, weekStart time.Weekday, scheduleID int) (*Schedule, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "EditSchedule")
+	defer cancel()
+
+	newStartDay, err := NormalizeStartDay(newStartDay, weekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	query := db.Where(ScheduleColumns.ID.Eq(scheduleID))
+	schedule, err := FindOneSchedule(ctx, tx, userID, query)
 	if err != nil {
 		return nil, errors.Wrap(err, "FindOneSchedule")
 	}
 
 	if schedule == nil {
-		return nil, errors.New("Cannot find schedule.")
+		return nil, ErrRecordNotFound
 	}
 
-	filter = map[string]any{"start_day": newStartDay, "userid": schedule.UserID}
-	foundSchedule, err := FindOneSchedule(tx, filter)
+	foundSchedule, err := findScheduleInWeek(ctx, tx, userID, newStartDay, scheduleID)
 	if err != nil {
-		return nil, errors.Wrap(err, "FindOneSchedule")
+		return nil, errors.Wrap(err, "findScheduleInWeek")
 	}
 
 	if foundSchedule != nil {
-		return nil, errors.New("Schedule exists already.")
+		return nil, ErrScheduleExists
 	}
 
 	schedule.StartDay = newStartDay
 
-	result, err := tx.Exec(`
+	result, err := tx.ExecContext(ctx, `
 		UPDATE schedules
 		SET    start_day = $1
 		WHERE scheduleid = $2
 	`, schedule.StartDay, schedule.ID)
 
-	if err != nil {
+	if isConstraintViolation(err, PG_UNIQUE_VIOLATION) {
+		return nil, ErrScheduleExists
+	} else if err != nil {
 		return nil, errors.Wrap(err, "tx.Exec")
 	}
 
@@ -155,18 +252,21 @@ func EditSchedule(tx db.WriteDBExecutor, newStartDay pgtype.Date, scheduleID int
 }
 
 // Deletes a schedule for a user.
-func DeleteSchedule(tx db.WriteDBExecutor, scheduleID int) (bool, error) {
-	filter := map[string]any{"scheduleid": scheduleID}
-	schedule, err := FindOneSchedule(tx, filter)
+func DeleteSchedule(ctx context.Context, tx db.WriteDBExecutor, userID int, scheduleID int) (bool, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "DeleteSchedule")
+	defer cancel()
+
+	query := db.Where(ScheduleColumns.ID.Eq(scheduleID))
+	schedule, err := FindOneSchedule(ctx, tx, userID, query)
 	if err != nil {
 		return false, errors.Wrap(err, "FindOneSchedule")
 	}
 
 	if schedule == nil {
-		return false, errors.New("Cannot find schedule.")
+		return false, ErrRecordNotFound
 	}
 
-	_, err = tx.Exec(`
+	_, err = tx.ExecContext(ctx, `
 		DELETE FROM schedules
 		WHERE scheduleid = $1
 	`, scheduleID)
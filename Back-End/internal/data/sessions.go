@@ -0,0 +1,169 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/db"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pkg/errors"
+)
+
+// How long a refresh token can be used before the user has to log in again.
+const SESSION_DURATION = 30 * 24 * time.Hour
+
+type Session struct {
+	ID               int                `db:"sessionid" json:"-"`
+	UserID           int                `db:"userid" json:"-"`
+	RefreshTokenHash string             `db:"refresh_token_hash" json:"-"`
+	CreatedAt        pgtype.Timestamptz `db:"created_at" json:"createdAt"`
+	ExpiresAt        pgtype.Timestamptz `db:"expires_at" json:"expiresAt"`
+	RevokedAt        pgtype.Timestamptz `db:"revoked_at" json:"revokedAt"`
+}
+
+func findSessionByRefreshTokenHash(ctx context.Context, readConn db.ReadDBExecutor, refreshTokenHash string) (*Session, error) {
+	session := &Session{}
+	err := readConn.GetContext(ctx, session, `
+		SELECT *
+		  FROM sessions
+		 WHERE refresh_token_hash = $1
+	`, refreshTokenHash)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Get")
+	}
+
+	return session, nil
+}
+
+// Creates a new login session for a user.
+// The plaintext refresh token is returned alongside the session; only its hash is stored.
+func CreateSession(ctx context.Context, tx db.WriteDBExecutor, userID int) (*Session, string, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "CreateSession")
+	defer cancel()
+
+	refreshToken, refreshTokenHash, err := internal.GenerateOpaqueToken()
+	if err != nil {
+		return nil, "", errors.Wrap(err, "GenerateOpaqueToken")
+	}
+
+	session := &Session{}
+	err = tx.GetContext(ctx, session, `
+		INSERT INTO sessions
+		(userid, refresh_token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING *
+	`, userID, refreshTokenHash, time.Now().Add(SESSION_DURATION))
+
+	if err != nil {
+		return nil, "", errors.Wrap(err, "tx.Get")
+	}
+
+	return session, refreshToken, nil
+}
+
+// Exchanges a refresh token for a new one, extending the session.
+// The presented refresh token can not be used again afterwards.
+// If the refresh token does not belong to an active session, ErrRecordNotFound is returned.
+func RotateSession(ctx context.Context, tx db.WriteDBExecutor, refreshToken string) (*Session, string, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "RotateSession")
+	defer cancel()
+
+	newRefreshToken, newRefreshTokenHash, err := internal.GenerateOpaqueToken()
+	if err != nil {
+		return nil, "", errors.Wrap(err, "GenerateOpaqueToken")
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE sessions
+		SET   refresh_token_hash = $1
+		    , expires_at         = $2
+		WHERE refresh_token_hash = $3
+		  AND revoked_at IS NULL
+		  AND expires_at > now()
+	`, newRefreshTokenHash, time.Now().Add(SESSION_DURATION), internal.HashOpaqueToken(refreshToken))
+
+	if err != nil {
+		return nil, "", errors.Wrap(err, "tx.Exec")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, "", errors.Wrap(err, "RowsAffected")
+	}
+
+	if rowsAffected == 0 {
+		return nil, "", ErrRecordNotFound
+	}
+
+	session, err := findSessionByRefreshTokenHash(ctx, tx, newRefreshTokenHash)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "findSessionByRefreshTokenHash")
+	}
+
+	return session, newRefreshToken, nil
+}
+
+// Revokes a single session of a user.
+func RevokeSession(ctx context.Context, tx db.WriteDBExecutor, userID int, sessionID int) error {
+	ctx, cancel := db.WithQueryTimeout(ctx, "RevokeSession")
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, `
+		UPDATE sessions
+		SET    revoked_at = now()
+		WHERE sessionid = $1
+		  AND userid = $2
+		  AND revoked_at IS NULL
+	`, sessionID, userID)
+
+	if err != nil {
+		return errors.Wrap(err, "tx.Exec")
+	}
+
+	return nil
+}
+
+// Revokes every active session of a user.
+// The session matching exceptSessionID is left untouched; pass 0 to revoke all of them.
+func RevokeUserSessions(ctx context.Context, tx db.WriteDBExecutor, userID int, exceptSessionID int) error {
+	ctx, cancel := db.WithQueryTimeout(ctx, "RevokeUserSessions")
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, `
+		UPDATE sessions
+		SET    revoked_at = now()
+		WHERE userid = $1
+		  AND sessionid <> $2
+		  AND revoked_at IS NULL
+	`, userID, exceptSessionID)
+
+	if err != nil {
+		return errors.Wrap(err, "tx.Exec")
+	}
+
+	return nil
+}
+
+// Checks whether a session has been revoked, has expired, or does not exist.
+func IsSessionRevoked(ctx context.Context, readConn db.ReadDBExecutor, sessionID int) (bool, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "IsSessionRevoked")
+	defer cancel()
+
+	var active bool
+	err := readConn.GetContext(ctx, &active, `
+		SELECT revoked_at IS NULL AND expires_at > now()
+		  FROM sessions
+		 WHERE sessionid = $1
+	`, sessionID)
+
+	if err == sql.ErrNoRows {
+		return true, nil
+	} else if err != nil {
+		return false, errors.Wrap(err, "Get")
+	}
+
+	return !active, nil
+}
@@ -1,46 +1,67 @@
 package data
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
 	"prime-shine-api/internal"
 	"prime-shine-api/internal/db"
 	"strings"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/pkg/errors"
 )
 
 type User struct {
-	ID       int    `db:"userid" json:"userID"`
-	Name     string `db:"name" json:"name"`
-	Email    string `db:"email" json:"email"`
-	Password string `db:"password" json:"-"`
+	ID           int         `db:"userid" json:"userID"`
+	Name         string      `db:"name" json:"name"`
+	Email        string      `db:"email" json:"email"`
+	Password     string      `db:"password" json:"-"`
+	Role         string      `db:"role" json:"role"`
+	TOTPSecret   pgtype.Text `db:"totp_secret" json:"-"`
+	TOTPEnabled  bool        `db:"totp_enabled" json:"totpEnabled"`
+	TOTPLastStep pgtype.Int8 `db:"totp_last_step" json:"-"`
+	Account      pgtype.Int4 `db:"accountid" json:"-"`
+
+	EmailVerifiedAt pgtype.Timestamptz `db:"email_verified_at" json:"emailVerifiedAt"`
 }
 
-// Finds one user.
-// If a runtime error occurs, a nil user and error is returned.
-// Otherwise, a user and nil error is returned.
-func FindOneUser(readConn db.ReadDBExecutor, filter map[string]any) (*User, error) {
-	user := &User{}
+// Returns the ID of the account that the user works in.
+// Schedules, recurring services, employees and crews belong to an account, which is keyed by the
+// user that holds it; everyone else joined it through an invitation.
+func (user *User) AccountID() int {
+	if user.Account.Valid {
+		return int(user.Account.Int32)
+	}
 
-	var args []any
-	var whereClauses []string
+	return user.ID
+}
 
-	for k, v := range filter {
-		argNum := len(args) + 1
-		whereClause := fmt.Sprintf("%v = $%v", k, argNum)
+var usersTable = db.NewTable[User]("users")
+
+// Columns that users can be looked up by.
+var UserColumns = struct {
+	ID    db.Column[User]
+	Name  db.Column[User]
+	Email db.Column[User]
+	Role  db.Column[User]
+}{
+	ID:    usersTable.Column("userid"),
+	Name:  usersTable.Column("name"),
+	Email: usersTable.Column("email"),
+	Role:  usersTable.Column("role"),
+}
 
-		whereClauses = append(whereClauses, whereClause)
-		args = append(args, v)
-	}
+// Finds one user.
+// If a runtime error occurs, a nil user and error is returned.
+// Otherwise, a user and nil error is returned.
+func FindOneUser(ctx context.Context, readConn db.ReadDBExecutor, query db.Query[User]) (*User, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "FindOneUser")
+	defer cancel()
 
-	query := fmt.Sprintf(`
-		SELECT *
-		  FROM users
-		 WHERE %v
-	`, strings.Join(whereClauses, " AND "))
+	user := &User{}
 
-	err := readConn.Get(user, query, args...)
+	statement, args := usersTable.Select(query.Limit(1))
+	err := readConn.GetContext(ctx, user, statement, args...)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	} else if err != nil {
@@ -53,9 +74,12 @@ func FindOneUser(readConn db.ReadDBExecutor, filter map[string]any) (*User, erro
 // Searches for a user in the context of logging in.
 // If a runtime error occurs, a nil user and error is returned.
 // Otherwise, a user and nil error is returned.
-func QueryUserAndPassword(readConn db.ReadDBExecutor, email string, password string) (*User, error) {
-	filter := map[string]any{"email": email}
-	user, err := FindOneUser(readConn, filter)
+func QueryUserAndPassword(ctx context.Context, readConn db.ReadDBExecutor, email string, password string) (*User, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "QueryUserAndPassword")
+	defer cancel()
+
+	query := db.Where(UserColumns.Email.Eq(email))
+	user, err := FindOneUser(ctx, readConn, query)
 	if err != nil {
 		return nil, errors.Wrap(err, "FindOneUser")
 	}
@@ -76,10 +100,25 @@ func QueryUserAndPassword(readConn db.ReadDBExecutor, email string, password str
 	}
 }
 
-// Creates a new user.
-func CreateUser(tx db.WriteDBExecutor, name string, email string, password string) (*User, error) {
-	filter := map[string]any{"email": email}
-	foundUser, err := FindOneUser(tx, filter)
+// Creates a new user from an invitation.
+// The email, role and account of the user come from the invitation, and the email counts as verified
+// since the invitation was sent to it.
+// If the invitation is invalid, has expired, or was already accepted, ErrRecordNotFound is returned.
+func CreateUser(ctx context.Context, tx db.WriteDBExecutor, invitationToken string, name string, password string) (*User, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "CreateUser")
+	defer cancel()
+
+	if strings.TrimSpace(name) == "" {
+		return nil, errors.New("Name can not be empty.")
+	}
+
+	invitation, err := AcceptInvitation(ctx, tx, invitationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	query := db.Where(UserColumns.Email.Eq(invitation.Email))
+	foundUser, err := FindOneUser(ctx, tx, query)
 	if err != nil {
 		return nil, errors.Wrap(err, "FindOneUser")
 	}
@@ -93,54 +132,53 @@ func CreateUser(tx db.WriteDBExecutor, name string, email string, password strin
 		return nil, errors.Wrap(err, "HashPassword")
 	}
 
-	result, err := tx.Exec(`
+	newUser := &User{}
+	err = tx.GetContext(ctx, newUser, `
 		INSERT INTO users
-		(name, email, password)
-		VALUES ($1, $2, $3)
-	`, name, email, string(hashedPassword))
+		(name, email, password, role, accountid, email_verified_at)
+		VALUES ($1, $2, $3, $4, $5, now())
+		RETURNING `+usersTable.Columns()+`
+	`, name, invitation.Email, string(hashedPassword), invitation.Role, invitation.AccountID)
 
 	if err != nil {
-		return nil, errors.Wrap(err, "tx.Exec")
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return nil, errors.Wrap(err, "RowsAffected")
-	}
-
-	if rowsAffected != 1 {
-		return nil, errors.New("failed to insert users entry")
-	}
-
-	// Grab the newly created user
-	newUser, err := FindOneUser(tx, filter)
-	if err != nil {
-		return nil, errors.Wrap(err, "FindOneUser")
+		return nil, errors.Wrap(err, "tx.Get")
 	}
 
 	return newUser, nil
 }
 
-// Edits a user.
-func EditUser(tx db.WriteDBExecutor, userID int, newEmail string, newName string, newPassword string) (*User, error) {
-	hashedPassword, err := internal.HashPassword(newPassword)
-	if err != nil {
-		return nil, errors.Wrap(err, "HashPassword")
-	}
+// Edits a user's profile.
+// Fields that are nil are left unchanged. Passwords are changed through SetUserPassword.
+// Changing the email marks it as unverified.
+func EditUser(ctx context.Context, tx db.WriteDBExecutor, userID int, newName *string, newEmail *string) (*User, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "EditUser")
+	defer cancel()
 
-	filter := map[string]any{"userid": userID}
-	user, err := FindOneUser(tx, filter)
+	query := db.Where(UserColumns.ID.Eq(userID))
+	user, err := FindOneUser(ctx, tx, query)
 	if err != nil {
 		return nil, errors.Wrap(err, "FindOneUser")
 	}
 
 	if user == nil {
-		return nil, errors.New("Unable to find user.")
+		return nil, ErrRecordNotFound
 	}
 
-	if user.Email != newEmail {
-		filter = map[string]any{"email": newEmail}
-		foundUser, err := FindOneUser(tx, filter)
+	if newName != nil {
+		if strings.TrimSpace(*newName) == "" {
+			return nil, errors.New("Name can not be empty.")
+		}
+
+		user.Name = *newName
+	}
+
+	if newEmail != nil && *newEmail != user.Email {
+		if strings.TrimSpace(*newEmail) == "" {
+			return nil, errors.New("Email can not be empty.")
+		}
+
+		query = db.Where(UserColumns.Email.Eq(*newEmail))
+		foundUser, err := FindOneUser(ctx, tx, query)
 		if err != nil {
 			return nil, errors.Wrap(err, "FindOneUser")
 		}
@@ -148,20 +186,18 @@ func EditUser(tx db.WriteDBExecutor, userID int, newEmail string, newName string
 		if foundUser != nil {
 			return nil, errors.New("User with that email exists.")
 		}
-	}
 
-	user.ID = userID
-	user.Name = newName
-	user.Email = newEmail
-	user.Password = string(hashedPassword)
+		user.Email = *newEmail
+		user.EmailVerifiedAt = pgtype.Timestamptz{}
+	}
 
-	result, err := tx.Exec(`
+	result, err := tx.ExecContext(ctx, `
 		UPDATE users
-		SET   name     = $1
-		    , email    = $2
-			, password = $3
+		SET   name              = $1
+		    , email             = $2
+		    , email_verified_at = $3
 		WHERE userid = $4
-	`, user.Name, user.Email, user.Password, user.ID)
+	`, user.Name, user.Email, user.EmailVerifiedAt, user.ID)
 
 	if err != nil {
 		return nil, errors.Wrap(err, "tx.Exec")
@@ -179,10 +215,17 @@ func EditUser(tx db.WriteDBExecutor, userID int, newEmail string, newName string
 	return user, nil
 }
 
+// Returned when deleting a user who holds an account that others still work in, since the
+// account's data would go with them.
+var ErrAccountHasMembers = errors.New("Users who joined this user's account have to be deleted first.")
+
 // Deletes a user.
-func DeleteUser(tx db.WriteDBExecutor, userID int) (bool, error) {
-	filter := map[string]any{"userid": userID}
-	user, err := FindOneUser(tx, filter)
+func DeleteUser(ctx context.Context, tx db.WriteDBExecutor, userID int) (bool, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "DeleteUser")
+	defer cancel()
+
+	query := db.Where(UserColumns.ID.Eq(userID))
+	user, err := FindOneUser(ctx, tx, query)
 	if err != nil {
 		return false, errors.Wrap(err, "FindOneUser")
 	}
@@ -191,7 +234,22 @@ func DeleteUser(tx db.WriteDBExecutor, userID int) (bool, error) {
 		return false, errors.New("User not found.")
 	}
 
-	_, err = tx.Exec(`
+	var members int
+	err = tx.GetContext(ctx, &members, `
+		SELECT count(*)
+		  FROM users
+		 WHERE accountid = $1
+	`, userID)
+
+	if err != nil {
+		return false, errors.Wrap(err, "tx.Get")
+	}
+
+	if members > 0 {
+		return false, ErrAccountHasMembers
+	}
+
+	_, err = tx.ExecContext(ctx, `
 		DELETE FROM users
 		WHERE userid = $1
 	`, userID)
@@ -202,3 +260,91 @@ func DeleteUser(tx db.WriteDBExecutor, userID int) (bool, error) {
 
 	return true, nil
 }
+
+// Returned when the only owner would lose their role, which would leave nobody to manage users.
+var ErrLastOwner = errors.New("The last owner can not be given another role.")
+
+// Changes the role of a user.
+func EditUserRole(ctx context.Context, tx db.WriteDBExecutor, userID int, newRole string) (*User, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx, "EditUserRole")
+	defer cancel()
+
+	if !internal.IsValidRole(newRole) {
+		return nil, errors.Errorf("Invalid role: %v", newRole)
+	}
+
+	query := db.Where(UserColumns.ID.Eq(userID))
+	user, err := FindOneUser(ctx, tx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "FindOneUser")
+	}
+
+	if user == nil {
+		return nil, ErrRecordNotFound
+	}
+
+	if user.Role == internal.ROLE_OWNER && newRole != internal.ROLE_OWNER {
+		// locks the owners, so that two owners can not demote each other at the same time
+		owners := []int{}
+		err = tx.SelectContext(ctx, &owners, `
+			SELECT userid
+			  FROM users
+			 WHERE role = $1
+			   FOR UPDATE
+		`, internal.ROLE_OWNER)
+
+		if err != nil {
+			return nil, errors.Wrap(err, "Select")
+		}
+
+		if len(owners) <= 1 {
+			return nil, ErrLastOwner
+		}
+	}
+
+	user.Role = newRole
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE users
+		SET    role = $1
+		WHERE userid = $2
+	`, user.Role, user.ID)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "tx.Exec")
+	}
+
+	return user, nil
+}
+
+// Sets a new password for a user.
+func SetUserPassword(ctx context.Context, tx db.WriteDBExecutor, userID int, newPassword string) error {
+	ctx, cancel := db.WithQueryTimeout(ctx, "SetUserPassword")
+	defer cancel()
+
+	hashedPassword, err := internal.HashPassword(newPassword)
+	if err != nil {
+		return errors.Wrap(err, "HashPassword")
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET    password = $1
+		WHERE userid = $2
+	`, string(hashedPassword), userID)
+
+	if err != nil {
+		return errors.Wrap(err, "tx.Exec")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "RowsAffected")
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 )
 
@@ -11,17 +12,27 @@ import (
 // sqlx wraps the standard database/sql package, and provides a set of methods that are more convenient to use.
 // So there is no need to tightly couple the code to sqlx, but we can still use it.
 
+// Every method takes a context, so that queries are abandoned once the request is gone
+// or the query runs past its deadline (see WithQueryTimeout).
+
 // ReadDBExecutor is an interface that defines the methods that can be used to read from a database.
 type ReadDBExecutor interface {
-	Query(query string, args ...any) (*sql.Rows, error)
-	QueryRow(string, ...any) *sql.Row
+	// No QueryRowContext: a *sql.Row can not report that a LazyTx failed to begin its transaction.
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
 
-	Get(dest interface{}, query string, args ...any) error
-	Select(dest interface{}, query string, args ...any) error
+	GetContext(ctx context.Context, dest interface{}, query string, args ...any) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...any) error
 }
 
 // WriteDBExecutor is an interface that defines the methods that can be used to write to a database.
 type WriteDBExecutor interface {
-	Exec(query string, args ...any) (sql.Result, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 	ReadDBExecutor
 }
+
+// Tx is a WriteDBExecutor whose writes only take effect once committed.
+type Tx interface {
+	WriteDBExecutor
+	Commit() error
+	Rollback() error
+}
@@ -1,28 +1,33 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 )
 
-// LazyTx is a struct that supports both read and write operations, starting a transaction lazily
+// LazyTx is a struct that supports both read and write operations, starting a transaction lazily.
+// The transaction starts with the first query of any kind, so requests that never reach the database do not hold a connection.
 type LazyTx struct {
+	ctx     context.Context
 	db      *sqlx.DB
 	tx      *sqlx.Tx
 	started bool
 }
 
-// Creates a new LazyTx instance
-func NewLazyTx(db *sqlx.DB) *LazyTx {
-	return &LazyTx{db: db}
+// Creates a new LazyTx instance.
+// The transaction is bound to ctx (usually the request's), and is rolled back if ctx is cancelled.
+func NewLazyTx(ctx context.Context, db *sqlx.DB) *LazyTx {
+	return &LazyTx{ctx: ctx, db: db}
 }
 
 // Lazily starts a transaction if it hasn't been started yet
 func (lt *LazyTx) beginTx() error {
 	if !lt.started {
-		tx, err := lt.db.Beginx()
+		// Not the query's context: the transaction has to outlive each query's deadline.
+		tx, err := lt.db.BeginTxx(lt.ctx, nil)
 		if err != nil {
 			return errors.Wrap(err, "sqlx begin transaction")
 		}
@@ -48,41 +53,36 @@ func (lt *LazyTx) Rollback() error {
 	return nil
 }
 
-// Get implements the ReadDBExecutor interface and can work with either tx or db
-func (lt *LazyTx) Get(dest interface{}, query string, args ...interface{}) error {
-	if lt.started {
-		return lt.tx.Get(dest, query, args...)
-	}
-	return lt.db.Get(dest, query, args...)
-}
-
-// Query implements the ReadDBExecutor interface and can work with either tx or db
-func (lt *LazyTx) Query(query string, args ...interface{}) (*sql.Rows, error) {
-	if lt.started {
-		return lt.tx.Query(query, args...)
+// GetContext implements the ReadDBExecutor interface, lazily starting a transaction if necessary.
+// Reads go through the transaction too: writes with RETURNING are run with GetContext, and locks
+// taken with SELECT ... FOR UPDATE have to last until the transaction ends.
+func (lt *LazyTx) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if err := lt.beginTx(); err != nil {
+		return err
 	}
-	return lt.db.Query(query, args...)
+	return lt.tx.GetContext(ctx, dest, query, args...)
 }
 
-// QueryRow implements the ReadDBExecutor interface and can work with either tx or db
-func (lt *LazyTx) QueryRow(query string, args ...interface{}) *sql.Row {
-	if lt.started {
-		return lt.tx.QueryRow(query, args...)
+// QueryContext implements the ReadDBExecutor interface, lazily starting a transaction if necessary
+func (lt *LazyTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if err := lt.beginTx(); err != nil {
+		return nil, err
 	}
-	return lt.db.QueryRow(query, args...)
+	return lt.tx.QueryContext(ctx, query, args...)
 }
 
-// Exec implements the WriteDBExecutor interface, lazily starting a transaction if necessary
-func (lt *LazyTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+// ExecContext implements the WriteDBExecutor interface, lazily starting a transaction if necessary
+func (lt *LazyTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
 	if err := lt.beginTx(); err != nil {
 		return nil, err
 	}
-	return lt.tx.Exec(query, args...)
+	return lt.tx.ExecContext(ctx, query, args...)
 }
 
-func (lt *LazyTx) Select(dest interface{}, query string, args ...interface{}) error {
-	if lt.started {
-		return lt.tx.Select(dest, query, args...)
+// SelectContext implements the ReadDBExecutor interface, lazily starting a transaction if necessary
+func (lt *LazyTx) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if err := lt.beginTx(); err != nil {
+		return err
 	}
-	return lt.db.Select(dest, query, args...)
+	return lt.tx.SelectContext(ctx, dest, query, args...)
 }
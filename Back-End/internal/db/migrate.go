@@ -0,0 +1,324 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"embed"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Migrations are pairs of files named <version>_<name>.up.sql and <version>_<name>.down.sql,
+// applied in order of version. Applied migrations must never be edited; add a new one instead.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Arbitrary, but fixed: every API instance must take the same advisory lock,
+// so that only one of them migrates the database at a time.
+const MIGRATION_LOCK_ID = 7_245_019_388
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+
+	up   string
+	down string
+}
+
+type MigrationStatus struct {
+	Version int64
+	Name    string
+	// Invalid when the migration has not been applied.
+	AppliedAt pgtype.Timestamptz
+	// True when the database has the migration, but this binary does not know about it
+	// (e.g. it was applied by a newer release).
+	Unknown bool
+}
+
+// Reads the migrations embedded in the binary, ordered by version.
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, errors.Wrap(err, "ReadDir")
+	}
+
+	byVersion := map[int64]*Migration{}
+
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, errors.Errorf("migration file %v is not named <version>_<name>.(up|down).sql", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "version of %v", entry.Name())
+		}
+
+		contents, err := fs.ReadFile(migrationFiles, "migrations/"+entry.Name())
+		if err != nil {
+			return nil, errors.Wrap(err, "ReadFile")
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != match[2] {
+			return nil, errors.Errorf("migration version %v is used by both %v and %v", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.up = string(contents)
+		} else {
+			migration.down = string(contents)
+		}
+	}
+
+	migrations := []Migration{}
+	for _, migration := range byVersion {
+		if migration.up == "" || migration.down == "" {
+			return nil, errors.Errorf("migration %v_%v needs both an up and a down file", migration.Version, migration.Name)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return migrations, nil
+}
+
+// Applies every migration that has not been applied yet, and returns the ones it applied.
+func MigrateUp(ctx context.Context, conn *sqlx.DB) ([]Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, errors.Wrap(err, "LoadMigrations")
+	}
+
+	applied := []Migration{}
+
+	err = withMigrationLock(ctx, conn, func(lockConn *sqlx.Conn) error {
+		versions, err := appliedMigrationVersions(ctx, lockConn)
+		if err != nil {
+			return errors.Wrap(err, "appliedMigrationVersions")
+		}
+
+		for _, migration := range migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+
+			err := runMigration(ctx, lockConn, migration.up, func(tx *sqlx.Tx) error {
+				_, err := tx.ExecContext(ctx, `
+					INSERT INTO schema_migrations
+					(version, name)
+					VALUES ($1, $2)
+				`, migration.Version, migration.Name)
+
+				return err
+			})
+
+			if err != nil {
+				return errors.Wrapf(err, "migration %v_%v up", migration.Version, migration.Name)
+			}
+
+			applied = append(applied, migration)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Reverts the last `steps` applied migrations, and returns the ones it reverted.
+func MigrateDown(ctx context.Context, conn *sqlx.DB, steps int) ([]Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, errors.Wrap(err, "LoadMigrations")
+	}
+
+	reverted := []Migration{}
+
+	err = withMigrationLock(ctx, conn, func(lockConn *sqlx.Conn) error {
+		versions, err := appliedMigrationVersions(ctx, lockConn)
+		if err != nil {
+			return errors.Wrap(err, "appliedMigrationVersions")
+		}
+
+		known := map[int64]Migration{}
+		for _, migration := range migrations {
+			known[migration.Version] = migration
+		}
+
+		appliedVersions := []int64{}
+		for version := range versions {
+			appliedVersions = append(appliedVersions, version)
+		}
+
+		slices.Sort(appliedVersions)
+		slices.Reverse(appliedVersions)
+
+		for _, version := range appliedVersions[:min(steps, len(appliedVersions))] {
+			migration, ok := known[version]
+			if !ok {
+				return errors.Errorf("migration %v was applied by a newer release, and cannot be reverted by this one", version)
+			}
+
+			err := runMigration(ctx, lockConn, migration.down, func(tx *sqlx.Tx) error {
+				_, err := tx.ExecContext(ctx, `
+					DELETE FROM schema_migrations
+					 WHERE version = $1
+				`, migration.Version)
+
+				return err
+			})
+
+			if err != nil {
+				return errors.Wrapf(err, "migration %v_%v down", migration.Version, migration.Name)
+			}
+
+			reverted = append(reverted, migration)
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Lists every migration, whether it was applied or not, ordered by version.
+func MigrationStatuses(ctx context.Context, conn *sqlx.DB) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, errors.Wrap(err, "LoadMigrations")
+	}
+
+	statuses := []MigrationStatus{}
+
+	err = withMigrationLock(ctx, conn, func(lockConn *sqlx.Conn) error {
+		versions, err := appliedMigrationVersions(ctx, lockConn)
+		if err != nil {
+			return errors.Wrap(err, "appliedMigrationVersions")
+		}
+
+		for _, migration := range migrations {
+			status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+			if applied, ok := versions[migration.Version]; ok {
+				status.AppliedAt = applied.AppliedAt
+				delete(versions, migration.Version)
+			}
+
+			statuses = append(statuses, status)
+		}
+
+		for _, applied := range versions {
+			applied.Unknown = true
+			statuses = append(statuses, applied)
+		}
+
+		return nil
+	})
+
+	slices.SortFunc(statuses, func(a, b MigrationStatus) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return statuses, err
+}
+
+// Runs fn on a single connection that holds the migration lock.
+// Advisory locks belong to a session, so the lock and the migrations have to share a connection.
+func withMigrationLock(ctx context.Context, conn *sqlx.DB, fn func(lockConn *sqlx.Conn) error) error {
+	lockConn, err := conn.Connx(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Connx")
+	}
+	defer lockConn.Close()
+
+	_, err = lockConn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", MIGRATION_LOCK_ID)
+	if err != nil {
+		return errors.Wrap(err, "pg_advisory_lock")
+	}
+
+	defer func() {
+		// Not ctx: the lock has to be released even if ctx was cancelled.
+		_, _ = lockConn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", MIGRATION_LOCK_ID)
+	}()
+
+	_, err = lockConn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			  version       int8                      NOT NULL
+			, name          varchar(256)              NOT NULL
+			, applied_at    timestamp with time zone  NOT NULL DEFAULT now()
+
+			, CONSTRAINT schema_migrations_pk PRIMARY KEY (version)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create schema_migrations")
+	}
+
+	return fn(lockConn)
+}
+
+func appliedMigrationVersions(ctx context.Context, lockConn *sqlx.Conn) (map[int64]MigrationStatus, error) {
+	rows, err := lockConn.QueryxContext(ctx, `
+		SELECT version, name, applied_at
+		  FROM schema_migrations
+	`)
+	if err != nil {
+		return nil, errors.Wrap(err, "QueryxContext")
+	}
+	defer rows.Close()
+
+	versions := map[int64]MigrationStatus{}
+	for rows.Next() {
+		var status MigrationStatus
+		err := rows.Scan(&status.Version, &status.Name, &status.AppliedAt)
+		if err != nil {
+			return nil, errors.Wrap(err, "Scan")
+		}
+
+		versions[status.Version] = status
+	}
+
+	return versions, errors.Wrap(rows.Err(), "rows")
+}
+
+// Runs a migration's SQL and its bookkeeping in one transaction,
+// so a failed migration leaves nothing behind.
+func runMigration(ctx context.Context, lockConn *sqlx.Conn, sql string, record func(tx *sqlx.Tx) error) error {
+	tx, err := lockConn.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "BeginTxx")
+	}
+	defer tx.Rollback()
+
+	// Without arguments the statements are sent as a simple query, so a file may hold several.
+	_, err = tx.ExecContext(ctx, sql)
+	if err != nil {
+		return errors.Wrap(err, "ExecContext")
+	}
+
+	err = record(tx)
+	if err != nil {
+		return errors.Wrap(err, "record")
+	}
+
+	return errors.Wrap(tx.Commit(), "Commit")
+}
@@ -0,0 +1,99 @@
+package db_test
+
+import (
+	"context"
+	"os"
+	"prime-shine-api/internal"
+	"prime-shine-api/internal/assert"
+	"prime-shine-api/internal/db"
+	"prime-shine-api/internal/testdb"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(testdb.Run(m))
+}
+
+// The schema of databases created from Docker/dev/postgres/tables.ddl, before migrations existed.
+const legacySchema = `
+	create table users (
+		  userid    int4            generated always as identity
+		, name      varchar(256)    not null
+		, email     varchar(256)    not null
+		, password  varchar(100)    not null
+
+		, constraint userid_pk      primary key (userid)
+		, constraint unique_email   unique (email)
+	);
+
+	create table schedules (
+		  scheduleid    int4    generated always as identity
+		, userid        int4    not null
+		, start_day     date    not null
+
+		, constraint scheduleid_pk primary key (scheduleid)
+		, foreign key (userid) references users (userid) on delete cascade
+	);
+
+	create table scheduled_customers (
+		  scheduledcustomerid   int4                      generated always as identity
+		, wave_customerid       varchar(84)               not null
+		, start_time            timestamp with time zone  not null
+		, end_time              timestamp with time zone  not null
+		, day_offset            int2                      not null
+		, scheduleid            int4                      not null
+
+		, constraint scheduledcustomerid_pk primary key (scheduledcustomerid)
+		, foreign key (scheduleid) references schedules (scheduleid) on delete cascade
+	);
+
+	insert into users (name, email, password) values
+	('Bob', 'bob@example.com', 'not a bcrypt hash'),
+	('Alice', 'alice@example.com', 'not a bcrypt hash');
+
+	insert into schedules (userid, start_day)
+	select userid, '2025-08-11' from users where email = 'bob@example.com';
+`
+
+func TestMigrateUpAdoptsLegacySchema(t *testing.T) {
+	conn := testdb.NewEmpty(t)
+	ctx := context.Background()
+
+	_, err := conn.ExecContext(ctx, legacySchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.MigrateUp(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var roles []string
+	err = conn.SelectContext(ctx, &roles, `SELECT role FROM users ORDER BY userid`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first user is backfilled as the owner
+	assert.Equal(t, len(roles), 2)
+	assert.Equal(t, roles[0], internal.ROLE_OWNER)
+	assert.Equal(t, roles[1], internal.ROLE_CLEANER)
+
+	var schedules int
+	err = conn.GetContext(ctx, &schedules, `SELECT count(*) FROM schedules`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, schedules, 1)
+
+	statuses, err := db.MigrationStatuses(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, status := range statuses {
+		assert.Equal(t, status.AppliedAt.Valid, true)
+	}
+}
@@ -0,0 +1,22 @@
+package db
+
+import (
+	"prime-shine-api/internal/assert"
+	"testing"
+)
+
+func TestMigrationsLoad(t *testing.T) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, migrations[0].Version, int64(1))
+	assert.Equal(t, migrations[0].Name, "initial_schema")
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			t.Errorf("migration %v is ordered after %v", migrations[i].Version, migrations[i-1].Version)
+		}
+	}
+}
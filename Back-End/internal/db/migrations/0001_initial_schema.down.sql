@@ -0,0 +1,11 @@
+drop table audit_log;
+drop table email_verification_tokens;
+drop table invitations;
+drop table mfa_challenges;
+drop table recovery_codes;
+drop table login_events;
+drop table password_reset_tokens;
+drop table sessions;
+drop table scheduled_customers;
+drop table schedules;
+drop table users;
@@ -0,0 +1,164 @@
+-- Databases from before migrations were created from Docker/dev/postgres/tables.ddl, which only had users, schedules
+-- and scheduled_customers. Those three are created if missing and brought up to date otherwise, so that this
+-- migration adopts such a database instead of failing on it.
+create table if not exists users (
+      userid            int4                      generated always as identity
+    , name              varchar(256)              not null
+    , email             varchar(256)              not null
+    , password          varchar(100)              not null
+    , role              varchar(32)               not null default 'cleaner'
+    , totp_secret       varchar(64)               -- base32 encoded; set once enrollment starts
+    , totp_enabled      boolean                   not null default false
+    , totp_last_step    int8                      -- last accepted TOTP time step, prevents code replay
+    , email_verified_at timestamp with time zone  -- null until the user proves they own the email
+
+    , constraint userid_pk      primary key (userid)
+    , constraint unique_email   unique (email)
+    , constraint valid_role     check (role in ('owner', 'office_manager', 'cleaner'))
+);
+
+alter table users
+      add column if not exists role              varchar(32)               not null default 'cleaner'
+    , add column if not exists totp_secret       varchar(64)
+    , add column if not exists totp_enabled      boolean                   not null default false
+    , add column if not exists totp_last_step    int8
+    , add column if not exists email_verified_at timestamp with time zone
+    , drop constraint if exists valid_role
+    , add constraint valid_role check (role in ('owner', 'office_manager', 'cleaner'));
+
+-- adopted users all start out as cleaners, which would leave nobody to manage them: the first user becomes the owner
+update users
+   set role = 'owner'
+ where userid = (select min(userid) from users)
+   and not exists (select 1 from users where role = 'owner');
+
+create table if not exists schedules (
+      scheduleid    int4    generated always as identity
+    , userid        int4    not null
+    , start_day     date    not null
+
+    , constraint scheduleid_pk primary key (scheduleid)
+    , foreign key (userid) references users (userid) on delete cascade
+);
+
+create table if not exists scheduled_customers (
+      scheduledcustomerid   int4                      generated always as identity
+    , wave_customerid       varchar(84)               not null -- size of IDs that Wave generates
+    , start_time            timestamp with time zone  not null
+    , end_time              timestamp with time zone  not null
+    , day_offset            int2                      not null
+    , scheduleid            int4                      not null
+
+    , constraint scheduledcustomerid_pk primary key (scheduledcustomerid)
+    , foreign key (scheduleid) references schedules (scheduleid) on delete cascade
+);
+
+create table sessions (
+      sessionid             int4                      generated always as identity
+    , userid                int4                      not null
+    , refresh_token_hash    char(64)                  not null -- hex encoded SHA-256 digest
+    , created_at            timestamp with time zone  not null default now()
+    , expires_at            timestamp with time zone  not null
+    , revoked_at            timestamp with time zone
+
+    , constraint sessionid_pk               primary key (sessionid)
+    , constraint unique_refresh_token_hash  unique (refresh_token_hash)
+    , foreign key (userid) references users (userid) on delete cascade
+);
+
+create table password_reset_tokens (
+      passwordresettokenid  int4                      generated always as identity
+    , userid                int4                      not null
+    , token_hash            char(64)                  not null -- hex encoded SHA-256 digest
+    , expires_at            timestamp with time zone  not null
+    , used_at               timestamp with time zone
+
+    , constraint passwordresettokenid_pk    primary key (passwordresettokenid)
+    , constraint unique_token_hash          unique (token_hash)
+    , foreign key (userid) references users (userid) on delete cascade
+);
+
+create table login_events (
+      logineventid  int4                      generated always as identity
+    , userid        int4                      -- null when the email does not belong to an account
+    , email         varchar(256)              not null
+    , ip_address    varchar(45)               not null
+    , succeeded     boolean                   not null
+    , created_at    timestamp with time zone  not null default now()
+
+    , constraint logineventid_pk primary key (logineventid)
+    , foreign key (userid) references users (userid) on delete set null
+);
+
+create index login_events_email_idx on login_events (email, created_at);
+create index login_events_ip_address_idx on login_events (ip_address, created_at);
+
+create table recovery_codes (
+      recoverycodeid    int4                      generated always as identity
+    , userid            int4                      not null
+    , code_hash         char(64)                  not null -- hex encoded SHA-256 digest
+    , used_at           timestamp with time zone
+
+    , constraint recoverycodeid_pk primary key (recoverycodeid)
+    , foreign key (userid) references users (userid) on delete cascade
+);
+
+create table mfa_challenges (
+      mfachallengeid    int4                      generated always as identity
+    , userid            int4                      not null
+    , token_hash        char(64)                  not null -- hex encoded SHA-256 digest
+    , attempts          int2                      not null default 0
+    , expires_at        timestamp with time zone  not null
+    , used_at           timestamp with time zone
+
+    , constraint mfachallengeid_pk          primary key (mfachallengeid)
+    , constraint unique_mfa_token_hash      unique (token_hash)
+    , foreign key (userid) references users (userid) on delete cascade
+);
+
+create table invitations (
+      invitationid      int4                      generated always as identity
+    , email             varchar(256)              not null
+    , role              varchar(32)               not null
+    , token_hash        char(64)                  not null -- hex encoded SHA-256 digest
+    , invited_by        int4
+    , created_at        timestamp with time zone  not null default now()
+    , expires_at        timestamp with time zone  not null
+    , accepted_at       timestamp with time zone
+
+    , constraint invitationid_pk            primary key (invitationid)
+    , constraint unique_invitation_token    unique (token_hash)
+    , constraint valid_invitation_role      check (role in ('owner', 'office_manager', 'cleaner'))
+    , foreign key (invited_by) references users (userid) on delete set null
+);
+
+create table email_verification_tokens (
+      emailverificationtokenid  int4                      generated always as identity
+    , userid                    int4                      not null
+    , email                     varchar(256)              not null -- the address being verified
+    , token_hash                char(64)                  not null -- hex encoded SHA-256 digest
+    , expires_at                timestamp with time zone  not null
+    , used_at                   timestamp with time zone
+
+    , constraint emailverificationtokenid_pk        primary key (emailverificationtokenid)
+    , constraint unique_email_verification_token    unique (token_hash)
+    , foreign key (userid) references users (userid) on delete cascade
+);
+
+create table audit_log (
+      auditlogid    int4                      generated always as identity
+    , userid        int4                      -- the actor; null once their account is deleted
+    , action        varchar(16)               not null
+    , entity_type   varchar(64)               not null
+    , entity_id     varchar(128)              -- null when Wave does not tell us the ID of a created entity
+    , before        jsonb
+    , after         jsonb
+    , created_at    timestamp with time zone  not null default now()
+
+    , constraint auditlogid_pk  primary key (auditlogid)
+    , constraint valid_action   check (action in ('create', 'edit', 'delete'))
+    , foreign key (userid) references users (userid) on delete set null
+);
+
+create index audit_log_entity_idx on audit_log (entity_type, entity_id, created_at);
+create index audit_log_userid_idx on audit_log (userid, created_at);
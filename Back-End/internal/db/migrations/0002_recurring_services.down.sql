@@ -0,0 +1,4 @@
+alter table scheduled_customers drop column recurringserviceid;
+
+drop table recurring_service_skips;
+drop table recurring_services;
@@ -0,0 +1,36 @@
+create table recurring_services (
+      recurringserviceid    int4                      generated always as identity
+    , userid                int4                      not null
+    , wave_customerid       varchar(84)               not null -- size of IDs that Wave generates
+    , weekday               int2                      not null -- 0 is Sunday
+    , start_time            time                      not null -- wall clock time in the business time zone (-business-time-zone)
+    , end_time              time                      not null
+    , frequency             varchar(16)               not null
+    , week_of_month         int2                               -- monthly services only: 1 to 4, or -1 for the last week
+    , start_date            date                      not null
+    , end_date              date                               -- inclusive; services without one never end
+
+    , constraint recurringserviceid_pk              primary key (recurringserviceid)
+    , constraint recurring_service_weekday          check (weekday between 0 and 6)
+    , constraint recurring_service_time_window      check (start_time < end_time)
+    , constraint recurring_service_frequency        check (frequency in ('weekly', 'biweekly', 'monthly'))
+    , constraint recurring_service_week_of_month    check (
+          (frequency = 'monthly' and week_of_month in (1, 2, 3, 4, -1))
+          or (frequency <> 'monthly' and week_of_month is null)
+      )
+    , constraint recurring_service_dates            check (end_date is null or start_date <= end_date)
+    , foreign key (userid) references users (userid) on delete cascade
+);
+
+-- dates a recurring service does not take place on, e.g. holidays
+create table recurring_service_skips (
+      recurringserviceid    int4    not null
+    , skip_date             date    not null
+
+    , constraint recurring_service_skips_pk primary key (recurringserviceid, skip_date)
+    , foreign key (recurringserviceid) references recurring_services (recurringserviceid) on delete cascade
+);
+
+-- the recurring service a scheduled customer was generated from, if any
+alter table scheduled_customers
+    add column recurringserviceid int4 references recurring_services (recurringserviceid) on delete set null;
@@ -0,0 +1,6 @@
+drop table scheduled_customer_employees;
+drop table scheduled_customer_crews;
+drop table crew_members;
+drop table crews;
+drop table employee_availability;
+drop table employees;
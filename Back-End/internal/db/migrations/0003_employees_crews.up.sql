@@ -0,0 +1,66 @@
+create table employees (
+      employeeid        int4            generated always as identity
+    , userid            int4            not null
+    , name              varchar(256)    not null
+    , email             varchar(256)
+    , phone             varchar(32)
+    , active            boolean         not null default true
+
+    , constraint employeeid_pk  primary key (employeeid)
+    , foreign key (userid) references users (userid) on delete cascade
+);
+
+-- weekly windows an employee can work in, in the business time zone
+create table employee_availability (
+      employeeid        int4    not null
+    , weekday           int2    not null -- 0 is Sunday
+    , start_time        time    not null
+    , end_time          time    not null
+
+    , constraint employee_availability_pk       primary key (employeeid, weekday, start_time)
+    , constraint employee_availability_weekday  check (weekday between 0 and 6)
+    , constraint employee_availability_window   check (start_time < end_time)
+    , foreign key (employeeid) references employees (employeeid) on delete cascade
+);
+
+create table crews (
+      crewid            int4            generated always as identity
+    , userid            int4            not null
+    , name              varchar(256)    not null
+
+    , constraint crewid_pk          primary key (crewid)
+    , constraint unique_crew_name   unique (userid, name)
+    , foreign key (userid) references users (userid) on delete cascade
+);
+
+create table crew_members (
+      crewid            int4    not null
+    , employeeid        int4    not null
+
+    , constraint crew_members_pk primary key (crewid, employeeid)
+    , foreign key (crewid) references crews (crewid) on delete cascade
+    , foreign key (employeeid) references employees (employeeid) on delete cascade
+);
+
+-- who cleans a scheduled customer: whole crews, individual employees, or both
+create table scheduled_customer_crews (
+      scheduledcustomerid   int4    not null
+    , crewid                int4    not null
+
+    , constraint scheduled_customer_crews_pk primary key (scheduledcustomerid, crewid)
+    , foreign key (scheduledcustomerid) references scheduled_customers (scheduledcustomerid) on delete cascade
+    , foreign key (crewid) references crews (crewid) on delete cascade
+);
+
+create index scheduled_customer_crews_crewid_idx on scheduled_customer_crews (crewid);
+
+create table scheduled_customer_employees (
+      scheduledcustomerid   int4    not null
+    , employeeid            int4    not null
+
+    , constraint scheduled_customer_employees_pk primary key (scheduledcustomerid, employeeid)
+    , foreign key (scheduledcustomerid) references scheduled_customers (scheduledcustomerid) on delete cascade
+    , foreign key (employeeid) references employees (employeeid) on delete cascade
+);
+
+create index scheduled_customer_employees_employeeid_idx on scheduled_customer_employees (employeeid);
@@ -0,0 +1,17 @@
+alter table scheduled_customer_employees drop constraint employee_not_double_booked;
+alter table scheduled_customer_crews drop constraint crew_not_double_booked;
+
+drop trigger scheduled_customers_assignments_during on scheduled_customers;
+drop function update_assignments_during();
+
+drop trigger scheduled_customer_employees_during on scheduled_customer_employees;
+drop trigger scheduled_customer_crews_during on scheduled_customer_crews;
+drop function set_assignment_during();
+
+alter table scheduled_customer_employees drop column during;
+alter table scheduled_customer_crews drop column during;
+
+-- btree_gist is left installed; other objects may have come to rely on it
+
+alter table scheduled_customers drop constraint scheduled_customer_day_offset;
+alter table scheduled_customers drop constraint scheduled_customer_window;
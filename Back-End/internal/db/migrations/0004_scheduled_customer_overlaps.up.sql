@@ -0,0 +1,72 @@
+-- not valid: rows from before the API checked visits are left alone, new and edited ones are checked
+alter table scheduled_customers
+    add constraint scheduled_customer_window check (start_time < end_time) not valid;
+
+alter table scheduled_customers
+    add constraint scheduled_customer_day_offset check (day_offset between 0 and 6) not valid;
+
+-- lets the exclusion constraints below compare the integer IDs with =
+-- public, rather than the first schema on the search path, so that it is not dropped along with another schema
+create extension if not exists btree_gist with schema public;
+
+-- Assignments carry the time of their visit, so that Postgres can refuse to book a crew, or an
+-- employee assigned on their own, for two visits at once. The triggers keep the copies in sync.
+-- Employees at a visit through a crew are only checked by the API.
+alter table scheduled_customer_crews add column during tstzrange;
+alter table scheduled_customer_employees add column during tstzrange;
+
+update scheduled_customer_crews
+   set during = tstzrange(scheduled_customers.start_time, scheduled_customers.end_time)
+  from scheduled_customers
+ where scheduled_customers.scheduledcustomerid = scheduled_customer_crews.scheduledcustomerid;
+
+update scheduled_customer_employees
+   set during = tstzrange(scheduled_customers.start_time, scheduled_customers.end_time)
+  from scheduled_customers
+ where scheduled_customers.scheduledcustomerid = scheduled_customer_employees.scheduledcustomerid;
+
+alter table scheduled_customer_crews alter column during set not null;
+alter table scheduled_customer_employees alter column during set not null;
+
+create function set_assignment_during() returns trigger as $$
+begin
+    select tstzrange(start_time, end_time)
+      into new.during
+      from scheduled_customers
+     where scheduledcustomerid = new.scheduledcustomerid;
+
+    return new;
+end;
+$$ language plpgsql;
+
+create trigger scheduled_customer_crews_during
+    before insert or update on scheduled_customer_crews
+    for each row execute function set_assignment_during();
+
+create trigger scheduled_customer_employees_during
+    before insert or update on scheduled_customer_employees
+    for each row execute function set_assignment_during();
+
+create function update_assignments_during() returns trigger as $$
+begin
+    update scheduled_customer_crews
+       set during = tstzrange(new.start_time, new.end_time)
+     where scheduledcustomerid = new.scheduledcustomerid;
+
+    update scheduled_customer_employees
+       set during = tstzrange(new.start_time, new.end_time)
+     where scheduledcustomerid = new.scheduledcustomerid;
+
+    return new;
+end;
+$$ language plpgsql;
+
+create trigger scheduled_customers_assignments_during
+    after update of start_time, end_time on scheduled_customers
+    for each row execute function update_assignments_during();
+
+alter table scheduled_customer_crews
+    add constraint crew_not_double_booked exclude using gist (crewid with =, during with &&);
+
+alter table scheduled_customer_employees
+    add constraint employee_not_double_booked exclude using gist (employeeid with =, during with &&);
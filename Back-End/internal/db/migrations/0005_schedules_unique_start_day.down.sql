@@ -0,0 +1 @@
+alter table schedules drop constraint schedules_userid_start_day_key;
@@ -0,0 +1,5 @@
+-- one schedule per user and week; the API checks this too, but two requests at once could both pass the check.
+-- Users with duplicate schedules from before have to merge or delete them before this migration can run.
+-- Days are not checked to be the start of a week here, since the day weeks start on is configured in the API.
+alter table schedules
+    add constraint schedules_userid_start_day_key unique (userid, start_day);
@@ -0,0 +1,2 @@
+alter table invitations drop column accountid;
+alter table users drop column accountid;
@@ -0,0 +1,29 @@
+-- users share the schedules, recurring services, employees and crews of an account. The account is keyed by the
+-- user who holds it, so existing rows do not move: every user from before holds their own account.
+alter table users
+    add column accountid int4; -- null when the user holds their own account
+
+alter table users
+    add foreign key (accountid) references users (userid);
+
+-- the account that whoever accepts the invitation joins
+alter table invitations
+    add column accountid int4;
+
+update invitations
+   set accountid = coalesce(inviter.accountid, inviter.userid)
+  from users inviter
+ where inviter.userid = invitations.invited_by;
+
+-- invitations whose inviter was deleted join the first owner's account
+update invitations
+   set accountid = (select coalesce(accountid, userid) from users where role = 'owner' order by userid limit 1)
+ where accountid is null;
+
+delete from invitations where accountid is null;
+
+alter table invitations
+    alter column accountid set not null;
+
+alter table invitations
+    add foreign key (accountid) references users (userid) on delete cascade;
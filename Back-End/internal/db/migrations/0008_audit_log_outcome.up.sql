@@ -0,0 +1,7 @@
+-- Wave changes can not be rolled back with the request, so they are recorded as pending before Wave is called, and
+-- marked once it answered. Entries left pending are changes that Wave may or may not have made.
+alter table audit_log
+    add column outcome varchar(16) not null default 'succeeded';
+
+alter table audit_log
+    add constraint valid_outcome check (outcome in ('pending', 'succeeded', 'failed'));
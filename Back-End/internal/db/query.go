@@ -0,0 +1,183 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// A tiny query builder for the WHERE, ORDER BY and LIMIT clauses of SELECTs.
+// Column names are never taken from callers: they are declared once per table with NewColumn,
+// and the type parameter E (the entity the table stores) keeps columns of one table
+// from being used in queries on another. Values always become placeholders.
+
+var columnName = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// A column of the table that entities of type E are stored in.
+type Column[E any] struct {
+	name string
+}
+
+// Declares a column. Panics if the name is not a plain, lowercase SQL identifier.
+func NewColumn[E any](name string) Column[E] {
+	if !columnName.MatchString(name) {
+		panic(fmt.Sprintf("invalid column name %q", name))
+	}
+
+	return Column[E]{name: name}
+}
+
+func (c Column[E]) Name() string {
+	return c.name
+}
+
+// A condition on a column, e.g. `start_day >= $1`.
+type Condition[E any] struct {
+	column   string
+	operator string
+	values   []any
+}
+
+func (c Column[E]) Eq(value any) Condition[E] {
+	return Condition[E]{column: c.name, operator: "=", values: []any{value}}
+}
+
+func (c Column[E]) NotEq(value any) Condition[E] {
+	return Condition[E]{column: c.name, operator: "<>", values: []any{value}}
+}
+
+func (c Column[E]) Lt(value any) Condition[E] {
+	return Condition[E]{column: c.name, operator: "<", values: []any{value}}
+}
+
+func (c Column[E]) Lte(value any) Condition[E] {
+	return Condition[E]{column: c.name, operator: "<=", values: []any{value}}
+}
+
+func (c Column[E]) Gt(value any) Condition[E] {
+	return Condition[E]{column: c.name, operator: ">", values: []any{value}}
+}
+
+func (c Column[E]) Gte(value any) Condition[E] {
+	return Condition[E]{column: c.name, operator: ">=", values: []any{value}}
+}
+
+// Matches values in the half-open range [from, until).
+func (c Column[E]) Range(from any, until any) Condition[E] {
+	return Condition[E]{column: c.name, operator: "RANGE", values: []any{from, until}}
+}
+
+// Matches any of the values. Matches nothing when no values are given.
+func (c Column[E]) In(values ...any) Condition[E] {
+	return Condition[E]{column: c.name, operator: "IN", values: values}
+}
+
+func (c Column[E]) IsNull() Condition[E] {
+	return Condition[E]{column: c.name, operator: "IS NULL"}
+}
+
+type ordering struct {
+	column     string
+	descending bool
+}
+
+// The conditions (all of which must hold), ordering and limit of a SELECT on entities of type E.
+// Queries are values: every method returns a new query and leaves the receiver untouched.
+type Query[E any] struct {
+	conditions []Condition[E]
+	orderBy    []ordering
+	limit      int
+}
+
+// Starts a query with the given conditions.
+func Where[E any](conditions ...Condition[E]) Query[E] {
+	return Query[E]{conditions: conditions}
+}
+
+// Adds conditions to the query.
+func (q Query[E]) And(conditions ...Condition[E]) Query[E] {
+	q.conditions = append(slices.Clip(q.conditions), conditions...)
+	return q
+}
+
+// Orders the results by a column, ascending. Later orderings break ties of earlier ones.
+func (q Query[E]) OrderBy(column Column[E]) Query[E] {
+	q.orderBy = append(slices.Clip(q.orderBy), ordering{column: column.name})
+	return q
+}
+
+// Orders the results by a column, descending. Later orderings break ties of earlier ones.
+func (q Query[E]) OrderByDesc(column Column[E]) Query[E] {
+	q.orderBy = append(slices.Clip(q.orderBy), ordering{column: column.name, descending: true})
+	return q
+}
+
+// Returns at most n results.
+func (q Query[E]) Limit(n int) Query[E] {
+	q.limit = n
+	return q
+}
+
+// Builds `SELECT <columns> FROM <table> ...` for the query, and the arguments of its placeholders.
+// table and columns must be constants of the data package, never user input.
+func (q Query[E]) Select(table string, columns string) (string, []any) {
+	var sql strings.Builder
+	var args []any
+
+	placeholder := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	fmt.Fprintf(&sql, "SELECT %v FROM %v", columns, table)
+
+	for i, condition := range q.conditions {
+		if i == 0 {
+			sql.WriteString(" WHERE ")
+		} else {
+			sql.WriteString(" AND ")
+		}
+
+		switch condition.operator {
+		case "IS NULL":
+			fmt.Fprintf(&sql, "%v IS NULL", condition.column)
+		case "RANGE":
+			from, until := placeholder(condition.values[0]), placeholder(condition.values[1])
+			fmt.Fprintf(&sql, "(%v >= %v AND %v < %v)", condition.column, from, condition.column, until)
+		case "IN":
+			if len(condition.values) == 0 {
+				sql.WriteString("false")
+				continue
+			}
+
+			placeholders := make([]string, len(condition.values))
+			for j, value := range condition.values {
+				placeholders[j] = placeholder(value)
+			}
+
+			fmt.Fprintf(&sql, "%v IN (%v)", condition.column, strings.Join(placeholders, ", "))
+		default:
+			fmt.Fprintf(&sql, "%v %v %v", condition.column, condition.operator, placeholder(condition.values[0]))
+		}
+	}
+
+	for i, order := range q.orderBy {
+		if i == 0 {
+			sql.WriteString(" ORDER BY ")
+		} else {
+			sql.WriteString(", ")
+		}
+
+		sql.WriteString(order.column)
+		if order.descending {
+			sql.WriteString(" DESC")
+		}
+	}
+
+	if q.limit > 0 {
+		fmt.Fprintf(&sql, " LIMIT %d", q.limit)
+	}
+
+	return sql.String(), args
+}
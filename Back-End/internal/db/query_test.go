@@ -0,0 +1,64 @@
+package db
+
+import (
+	"fmt"
+	"prime-shine-api/internal/assert"
+	"testing"
+)
+
+type testVisit struct{}
+
+type testUser struct{}
+
+var (
+	visitScheduleID = NewColumn[testVisit]("scheduleid")
+	visitDayOffset  = NewColumn[testVisit]("day_offset")
+	visitStartTime  = NewColumn[testVisit]("start_time")
+
+	userID    = NewColumn[testUser]("userid")
+	userName  = NewColumn[testUser]("name")
+	userEmail = NewColumn[testUser]("email")
+	userRole  = NewColumn[testUser]("role")
+)
+
+func TestQuerySelect(t *testing.T) {
+	query := Where(visitScheduleID.Eq(3)).
+		And(visitDayOffset.In(1, 2), visitStartTime.Range("2025-08-11", "2025-08-18")).
+		OrderBy(visitDayOffset).
+		OrderByDesc(visitStartTime).
+		Limit(10)
+
+	statement, args := query.Select("scheduled_customers", "*")
+
+	expected := "SELECT * FROM scheduled_customers" +
+		" WHERE scheduleid = $1 AND day_offset IN ($2, $3) AND (start_time >= $4 AND start_time < $5)" +
+		" ORDER BY day_offset, start_time DESC LIMIT 10"
+
+	assert.Equal(t, statement, expected)
+	assert.Equal(t, fmt.Sprint(args), "[3 1 2 2025-08-11 2025-08-18]")
+}
+
+func TestQuerySelectEmptyIn(t *testing.T) {
+	query := Where(userID.In())
+
+	statement, args := query.Select("users", "*")
+
+	assert.Equal(t, statement, "SELECT * FROM users WHERE false")
+	assert.Equal(t, len(args), 0)
+}
+
+func TestQueryIsImmutable(t *testing.T) {
+	base := Where(userRole.Eq("owner"))
+
+	byEmail := base.And(userEmail.Eq("bob@fakeuser.com"))
+	byName := base.And(userName.Eq("Bob"))
+
+	statement, _ := base.Select("users", "*")
+	assert.Equal(t, statement, "SELECT * FROM users WHERE role = $1")
+
+	statement, _ = byEmail.Select("users", "*")
+	assert.Equal(t, statement, "SELECT * FROM users WHERE role = $1 AND email = $2")
+
+	statement, _ = byName.Select("users", "*")
+	assert.Equal(t, statement, "SELECT * FROM users WHERE role = $1 AND name = $2")
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// The table that entities of type E are stored in.
+// Its columns are the `db` tags of E's fields, the same ones sqlx scans rows into,
+// so reads and writes always select every field of E, and nothing E can not hold.
+type Table[E any] struct {
+	name    string
+	columns []string
+}
+
+// Declares the table that entities of type E are stored in.
+// Panics if E is not a struct with `db` tags.
+func NewTable[E any](name string) Table[E] {
+	entity := reflect.TypeFor[E]()
+	if entity.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("table %v: %v is not a struct", name, entity))
+	}
+
+	columns := []string{}
+	for i := 0; i < entity.NumField(); i++ {
+		column := entity.Field(i).Tag.Get("db")
+		if column == "" || column == "-" {
+			continue
+		}
+
+		columns = append(columns, column)
+	}
+
+	if len(columns) == 0 {
+		panic(fmt.Sprintf("table %v: %v has no db tags", name, entity))
+	}
+
+	return Table[E]{name: name, columns: columns}
+}
+
+func (t Table[E]) Name() string {
+	return t.name
+}
+
+// The table's columns, comma separated, e.g. for a SELECT or a RETURNING clause.
+func (t Table[E]) Columns() string {
+	return strings.Join(t.columns, ", ")
+}
+
+// Declares a column that queries on the table can use.
+// Panics if E has no field for it.
+func (t Table[E]) Column(name string) Column[E] {
+	if !slices.Contains(t.columns, name) {
+		panic(fmt.Sprintf("table %v has no column %q", t.name, name))
+	}
+
+	return NewColumn[E](name)
+}
+
+// Builds a SELECT of every column of the table for the query.
+func (t Table[E]) Select(query Query[E]) (string, []any) {
+	return query.Select(t.name, t.Columns())
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// How long a database operation may run when it has no timeout of its own.
+const DEFAULT_QUERY_TIMEOUT = 5 * time.Second
+
+// How long database operations may run before they are cancelled.
+type QueryTimeouts struct {
+	Default time.Duration
+	// Keyed by operation name, which is the name of the function in the data package.
+	Operations map[string]time.Duration
+}
+
+var queryTimeouts atomic.Pointer[QueryTimeouts]
+
+// Makes a set of timeouts the one that WithQueryTimeout applies.
+func SetQueryTimeouts(timeouts QueryTimeouts) {
+	queryTimeouts.Store(&timeouts)
+}
+
+// Parses per-operation timeouts, e.g. "QueryAuditLog=15s,QueryLoginEvents=10s".
+func ParseQueryTimeouts(spec string) (map[string]time.Duration, error) {
+	operations := map[string]time.Duration{}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		operation, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, errors.Errorf("expected operation=duration, got %q", entry)
+		}
+
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Wrapf(err, "timeout of %v", operation)
+		}
+
+		if timeout <= 0 {
+			return nil, errors.Errorf("timeout of %v must be positive", operation)
+		}
+
+		operations[strings.TrimSpace(operation)] = timeout
+	}
+
+	return operations, nil
+}
+
+// Returns how long an operation may run for.
+func QueryTimeout(operation string) time.Duration {
+	timeouts := queryTimeouts.Load()
+	if timeouts == nil {
+		return DEFAULT_QUERY_TIMEOUT
+	}
+
+	if timeout, ok := timeouts.Operations[operation]; ok {
+		return timeout
+	}
+
+	if timeouts.Default > 0 {
+		return timeouts.Default
+	}
+
+	return DEFAULT_QUERY_TIMEOUT
+}
+
+// Derives a context for the queries of a database operation, which is cancelled once
+// the operation's timeout passes. The returned cancel func must be called when the operation is done.
+func WithQueryTimeout(ctx context.Context, operation string) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, QueryTimeout(operation))
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"prime-shine-api/internal/assert"
+	"testing"
+	"time"
+)
+
+func TestQueryTimeoutsFlag(t *testing.T) {
+	operations, err := ParseQueryTimeouts("QueryAuditLog=15s, QueryLoginEvents=500ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	SetQueryTimeouts(QueryTimeouts{Default: 2 * time.Second, Operations: operations})
+	t.Cleanup(func() { SetQueryTimeouts(QueryTimeouts{}) })
+
+	assert.Equal(t, QueryTimeout("QueryAuditLog"), 15*time.Second)
+	assert.Equal(t, QueryTimeout("QueryLoginEvents"), 500*time.Millisecond)
+	assert.Equal(t, QueryTimeout("FindOneUser"), 2*time.Second)
+}
+
+func TestQueryTimeoutsFlagInvalid(t *testing.T) {
+	specs := []string{
+		"QueryAuditLog",
+		"QueryAuditLog=soon",
+		"QueryAuditLog=-1s",
+	}
+
+	for _, spec := range specs {
+		t.Run(spec, func(t *testing.T) {
+			_, err := ParseQueryTimeouts(spec)
+			assert.NotEqual(t, err, nil)
+		})
+	}
+}
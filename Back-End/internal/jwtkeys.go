@@ -0,0 +1,251 @@
+package internal
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+)
+
+// A key that tokens are signed or verified with.
+type JWTKey struct {
+	// Empty for the shared HMAC secret, which predates key IDs.
+	ID     string
+	Method jwt.SigningMethod
+
+	signingKey      any
+	verificationKey any
+}
+
+// The key that new tokens are signed with, and every key that tokens are still accepted from.
+type JWTKeySet struct {
+	signing      *JWTKey
+	verification map[string]*JWTKey
+}
+
+// A public key in JSON Web Key format.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+var jwtKeys atomic.Pointer[JWTKeySet]
+
+// Makes a key set the one that tokens are created and verified with.
+func SetJWTKeys(keys *JWTKeySet) {
+	jwtKeys.Store(keys)
+}
+
+// Falls back to the shared HMAC secret when no key set was configured.
+func currentJWTKeys() *JWTKeySet {
+	keys := jwtKeys.Load()
+	if keys == nil {
+		return NewHMACKeySet(getJWTSecret())
+	}
+
+	return keys
+}
+
+// Creates a key set that signs and verifies tokens with a shared HMAC secret.
+func NewHMACKeySet(secret []byte) *JWTKeySet {
+	key := &JWTKey{
+		Method:          jwt.SigningMethodHS256,
+		signingKey:      secret,
+		verificationKey: secret,
+	}
+
+	return &JWTKeySet{
+		signing:      key,
+		verification: map[string]*JWTKey{key.ID: key},
+	}
+}
+
+// Loads the key set from the files named in the environment.
+//
+// JWT_SIGNING_KEY_FILE is a PEM encoded RSA or Ed25519 private key that new tokens are signed with.
+// JWT_VERIFICATION_KEY_FILES is a comma separated list of PEM encoded keys that are still accepted,
+// e.g. the previous signing key while its tokens expire.
+// If JWT_TOKEN is set, tokens signed with it are accepted too, and it is used for signing
+// when no signing key file is given.
+func LoadJWTKeys() (*JWTKeySet, error) {
+	keys := &JWTKeySet{verification: map[string]*JWTKey{}}
+
+	if secret := getJWTSecret(); len(secret) > 0 {
+		keys = NewHMACKeySet(secret)
+	}
+
+	if path := os.Getenv("JWT_SIGNING_KEY_FILE"); path != "" {
+		key, err := readJWTKeyFile(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "readJWTKeyFile")
+		}
+
+		if key.signingKey == nil {
+			return nil, errors.Errorf("%v does not contain a private key", path)
+		}
+
+		keys.signing = key
+		keys.verification[key.ID] = key
+	}
+
+	for _, path := range strings.Split(os.Getenv("JWT_VERIFICATION_KEY_FILES"), ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		key, err := readJWTKeyFile(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "readJWTKeyFile")
+		}
+
+		keys.verification[key.ID] = key
+	}
+
+	if keys.signing == nil {
+		return nil, errors.New("JWT_SIGNING_KEY_FILE or JWT_TOKEN must be set")
+	}
+
+	return keys, nil
+}
+
+func readJWTKeyFile(path string) (*JWTKey, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "ReadFile")
+	}
+
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return nil, errors.Errorf("%v is not PEM encoded", path)
+	}
+
+	key, err := parseJWTKey(block)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parseJWTKey %v", path)
+	}
+
+	return key, nil
+}
+
+func parseJWTKey(block *pem.Block) (*JWTKey, error) {
+	var signingKey crypto.Signer
+	var verificationKey any
+	var err error
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		verificationKey, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		signingKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		var parsed any
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err == nil {
+			var ok bool
+			signingKey, ok = parsed.(crypto.Signer)
+			if !ok {
+				return nil, errors.Errorf("unsupported private key type %T", parsed)
+			}
+		}
+	default:
+		return nil, errors.Errorf("unsupported PEM block %v", block.Type)
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "x509")
+	}
+
+	if signingKey != nil {
+		verificationKey = signingKey.Public()
+	}
+
+	key := &JWTKey{signingKey: signingKey, verificationKey: verificationKey}
+
+	switch verificationKey.(type) {
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, errors.Errorf("unsupported key type %T", verificationKey)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(verificationKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "MarshalPKIXPublicKey")
+	}
+
+	// Derived from the public key, so the same key file always gets the same ID.
+	digest := sha256.Sum256(der)
+	key.ID = base64.RawURLEncoding.EncodeToString(digest[:16])
+
+	return key, nil
+}
+
+// Looks up the key that a token claims to be signed with.
+func (s *JWTKeySet) verificationKeyFor(token *jwt.Token) (*JWTKey, bool) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.verification[kid]
+	if !ok || key.Method.Alg() != token.Method.Alg() {
+		return nil, false
+	}
+
+	return key, true
+}
+
+// The public keys of the key set. Shared HMAC secrets are never published.
+func (s *JWTKeySet) JWKS() []JWK {
+	jwks := []JWK{}
+
+	for _, key := range s.verification {
+		switch publicKey := key.verificationKey.(type) {
+		case *rsa.PublicKey:
+			jwks = append(jwks, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Method.Alg(),
+				N:         base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks = append(jwks, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Method.Alg(),
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(publicKey),
+			})
+		}
+	}
+
+	slices.SortFunc(jwks, func(a, b JWK) int {
+		return strings.Compare(a.KeyID, b.KeyID)
+	})
+
+	return jwks
+}
+
+// The public keys that tokens are currently verified with.
+func JWKS() []JWK {
+	return currentJWTKeys().JWKS()
+}
@@ -1,8 +1,10 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -10,28 +12,87 @@ import (
 )
 
 const JWT_ISSUER = "prime-shine-api"
+const JWT_AUDIENCE = "prime-shine-api"
+
+// Machine-readable reasons for rejecting a token, sent to clients alongside the 401.
+const (
+	TOKEN_ERROR_MISSING         = "token_missing"
+	TOKEN_ERROR_MALFORMED       = "token_malformed"
+	TOKEN_ERROR_SIGNATURE       = "token_signature_invalid"
+	TOKEN_ERROR_EXPIRED         = "token_expired"
+	TOKEN_ERROR_NOT_YET_VALID   = "token_not_yet_valid"
+	TOKEN_ERROR_INVALID_CLAIMS  = "token_claims_invalid"
+	TOKEN_ERROR_SESSION_REVOKED = "session_revoked"
+)
+
+// Reports whether a session has been revoked (or no longer exists).
+type SessionRevokedFunc func(ctx context.Context, sessionID int) (bool, error)
+
+// Identifies the user session that a token was issued for.
+type TokenSession struct {
+	UserID    string
+	AccountID string
+	Role      string
+	SessionID int
+}
+
+// The claims carried by our tokens.
+type TokenClaims struct {
+	jwt.RegisteredClaims
+	SessionID string `json:"sid"`
+	AccountID string `json:"acc"`
+	Role      string `json:"role"`
+}
+
+// Returned when a token is rejected because of something the client sent.
+type TokenError struct {
+	Code string
+	err  error
+}
+
+func (e *TokenError) Error() string {
+	if e.err == nil {
+		return e.Code
+	}
+
+	return fmt.Sprintf("%v: %v", e.Code, e.err.Error())
+}
+
+func (e *TokenError) Unwrap() error {
+	return e.err
+}
 
 func getJWTSecret() []byte {
 	return []byte(os.Getenv("JWT_TOKEN"))
 }
 
 // Creates a signed JSON Web Token for a user session.
-func CreateToken(userID string) (string, error) {
-	jwtKey := getJWTSecret()
+// accountID is the account whose data the user works on, which is not always their own.
+func CreateToken(userID string, accountID string, role string, sessionID int) (string, error) {
+	key := currentJWTKeys().signing
 	currentTime := jwt.NewNumericDate(time.Now())
 	expirationDate := jwt.NewNumericDate(time.Now().Add(4 * time.Hour))
 
-	claims := jwt.MapClaims{
-		"sub": userID,
-		"iat": currentTime,
-		"nbf": currentTime,
-		"exp": expirationDate,
-		"iss": JWT_ISSUER,
-		"aud": []string{JWT_ISSUER},
+	claims := TokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			IssuedAt:  currentTime,
+			NotBefore: currentTime,
+			ExpiresAt: expirationDate,
+			Issuer:    JWT_ISSUER,
+			Audience:  jwt.ClaimStrings{JWT_AUDIENCE},
+		},
+		SessionID: strconv.Itoa(sessionID),
+		AccountID: accountID,
+		Role:      role,
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedJWT, err := token.SignedString(jwtKey)
+	signedJWT, err := token.SignedString(key.signingKey)
 	if err != nil {
 		return "", errors.Wrap(err, "SignedString")
 	}
@@ -39,40 +100,80 @@ func CreateToken(userID string) (string, error) {
 	return signedJWT, err
 }
 
-func verifyHelper(token *jwt.Token) (interface{}, error) {
-	if token.Method != jwt.SigningMethodHS256 {
-		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+func verifyHelper(keys *JWTKeySet) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		key, ok := keys.verificationKeyFor(token)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %v for method %v", token.Header["kid"], token.Header["alg"])
+		}
+
+		return key.verificationKey, nil
 	}
+}
 
-	jwtKey := getJWTSecret()
-	return jwtKey, nil
+// Maps a jwt parsing error to the reason reported to the client.
+func tokenErrorCode(err error) string {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return TOKEN_ERROR_EXPIRED
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return TOKEN_ERROR_NOT_YET_VALID
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return TOKEN_ERROR_SIGNATURE
+	case errors.Is(err, jwt.ErrTokenInvalidAudience),
+		errors.Is(err, jwt.ErrTokenInvalidIssuer),
+		errors.Is(err, jwt.ErrTokenRequiredClaimMissing),
+		errors.Is(err, jwt.ErrTokenInvalidClaims):
+		return TOKEN_ERROR_INVALID_CLAIMS
+	default:
+		return TOKEN_ERROR_MALFORMED
+	}
 }
 
-// Verifies a JSON Web Token.
-func VerifyToken(tokenStr string) (bool, error) {
-	token, err := jwt.Parse(tokenStr, verifyHelper, jwt.WithoutClaimsValidation())
+// Verifies a JSON Web Token and returns the session that it was issued for.
+// If the token is rejected because of the client, a *TokenError is returned.
+// Any other error means the token could not be checked.
+func VerifyToken(ctx context.Context, tokenStr string, isSessionRevoked SessionRevokedFunc) (*TokenSession, error) {
+	claims := &TokenClaims{}
+	_, err := jwt.ParseWithClaims(
+		tokenStr,
+		claims,
+		verifyHelper(currentJWTKeys()),
+		jwt.WithIssuer(JWT_ISSUER),
+		jwt.WithAudience(JWT_AUDIENCE),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+
 	if err != nil {
-		return false, errors.Wrap(err, "jwt.Parse")
+		return nil, &TokenError{Code: tokenErrorCode(err), err: err}
 	}
 
-	if issuer, err := token.Claims.GetIssuer(); err != nil || issuer != JWT_ISSUER {
-		return false, errors.Wrap(err, "GetIssuer")
+	if claims.Subject == "" {
+		return nil, &TokenError{Code: TOKEN_ERROR_INVALID_CLAIMS, err: errors.New("missing sub")}
 	}
 
-	nbt, err := token.Claims.GetNotBefore()
+	sessionID, err := strconv.Atoi(claims.SessionID)
 	if err != nil {
-		return false, errors.Wrap(err, "GetNotBefore")
+		return nil, &TokenError{Code: TOKEN_ERROR_INVALID_CLAIMS, err: errors.Wrap(err, "sid")}
+	}
+
+	if claims.AccountID == "" {
+		return nil, &TokenError{Code: TOKEN_ERROR_INVALID_CLAIMS, err: errors.New("missing acc")}
+	}
+
+	if !IsValidRole(claims.Role) {
+		return nil, &TokenError{Code: TOKEN_ERROR_INVALID_CLAIMS, err: errors.Errorf("unknown role %v", claims.Role)}
 	}
 
-	exp, err := token.Claims.GetExpirationTime()
+	revoked, err := isSessionRevoked(ctx, sessionID)
 	if err != nil {
-		return false, errors.Wrap(err, "GetExpirationTime")
+		return nil, errors.Wrap(err, "isSessionRevoked")
 	}
 
-	currentTime := time.Now()
-	if nbt.After(currentTime) || exp.Before(currentTime) {
-		return false, nil
+	if revoked {
+		return nil, &TokenError{Code: TOKEN_ERROR_SESSION_REVOKED}
 	}
 
-	return token.Valid, nil
+	return &TokenSession{UserID: claims.Subject, AccountID: claims.AccountID, Role: claims.Role, SessionID: sessionID}, nil
 }
@@ -0,0 +1,63 @@
+package internal
+
+import "time"
+
+// Describes how aggressively repeated failed logins are slowed down.
+type LoginThrottlePolicy struct {
+	// Failed attempts allowed before any delay kicks in.
+	FreeAttempts int
+	// Delay after the first throttled attempt; doubles with every further failure.
+	BaseDelay time.Duration
+	// Failed attempts after which the client is locked out for LockoutDuration.
+	LockoutAttempts int
+	// How long a lockout lasts. This is also the window in which failures are counted.
+	LockoutDuration time.Duration
+}
+
+// Throttling applied to a single account (keyed by email).
+var ACCOUNT_LOGIN_THROTTLE = LoginThrottlePolicy{
+	FreeAttempts:    3,
+	BaseDelay:       time.Second,
+	LockoutAttempts: 10,
+	LockoutDuration: 15 * time.Minute,
+}
+
+// Throttling applied to a single client IP address, across all accounts.
+var IP_LOGIN_THROTTLE = LoginThrottlePolicy{
+	FreeAttempts:    10,
+	BaseDelay:       time.Second,
+	LockoutAttempts: 50,
+	LockoutDuration: 15 * time.Minute,
+}
+
+// Computes the delay that must pass after the last failed attempt before another login attempt is allowed.
+func (p LoginThrottlePolicy) Delay(failedAttempts int) time.Duration {
+	if failedAttempts >= p.LockoutAttempts {
+		return p.LockoutDuration
+	}
+
+	if failedAttempts < p.FreeAttempts {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for range failedAttempts - p.FreeAttempts {
+		delay *= 2
+		if delay >= p.LockoutDuration {
+			return p.LockoutDuration
+		}
+	}
+
+	return delay
+}
+
+// Computes how long the client has to wait before another login attempt, given its recent failures.
+// A zero duration means that the attempt is allowed.
+func (p LoginThrottlePolicy) RetryAfter(failedAttempts int, lastFailure time.Time, now time.Time) time.Duration {
+	wait := lastFailure.Add(p.Delay(failedAttempts)).Sub(now)
+	if wait < 0 {
+		return 0
+	}
+
+	return wait
+}
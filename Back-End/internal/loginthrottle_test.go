@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"prime-shine-api/internal/assert"
+	"testing"
+	"time"
+)
+
+var testLoginThrottle = LoginThrottlePolicy{
+	FreeAttempts:    3,
+	BaseDelay:       time.Second,
+	LockoutAttempts: 10,
+	LockoutDuration: 15 * time.Minute,
+}
+
+func TestLoginThrottleDelay(t *testing.T) {
+	tests := []struct {
+		name           string
+		policy         LoginThrottlePolicy
+		failedAttempts int
+		want           time.Duration
+	}{
+		{"no failures", testLoginThrottle, 0, 0},
+		{"last free attempt", testLoginThrottle, 2, 0},
+		{"first throttled attempt", testLoginThrottle, 3, time.Second},
+		{"delay doubles", testLoginThrottle, 4, 2 * time.Second},
+		{"last attempt before lockout", testLoginThrottle, 9, 64 * time.Second},
+		{"lockout", testLoginThrottle, 10, 15 * time.Minute},
+		{"past lockout", testLoginThrottle, 25, 15 * time.Minute},
+		{
+			"delay is capped at the lockout",
+			LoginThrottlePolicy{FreeAttempts: 0, BaseDelay: time.Minute, LockoutAttempts: 100, LockoutDuration: 15 * time.Minute},
+			4,
+			15 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.policy.Delay(tt.failedAttempts), tt.want)
+		})
+	}
+}
+
+func TestLoginThrottleRetryAfter(t *testing.T) {
+	now := time.Date(2025, 8, 11, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		failedAttempts int
+		lastFailure    time.Time
+		want           time.Duration
+	}{
+		{"free attempts", 2, now, 0},
+		{"delay still running", 3, now.Add(-300 * time.Millisecond), 700 * time.Millisecond},
+		{"delay has passed", 3, now.Add(-2 * time.Second), 0},
+		{"delay ends now", 4, now.Add(-2 * time.Second), 0},
+		{"locked out", 10, now.Add(-5 * time.Minute), 10 * time.Minute},
+		{"lockout has passed", 10, now.Add(-20 * time.Minute), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, testLoginThrottle.RetryAfter(tt.failedAttempts, tt.lastFailure, now), tt.want)
+		})
+	}
+}
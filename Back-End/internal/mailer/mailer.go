@@ -0,0 +1,6 @@
+package mailer
+
+// Mailer sends plaintext emails.
+type Mailer interface {
+	Send(to string, subject string, body string) error
+}
@@ -0,0 +1,78 @@
+package mailer
+
+import (
+	"fmt"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SMTPMailer sends emails through an SMTP relay.
+type SMTPMailer struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+}
+
+// Creates an SMTP mailer configured from the SMTP_* environment variables.
+func NewSMTPMailer() (*SMTPMailer, error) {
+	port := 587
+	if portStr := os.Getenv("SMTP_PORT"); portStr != "" {
+		parsedPort, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing SMTP_PORT")
+		}
+
+		port = parsedPort
+	}
+
+	mailer := &SMTPMailer{
+		host:     os.Getenv("SMTP_HOST"),
+		port:     port,
+		username: os.Getenv("SMTP_USERNAME"),
+		password: os.Getenv("SMTP_PASSWORD"),
+		from:     os.Getenv("SMTP_FROM"),
+	}
+
+	return mailer, nil
+}
+
+// Sends an email through the SMTP relay.
+func (m *SMTPMailer) Send(to string, subject string, body string) error {
+	// a line break would let the value add headers of its own, e.g. a Bcc
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return errors.New("recipient and subject can not contain line breaks")
+	}
+
+	if m.host == "" {
+		return errors.New("SMTP_HOST is not configured")
+	}
+
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	headers := []string{
+		fmt.Sprintf("From: %v", m.from),
+		fmt.Sprintf("To: %v", to),
+		fmt.Sprintf("Subject: %v", subject),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=\"utf-8\"",
+	}
+
+	message := strings.Join(headers, "\r\n") + "\r\n\r\n" + body
+	addr := fmt.Sprintf("%v:%v", m.host, m.port)
+
+	err := smtp.SendMail(addr, auth, m.from, []string{to}, []byte(message))
+	if err != nil {
+		return errors.Wrap(err, "smtp.SendMail")
+	}
+
+	return nil
+}
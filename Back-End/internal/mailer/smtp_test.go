@@ -0,0 +1,28 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSMTPMailerRejectsHeaderInjection(t *testing.T) {
+	mailer := &SMTPMailer{host: "smtp.invalid", port: 587}
+
+	tests := []struct {
+		name    string
+		to      string
+		subject string
+	}{
+		{"recipient", "someone@example.com\r\nBcc: everyone@example.com", "Hello"},
+		{"subject", "someone@example.com", "Hello\nBcc: everyone@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := mailer.Send(tt.to, tt.subject, "body")
+			if err == nil || !strings.Contains(err.Error(), "line breaks") {
+				t.Fatalf("expected a line break error, got %v", err)
+			}
+		})
+	}
+}
@@ -12,18 +12,45 @@ func getMockJWTSecret() []byte {
 	return []byte(os.Getenv("JWT_TOKEN"))
 }
 
+func WrongAudienceJWT(userID string) (string, error) {
+	jwtKey := getMockJWTSecret()
+	currentTime := jwt.NewNumericDate(time.Now())
+	expirationDate := jwt.NewNumericDate(time.Now().Add(time.Hour))
+
+	claims := jwt.MapClaims{
+		"sub":  userID,
+		"sid":  "1",
+		"role": "owner",
+		"iat":  currentTime,
+		"nbf":  currentTime,
+		"exp":  expirationDate,
+		"iss":  "prime-shine-api",
+		"aud":  []string{"some-other-api"},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signedJWT, err := token.SignedString(jwtKey)
+	if err != nil {
+		return "", errors.Wrap(err, "SignedString")
+	}
+
+	return signedJWT, err
+}
+
 func ExpiredJWT(userID string) (string, error) {
 	jwtKey := getMockJWTSecret()
 	currentTime := jwt.NewNumericDate(time.Now())
 	expirationDate := jwt.NewNumericDate(time.Now())
 
 	claims := jwt.MapClaims{
-		"sub": userID,
-		"iat": currentTime,
-		"nbf": currentTime,
-		"exp": expirationDate,
-		"iss": "prime-shine-api",
-		"aud": []string{"prime-shine-api"},
+		"sub":  userID,
+		"sid":  "1",
+		"role": "owner",
+		"iat":  currentTime,
+		"nbf":  currentTime,
+		"exp":  expirationDate,
+		"iss":  "prime-shine-api",
+		"aud":  []string{"prime-shine-api"},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -0,0 +1,19 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+func SessionNotRevoked(ctx context.Context, sessionID int) (bool, error) {
+	return false, nil
+}
+
+func SessionRevoked(ctx context.Context, sessionID int) (bool, error) {
+	return true, nil
+}
+
+func SessionLookupFails(ctx context.Context, sessionID int) (bool, error) {
+	return false, errors.New("database is unavailable")
+}
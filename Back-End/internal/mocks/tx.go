@@ -0,0 +1,47 @@
+package mocks
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+var errNoDatabase = errors.New("mock transaction has no database")
+
+// A transaction that only records whether it was committed or rolled back.
+type Tx struct {
+	CommitErr  error
+	Committed  bool
+	RolledBack bool
+}
+
+func (tx *Tx) Commit() error {
+	if tx.CommitErr != nil {
+		return tx.CommitErr
+	}
+
+	tx.Committed = true
+	return nil
+}
+
+func (tx *Tx) Rollback() error {
+	tx.RolledBack = true
+	return nil
+}
+
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return nil, errNoDatabase
+}
+
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return nil, errNoDatabase
+}
+
+func (tx *Tx) GetContext(ctx context.Context, dest interface{}, query string, args ...any) error {
+	return errNoDatabase
+}
+
+func (tx *Tx) SelectContext(ctx context.Context, dest interface{}, query string, args ...any) error {
+	return errNoDatabase
+}
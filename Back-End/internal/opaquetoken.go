@@ -0,0 +1,29 @@
+package internal
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+)
+
+// Generates a random, URL-safe token along with its hashed equivalent.
+// Only the hash should be persisted; the plaintext token is handed to the client.
+func GenerateOpaqueToken() (string, string, error) {
+	buf := make([]byte, 32)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", "", errors.Wrap(err, "rand.Read")
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashOpaqueToken(token), nil
+}
+
+// Hashes a token generated by GenerateOpaqueToken for storage and lookups.
+func HashOpaqueToken(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(digest[:])
+}
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// bcrypt ignores everything past the first 72 bytes of a password.
+const BCRYPT_MAX_PASSWORD_LENGTH = 72
+
+// Rules that new passwords must follow.
+type PasswordPolicy struct {
+	MinLength     int
+	RequireMixed  bool // both upper and lower case letters
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// Checks a new password against the policy.
+// The returned error describes every rule that was broken, and is safe to show to users.
+func (p PasswordPolicy) Validate(password string) error {
+	var problems []string
+
+	minLength := max(p.MinLength, 1)
+	if len([]rune(password)) < minLength {
+		problems = append(problems, fmt.Sprintf("be at least %v characters long", minLength))
+	}
+
+	if len(password) > BCRYPT_MAX_PASSWORD_LENGTH {
+		problems = append(problems, fmt.Sprintf("be at most %v bytes long", BCRYPT_MAX_PASSWORD_LENGTH))
+	}
+
+	hasUpper, hasLower, hasDigit, hasSymbol := false, false, false, false
+	for _, char := range password {
+		switch {
+		case unicode.IsUpper(char):
+			hasUpper = true
+		case unicode.IsLower(char):
+			hasLower = true
+		case unicode.IsDigit(char):
+			hasDigit = true
+		case unicode.IsPunct(char) || unicode.IsSymbol(char):
+			hasSymbol = true
+		}
+	}
+
+	if p.RequireMixed && !(hasUpper && hasLower) {
+		problems = append(problems, "contain both upper and lower case letters")
+	}
+
+	if p.RequireDigit && !hasDigit {
+		problems = append(problems, "contain a digit")
+	}
+
+	if p.RequireSymbol && !hasSymbol {
+		problems = append(problems, "contain a symbol")
+	}
+
+	if len(problems) > 0 {
+		return errors.Errorf("Password must %v.", strings.Join(problems, ", "))
+	}
+
+	return nil
+}
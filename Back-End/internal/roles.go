@@ -0,0 +1,18 @@
+package internal
+
+// Roles that a user can be assigned.
+const (
+	ROLE_OWNER          = "owner"
+	ROLE_OFFICE_MANAGER = "office_manager"
+	ROLE_CLEANER        = "cleaner"
+)
+
+// Checks if a role is one that a user can be assigned.
+func IsValidRole(role string) bool {
+	switch role {
+	case ROLE_OWNER, ROLE_OFFICE_MANAGER, ROLE_CLEANER:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,207 @@
+// Package testdb gives tests that need Postgres a schema of their own.
+// It is only imported by tests.
+package testdb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"prime-shine-api/internal/db"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+)
+
+// Tests that need a database get a fresh schema of their own, migrated to the latest version.
+//
+// The database is TEST_DATABASE_URL when it is set. Otherwise a throwaway Postgres server is
+// started with the initdb and pg_ctl binaries on the PATH, and stopped once the tests are done.
+// If neither is available, database tests are skipped.
+
+var testPostgres struct {
+	once    sync.Once
+	dsn     string
+	dataDir string
+	err     error
+}
+
+// Runs the tests of a package, and stops the throwaway Postgres server if one was started.
+// Call it from TestMain: os.Exit(testdb.Run(m)).
+func Run(m *testing.M) int {
+	code := m.Run()
+
+	if testPostgres.dataDir != "" {
+		_ = exec.Command("pg_ctl", "-D", testPostgres.dataDir, "-m", "immediate", "stop").Run()
+		_ = os.RemoveAll(filepath.Dir(testPostgres.dataDir))
+	}
+
+	return code
+}
+
+func testDatabaseURL() (string, error) {
+	testPostgres.once.Do(func() {
+		if dsn := os.Getenv("TEST_DATABASE_URL"); dsn != "" {
+			testPostgres.dsn = dsn
+			return
+		}
+
+		testPostgres.dsn, testPostgres.err = startTestPostgres()
+	})
+
+	return testPostgres.dsn, testPostgres.err
+}
+
+func startTestPostgres() (string, error) {
+	for _, binary := range []string{"initdb", "pg_ctl"} {
+		if _, err := exec.LookPath(binary); err != nil {
+			return "", fmt.Errorf("TEST_DATABASE_URL is not set and %v is not installed", binary)
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "prime-shine-postgres-")
+	if err != nil {
+		return "", err
+	}
+
+	dataDir := filepath.Join(dir, "data")
+
+	output, err := exec.Command("initdb", "-D", dataDir, "-U", "postgres", "--auth=trust", "--no-sync").CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("initdb: %v: %s", err, output)
+	}
+
+	// Postgres wants a port even when it only listens on a unix socket; borrow a free one.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	options := fmt.Sprintf("-p %d -k %v -c listen_addresses='' -c fsync=off", port, dir)
+	output, err = exec.Command("pg_ctl", "-D", dataDir, "-o", options, "-w", "start").CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("pg_ctl start: %v: %s", err, output)
+	}
+
+	testPostgres.dataDir = dataDir
+
+	return fmt.Sprintf("host=%v port=%d user=postgres dbname=postgres sslmode=disable", dir, port), nil
+}
+
+// Connects to a new schema that only this test uses, with every migration applied.
+// The schema is dropped when the test ends.
+func New(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	conn := NewEmpty(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	_, err := db.MigrateUp(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return conn
+}
+
+// Like New, but without applying any migration, for tests of the migrations themselves.
+func NewEmpty(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	dsn, err := testDatabaseURL()
+	if err != nil {
+		t.Skipf("no test database: %v", err)
+	}
+
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		t.Fatal(err)
+	}
+
+	schema := "test_" + hex.EncodeToString(suffix)
+	ctx := context.Background()
+
+	admin, err := openTestDB(dsn, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { admin.Close() })
+
+	_, err = admin.ExecContext(ctx, "CREATE SCHEMA "+schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_, err := admin.ExecContext(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+		if err != nil {
+			t.Errorf("drop test schema: %v", err)
+		}
+	})
+
+	conn, err := openTestDB(dsn, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+// Opens a connection pool whose connections all use the given schema.
+func openTestDB(dsn string, schema string) (*sqlx.DB, error) {
+	config, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if schema != "" {
+		// public holds extensions such as btree_gist, which every schema shares
+		config.RuntimeParams["search_path"] = schema + ", public"
+	}
+
+	conn := sqlx.NewDb(stdlib.OpenDB(*config), "pgx")
+	if err := conn.Ping(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// Inserts a user straight into the database, for tests that need one to exist.
+func CreateUser(t *testing.T, conn *sqlx.DB, email string, role string) int {
+	t.Helper()
+
+	var userID int
+	err := conn.GetContext(context.Background(), &userID, `
+		INSERT INTO users
+		(name, email, password, role, email_verified_at)
+		VALUES ('Test User', $1, 'not a bcrypt hash', $2, now())
+		RETURNING userid
+	`, email, role)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return userID
+}
@@ -0,0 +1,75 @@
+package wave
+
+import (
+	"sync"
+	"time"
+)
+
+// Stops sending requests to Wave once enough of them failed in a row, so that an outage fails fast
+// instead of pinning every handler until it times out. After the cooldown, a single request is let
+// through: if it succeeds the breaker closes again, otherwise it stays open for another cooldown.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+
+	failures    int
+	openedAt    time.Time // zero while closed
+	trialActive bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Reports whether a request may be sent. Every allowed request has to be followed by record or release.
+func (b *circuitBreaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openedAt.IsZero() {
+		return true
+	}
+
+	if b.trialActive || time.Since(b.openedAt) < b.cooldown {
+		return false
+	}
+
+	b.trialActive = true
+	return true
+}
+
+// Records the outcome of an allowed request.
+func (b *circuitBreaker) record(success bool) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trialActive = false
+
+	if success {
+		b.failures = 0
+		b.openedAt = time.Time{}
+		return
+	}
+
+	b.failures++
+	if !b.openedAt.IsZero() || b.failures >= b.threshold {
+		b.openedAt = time.Now()
+	}
+}
+
+// Forgets an allowed request whose outcome says nothing about Wave, e.g. one the caller cancelled.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trialActive = false
+}
@@ -1,6 +1,7 @@
 package wave
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -30,10 +31,10 @@ type queryWaveBusinessAccountsData struct {
 	Accounts []WaveBusinessAccount `json:"accounts"`
 }
 
-func GetBusinessAccounts(identityBusinessID string) (*[]WaveBusinessAccount, error) {
+func (c *Client) GetBusinessAccounts(ctx context.Context, identityBusinessID string) (*[]WaveBusinessAccount, error) {
 	path := fmt.Sprintf("/%v/accountsv2/anchor/", identityBusinessID)
 
-	response, err := createWaveBusinessAPIRequest(http.MethodGet, path, nil)
+	response, err := c.createWaveBusinessAPIRequest(ctx, http.MethodGet, path, nil)
 	if err != nil {
 		return nil, errors.Wrap(err, "createWaveBusinessAPIRequest")
 	}
@@ -1,6 +1,7 @@
 package wave
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/pkg/errors"
@@ -37,7 +38,7 @@ type businessesQueryData struct {
 	} `json:"businesses"`
 }
 
-func GetBusinessInfo() (*WaveBusinessInfo, error) {
+func (c *Client) GetBusinessInfo(ctx context.Context) (*WaveBusinessInfo, error) {
 	body := WaveGraphQLBody{
 		Query: `
 					query {
@@ -61,7 +62,7 @@ func GetBusinessInfo() (*WaveBusinessInfo, error) {
 		`,
 	}
 
-	response, err := createWaveGraphQLRequest(body)
+	response, err := c.createWaveGraphQLRequest(ctx, body)
 	if err != nil {
 		return nil, errors.Wrap(err, "createWaveGraphQLRequest")
 	}
@@ -104,7 +105,7 @@ func GetBusinessInfo() (*WaveBusinessInfo, error) {
 		return nil, errors.New("Could not find primary Wave product data")
 	}
 
-	internalBusinessID, err := GetInternalBusinessInfo()
+	internalBusinessID, err := c.GetInternalBusinessInfo(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "GetInternalBusinessInfo")
 	}
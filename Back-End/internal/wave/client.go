@@ -0,0 +1,107 @@
+package wave
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// API is everything the API server asks of Wave.
+// Client implements it against the real Wave; tests can substitute their own implementation.
+type API interface {
+	GetBusinessInfo(ctx context.Context) (*WaveBusinessInfo, error)
+	GetInternalBusinessInfo(ctx context.Context) (string, error)
+	GetBusinessAccounts(ctx context.Context, identityBusinessID string) (*[]WaveBusinessAccount, error)
+
+	GetCustomers(ctx context.Context, businessID string, pageNum int, pageSize int) (*[]WaveCustomer, *WavePageInfoData, error)
+	GetCustomersWithData(ctx context.Context, businessID string, pageNum int, pageSize int) (*[]WaveCustomer, *WavePageInfoData, error)
+	GetAllCustomers(ctx context.Context, businessID string) (*[]WaveCustomer, error)
+	GetAllCustomersWithData(ctx context.Context, businessID string) (*[]WaveCustomer, error)
+	GetCustomer(ctx context.Context, businessID string, customerID string) (*WaveCustomer, error)
+	CreateCustomer(ctx context.Context, customerCreateInput map[string]any) error
+	EditCustomer(ctx context.Context, customerPatchInput map[string]any) error
+	DeleteCustomer(ctx context.Context, customerID string) error
+
+	GetInvoices(ctx context.Context, businessID string, filterStruct WaveInvoiceFilterData) (*[]WaveInvoice, *WavePageInfoData, error)
+	GetInvoice(ctx context.Context, businessID string, invoiceID string) (*WaveInvoice, error)
+	CreateInvoice(ctx context.Context, invoiceCreateInput map[string]any) error
+	EditInvoice(ctx context.Context, invoicePatchInput map[string]any) error
+	DeleteInvoice(ctx context.Context, invoiceID string) error
+
+	GetInvoicePayments(ctx context.Context, identityBusinessID string, internalInvoiceID string) (*[]WaveInvoicePayment, error)
+	CreateInvoicePayment(ctx context.Context, identityBusinessID string, internalInvoiceID string, invoicePayment map[string]any) (bool, error)
+	EditInvoicePayment(ctx context.Context, identityBusinessID string, internalInvoiceID string, invoicePaymentID string, invoicePayment map[string]any) (bool, error)
+	DeleteInvoicePayment(ctx context.Context, identityBusinessID string, internalInvoiceID string, invoicePaymentID string) (bool, error)
+}
+
+// Returns the API token that Wave requests are authorized with.
+type TokenProvider func() (string, error)
+
+// A token that never changes.
+func StaticToken(token string) TokenProvider {
+	return func() (string, error) {
+		return token, nil
+	}
+}
+
+// Reads the token from an environment variable on every request, so that it can be rotated without a restart.
+func EnvToken(name string) TokenProvider {
+	return func() (string, error) {
+		token := os.Getenv(name)
+		if token == "" {
+			return "", errors.Errorf("%v is not set", name)
+		}
+
+		return token, nil
+	}
+}
+
+// Client talks to Wave's GraphQL API and its business API.
+type Client struct {
+	graphQLURL  string
+	businessURL string
+	token       TokenProvider
+	httpClient  *http.Client
+	logger      *log.Logger
+	options     ClientOptions
+	breaker     *circuitBreaker
+}
+
+var _ API = (*Client)(nil)
+
+// Creates a client for the given Wave endpoints.
+// A nil httpClient means http.DefaultClient; timeouts are set per attempt through the options instead.
+func NewClient(graphQLURL string, businessURL string, token TokenProvider, httpClient *http.Client, logger *log.Logger, options ClientOptions) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{
+		graphQLURL:  graphQLURL,
+		businessURL: businessURL,
+		token:       token,
+		httpClient:  httpClient,
+		logger:      logger,
+		options:     options,
+		breaker:     newCircuitBreaker(options.BreakerThreshold, options.BreakerCooldown),
+	}
+}
+
+// Creates a client configured from the WAVE_* environment variables.
+// WAVE_GRAPHQL_URL and WAVE_BUSINESS_URL default to the real Wave endpoints.
+func NewClientFromEnv(logger *log.Logger, options ClientOptions) *Client {
+	graphQLURL := os.Getenv("WAVE_GRAPHQL_URL")
+	if graphQLURL == "" {
+		graphQLURL = WAVE_GRAPHQL_URL
+	}
+
+	businessURL := os.Getenv("WAVE_BUSINESS_URL")
+	if businessURL == "" {
+		businessURL = WAVE_BUSINESS_URL
+	}
+
+	return NewClient(graphQLURL, businessURL, EnvToken("WAVE_TOKEN"), nil, logger, options)
+}
@@ -1,6 +1,7 @@
 package wave
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/pkg/errors"
@@ -39,7 +40,7 @@ type waveCustomersQueryData struct {
 	} `json:"business"`
 }
 
-func GetCustomers(businessID string, pageNum int, pageSize int) (*[]WaveCustomer, *WavePageInfoData, error) {
+func (c *Client) GetCustomers(ctx context.Context, businessID string, pageNum int, pageSize int) (*[]WaveCustomer, *WavePageInfoData, error) {
 	body := WaveGraphQLBody{
 		Query: `
 					query($businessId: ID!, $pageNum: Int!, $pageSize: Int!) {
@@ -67,7 +68,7 @@ func GetCustomers(businessID string, pageNum int, pageSize int) (*[]WaveCustomer
 		},
 	}
 
-	response, err := createWaveGraphQLRequest(body)
+	response, err := c.createWaveGraphQLRequest(ctx, body)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "createWaveGraphQLRequest")
 	}
@@ -86,7 +87,7 @@ func GetCustomers(businessID string, pageNum int, pageSize int) (*[]WaveCustomer
 	return &customers, &queryData.Business.Customers.PageInfo, nil
 }
 
-func GetCustomersWithData(businessID string, pageNum int, pageSize int) (*[]WaveCustomer, *WavePageInfoData, error) {
+func (c *Client) GetCustomersWithData(ctx context.Context, businessID string, pageNum int, pageSize int) (*[]WaveCustomer, *WavePageInfoData, error) {
 	body := WaveGraphQLBody{
 		Query: `
 					query($businessId: ID!, $pageNum: Int!, $pageSize: Int!) {
@@ -127,7 +128,7 @@ func GetCustomersWithData(businessID string, pageNum int, pageSize int) (*[]Wave
 		},
 	}
 
-	response, err := createWaveGraphQLRequest(body)
+	response, err := c.createWaveGraphQLRequest(ctx, body)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "createWaveGraphQLRequest")
 	}
@@ -146,13 +147,13 @@ func GetCustomersWithData(businessID string, pageNum int, pageSize int) (*[]Wave
 	return &customers, &queryData.Business.Customers.PageInfo, nil
 }
 
-func GetAllCustomers(businessID string) (*[]WaveCustomer, error) {
+func (c *Client) GetAllCustomers(ctx context.Context, businessID string) (*[]WaveCustomer, error) {
 	pageSize := 500
 	pageNum := 1
 	var allCustomers []WaveCustomer
 
 	for {
-		customers, pageInfo, err := GetCustomers(businessID, pageNum, pageSize)
+		customers, pageInfo, err := c.GetCustomers(ctx, businessID, pageNum, pageSize)
 		if err != nil {
 			return nil, errors.Wrapf(err, "GetCustomers - page %v", pageNum)
 		}
@@ -169,13 +170,13 @@ func GetAllCustomers(businessID string) (*[]WaveCustomer, error) {
 	return &allCustomers, nil
 }
 
-func GetAllCustomersWithData(businessID string) (*[]WaveCustomer, error) {
+func (c *Client) GetAllCustomersWithData(ctx context.Context, businessID string) (*[]WaveCustomer, error) {
 	pageSize := 500
 	pageNum := 1
 	var allCustomers []WaveCustomer
 
 	for {
-		customers, pageInfo, err := GetCustomersWithData(businessID, pageNum, pageSize)
+		customers, pageInfo, err := c.GetCustomersWithData(ctx, businessID, pageNum, pageSize)
 		if err != nil {
 			return nil, errors.Wrapf(err, "GetCustomersWithData - page %v", pageNum)
 		}
@@ -198,7 +199,7 @@ type waveCustomerQueryData struct {
 	} `json:"business"`
 }
 
-func GetCustomer(businessID string, customerID string) (*WaveCustomer, error) {
+func (c *Client) GetCustomer(ctx context.Context, businessID string, customerID string) (*WaveCustomer, error) {
 	body := WaveGraphQLBody{
 		Query: `
 					query($businessId: ID!, $customerId: ID!) {
@@ -229,7 +230,7 @@ func GetCustomer(businessID string, customerID string) (*WaveCustomer, error) {
 		},
 	}
 
-	response, err := createWaveGraphQLRequest(body)
+	response, err := c.createWaveGraphQLRequest(ctx, body)
 	if err != nil {
 		return nil, errors.Wrap(err, "createWaveGraphQLRequest")
 	}
@@ -251,7 +252,7 @@ type editCustomerMutationData struct {
 	} `json:"customerPatch"`
 }
 
-func EditCustomer(customerPatchInput map[string]any) error {
+func (c *Client) EditCustomer(ctx context.Context, customerPatchInput map[string]any) error {
 	body := WaveGraphQLBody{
 		Query: `
 					mutation($input: CustomerPatchInput!) {
@@ -270,7 +271,7 @@ func EditCustomer(customerPatchInput map[string]any) error {
 		},
 	}
 
-	response, err := createWaveGraphQLRequest(body)
+	response, err := c.createWaveGraphQLRequest(ctx, body)
 	if err != nil {
 		return errors.Wrap(err, "createWaveGraphQLRequest")
 	}
@@ -281,18 +282,8 @@ func EditCustomer(customerPatchInput map[string]any) error {
 		return errors.Wrap(err, "json deserialization")
 	}
 
-	inputErrors := mutationData.CustomerPatch.InputErrors
-	didSucceed := mutationData.CustomerPatch.DidSucceed
-
-	if inputErrors != nil {
-		return errors.Errorf("%v", *inputErrors)
-	}
-
-	if !didSucceed {
-		return errors.New("Failed to edit customer.")
-	}
-
-	return nil
+	result := mutationData.CustomerPatch
+	return mutationError("customerPatch", result.DidSucceed, result.InputErrors)
 }
 
 type deleteCustomerMutationData struct {
@@ -302,7 +293,7 @@ type deleteCustomerMutationData struct {
 	} `json:"customerDelete"`
 }
 
-func DeleteCustomer(customerID string) error {
+func (c *Client) DeleteCustomer(ctx context.Context, customerID string) error {
 	body := WaveGraphQLBody{
 		Query: `
 					mutation($input: CustomerDeleteInput!) {
@@ -323,7 +314,7 @@ func DeleteCustomer(customerID string) error {
 		},
 	}
 
-	response, err := createWaveGraphQLRequest(body)
+	response, err := c.createWaveGraphQLRequest(ctx, body)
 	if err != nil {
 		return errors.Wrap(err, "createWaveGraphQLRequest")
 	}
@@ -334,18 +325,8 @@ func DeleteCustomer(customerID string) error {
 		return errors.Wrap(err, "json deserialization")
 	}
 
-	inputErrors := mutationData.CustomerDelete.InputErrors
-	didSucceed := mutationData.CustomerDelete.DidSucceed
-
-	if inputErrors != nil {
-		return errors.Errorf("%v", *inputErrors)
-	}
-
-	if !didSucceed {
-		return errors.New("Failed to delete customer.")
-	}
-
-	return nil
+	result := mutationData.CustomerDelete
+	return mutationError("customerDelete", result.DidSucceed, result.InputErrors)
 }
 
 type createCustomerMutationData struct {
@@ -355,7 +336,7 @@ type createCustomerMutationData struct {
 	} `json:"customerCreate"`
 }
 
-func CreateCustomer(customerCreateInput map[string]any) error {
+func (c *Client) CreateCustomer(ctx context.Context, customerCreateInput map[string]any) error {
 	body := WaveGraphQLBody{
 		Query: `
 					mutation($input: CustomerCreateInput!) {
@@ -374,7 +355,7 @@ func CreateCustomer(customerCreateInput map[string]any) error {
 		},
 	}
 
-	response, err := createWaveGraphQLRequest(body)
+	response, err := c.createWaveGraphQLRequest(ctx, body)
 	if err != nil {
 		return errors.Wrap(err, "createWaveGraphQLRequest")
 	}
@@ -385,16 +366,6 @@ func CreateCustomer(customerCreateInput map[string]any) error {
 		return errors.Wrap(err, "json deserialization")
 	}
 
-	inputErrors := mutationData.CustomerCreate.InputErrors
-	didSucceed := mutationData.CustomerCreate.DidSucceed
-
-	if inputErrors != nil {
-		return errors.Errorf("%v", *inputErrors)
-	}
-
-	if !didSucceed {
-		return errors.New("Failed to create customer.")
-	}
-
-	return nil
+	result := mutationData.CustomerCreate
+	return mutationError("customerCreate", result.DidSucceed, result.InputErrors)
 }
@@ -0,0 +1,106 @@
+package wave
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Returned without contacting Wave while the circuit breaker is open, i.e. after Wave kept failing.
+var ErrUnavailable = errors.New("Wave is unavailable")
+
+// Wave answered with a status other than 200 or 201.
+type StatusError struct {
+	StatusCode int
+	Body       string
+
+	// Field errors found in the body, for business API requests that Wave rejected as invalid.
+	InputErrors []WaveInputError
+}
+
+func newStatusError(statusCode int, body []byte) *StatusError {
+	return &StatusError{
+		StatusCode:  statusCode,
+		Body:        string(body),
+		InputErrors: parseBusinessAPIInputErrors(body),
+	}
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("Wave responded with status %v: %v", e.StatusCode, e.Body)
+}
+
+// Wave answered a GraphQL request with errors instead of data.
+type GraphQLErrors []WaveGraphQLError
+
+func (e GraphQLErrors) Error() string {
+	var errorMessages []string
+
+	for _, graphQLError := range e {
+		if len(graphQLError.Path) > 0 {
+			errorMessages = append(errorMessages, fmt.Sprintf("(%v: %v)", graphQLError.Message, graphQLError.Path))
+		} else {
+			errorMessages = append(errorMessages, graphQLError.Message)
+		}
+	}
+
+	return strings.Join(errorMessages, ", ")
+}
+
+// Wave refused the input of a mutation.
+type InputError struct {
+	Operation string // e.g. customerCreate
+	Errors    []WaveInputError
+}
+
+func (e *InputError) Error() string {
+	var errorMessages []string
+
+	for _, inputError := range e.Errors {
+		if field := inputError.Field(); field != "" {
+			errorMessages = append(errorMessages, fmt.Sprintf("%v: %v", field, inputError.Message))
+		} else {
+			errorMessages = append(errorMessages, inputError.Message)
+		}
+	}
+
+	return fmt.Sprintf("%v: %v", e.Operation, strings.Join(errorMessages, ", "))
+}
+
+// Checks the result of a mutation, which reports invalid input in the response data rather than as an error.
+func mutationError(operation string, didSucceed bool, inputErrors *[]WaveInputError) error {
+	if inputErrors != nil && len(*inputErrors) > 0 {
+		return &InputError{Operation: operation, Errors: *inputErrors}
+	}
+
+	if !didSucceed {
+		return errors.Errorf("%v did not succeed", operation)
+	}
+
+	return nil
+}
+
+// The business API rejects invalid input with an object of messages per field, e.g. {"amount": ["This field is required."]}.
+// Anything else in the body (such as {"detail": "Not found."}) is not about a field.
+func parseBusinessAPIInputErrors(body []byte) []WaveInputError {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil
+	}
+
+	var inputErrors []WaveInputError
+	for field, value := range fields {
+		var messages []string
+		if err := json.Unmarshal(value, &messages); err != nil {
+			continue
+		}
+
+		for _, message := range messages {
+			inputErrors = append(inputErrors, WaveInputError{Message: message, Path: []string{field}})
+		}
+	}
+
+	return inputErrors
+}
@@ -1,6 +1,7 @@
 package wave
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -15,13 +16,13 @@ type WaveInternalBusinessInfo struct {
 	URL         string `json:"url"`
 }
 
-func GetInternalBusinessInfo() (string, error) {
+func (c *Client) GetInternalBusinessInfo(ctx context.Context) (string, error) {
 	params := url.Values{}
 	params.Set("include_personal", "false")
 
 	path := fmt.Sprintf("/?%v", params.Encode())
 
-	data, err := createWaveBusinessAPIRequest(http.MethodGet, path, nil)
+	data, err := c.createWaveBusinessAPIRequest(ctx, http.MethodGet, path, nil)
 	if err != nil {
 		return "", errors.Wrap(err, "createWaveBusinessAPIRequest")
 	}
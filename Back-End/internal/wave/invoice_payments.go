@@ -1,6 +1,7 @@
 package wave
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -29,7 +30,7 @@ type queryWaveInvoicePaymentsData struct {
 	ExchangeRate float32              `json:"exchange_rate"`
 }
 
-func GetInvoicePayments(identityBusinessID string, internalInvoiceID string) (*[]WaveInvoicePayment, error) {
+func (c *Client) GetInvoicePayments(ctx context.Context, identityBusinessID string, internalInvoiceID string) (*[]WaveInvoicePayment, error) {
 	params := url.Values{}
 	params.Set("embed_accounts", "true")
 	params.Set("embed_customer", "true")
@@ -43,7 +44,7 @@ func GetInvoicePayments(identityBusinessID string, internalInvoiceID string) (*[
 
 	path := fmt.Sprintf("/%v/invoices/%v/?%v", identityBusinessID, internalInvoiceID, params.Encode())
 
-	response, err := createWaveBusinessAPIRequest(http.MethodGet, path, nil)
+	response, err := c.createWaveBusinessAPIRequest(ctx, http.MethodGet, path, nil)
 	if err != nil {
 		return nil, errors.Wrap(err, "createWaveBusinessAPIRequest")
 	}
@@ -57,10 +58,10 @@ func GetInvoicePayments(identityBusinessID string, internalInvoiceID string) (*[
 	return &data.Payments, nil
 }
 
-func CreateInvoicePayment(identityBusinessID string, internalInvoiceID string, invoicePayment map[string]any) (bool, error) {
+func (c *Client) CreateInvoicePayment(ctx context.Context, identityBusinessID string, internalInvoiceID string, invoicePayment map[string]any) (bool, error) {
 	path := fmt.Sprintf("/%v/invoices/%v/payments/", identityBusinessID, internalInvoiceID)
 
-	_, err := createWaveBusinessAPIRequest(http.MethodPost, path, &invoicePayment)
+	_, err := c.createWaveBusinessAPIRequest(ctx, http.MethodPost, path, &invoicePayment)
 	if err != nil {
 		return false, errors.Wrap(err, "createWaveBusinessAPIRequest")
 	}
@@ -68,10 +69,10 @@ func CreateInvoicePayment(identityBusinessID string, internalInvoiceID string, i
 	return true, nil
 }
 
-func EditInvoicePayment(identityBusinessID string, internalInvoiceID string, invoicePaymentID string, invoicePayment map[string]any) (bool, error) {
+func (c *Client) EditInvoicePayment(ctx context.Context, identityBusinessID string, internalInvoiceID string, invoicePaymentID string, invoicePayment map[string]any) (bool, error) {
 	path := fmt.Sprintf("/%v/invoices/%v/payments/%v/", identityBusinessID, internalInvoiceID, invoicePaymentID)
 
-	_, err := createWaveBusinessAPIRequest(http.MethodPatch, path, &invoicePayment)
+	_, err := c.createWaveBusinessAPIRequest(ctx, http.MethodPatch, path, &invoicePayment)
 	if err != nil {
 		return false, errors.Wrap(err, "createWaveBusinessAPIRequest")
 	}
@@ -79,10 +80,10 @@ func EditInvoicePayment(identityBusinessID string, internalInvoiceID string, inv
 	return true, nil
 }
 
-func DeleteInvoicePayment(identityBusinessID string, internalInvoiceID string, invoicePaymentID string) (bool, error) {
+func (c *Client) DeleteInvoicePayment(ctx context.Context, identityBusinessID string, internalInvoiceID string, invoicePaymentID string) (bool, error) {
 	path := fmt.Sprintf("/%v/invoices/%v/payments/%v/", identityBusinessID, internalInvoiceID, invoicePaymentID)
 
-	_, err := createWaveBusinessAPIRequest(http.MethodDelete, path, nil)
+	_, err := c.createWaveBusinessAPIRequest(ctx, http.MethodDelete, path, nil)
 	if err != nil {
 		return false, errors.Wrap(err, "createWaveBusinessAPIRequest")
 	}
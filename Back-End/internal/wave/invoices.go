@@ -1,6 +1,7 @@
 package wave
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"prime-shine-api/internal/graphql"
@@ -126,7 +127,7 @@ type waveInvoicesQueryData struct {
 	} `json:"business"`
 }
 
-func GetInvoices(businessID string, filterStruct WaveInvoiceFilterData) (*[]WaveInvoice, *WavePageInfoData, error) {
+func (c *Client) GetInvoices(ctx context.Context, businessID string, filterStruct WaveInvoiceFilterData) (*[]WaveInvoice, *WavePageInfoData, error) {
 	variablesStr, paramsStr := constructInvoiceFilterStrings(filterStruct)
 	variables := constructInvoiceGraphQLVariablesMap(businessID, filterStruct)
 
@@ -185,7 +186,7 @@ func GetInvoices(businessID string, filterStruct WaveInvoiceFilterData) (*[]Wave
 		Variables: variables,
 	}
 
-	response, err := createWaveGraphQLRequest(body)
+	response, err := c.createWaveGraphQLRequest(ctx, body)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "createWaveGraphQLRequest")
 	}
@@ -210,7 +211,7 @@ type waveInvoiceQueryData struct {
 	} `json:"business"`
 }
 
-func GetInvoice(businessID string, invoiceID string) (*WaveInvoice, error) {
+func (c *Client) GetInvoice(ctx context.Context, businessID string, invoiceID string) (*WaveInvoice, error) {
 	body := WaveGraphQLBody{
 		Query: `
 					query($businessId: ID!, $invoiceId: ID!) {
@@ -258,7 +259,7 @@ func GetInvoice(businessID string, invoiceID string) (*WaveInvoice, error) {
 		},
 	}
 
-	response, err := createWaveGraphQLRequest(body)
+	response, err := c.createWaveGraphQLRequest(ctx, body)
 	if err != nil {
 		return nil, errors.Wrap(err, "createWaveGraphQLRequest")
 	}
@@ -279,7 +280,7 @@ type editInvoiceMutationData struct {
 	} `json:"invoicePatch"`
 }
 
-func EditInvoice(invoicePatchInput map[string]any) error {
+func (c *Client) EditInvoice(ctx context.Context, invoicePatchInput map[string]any) error {
 	body := WaveGraphQLBody{
 		Query: `
 					mutation($input: InvoicePatchInput!) {
@@ -298,7 +299,7 @@ func EditInvoice(invoicePatchInput map[string]any) error {
 		},
 	}
 
-	response, err := createWaveGraphQLRequest(body)
+	response, err := c.createWaveGraphQLRequest(ctx, body)
 	if err != nil {
 		return errors.Wrap(err, "createWaveGraphQLRequest")
 	}
@@ -309,18 +310,8 @@ func EditInvoice(invoicePatchInput map[string]any) error {
 		return errors.Wrap(err, "json deserialization")
 	}
 
-	inputErrors := mutationData.InvoicePatch.InputErrors
-	didSucceed := mutationData.InvoicePatch.DidSucceed
-
-	if inputErrors != nil {
-		return errors.Errorf("%v", *inputErrors)
-	}
-
-	if !didSucceed {
-		return errors.New("Failed to edit invoice.")
-	}
-
-	return nil
+	result := mutationData.InvoicePatch
+	return mutationError("invoicePatch", result.DidSucceed, result.InputErrors)
 }
 
 type deleteInvoiceMutationData struct {
@@ -330,7 +321,7 @@ type deleteInvoiceMutationData struct {
 	} `json:"invoiceDelete"`
 }
 
-func DeleteInvoice(invoiceID string) error {
+func (c *Client) DeleteInvoice(ctx context.Context, invoiceID string) error {
 	body := WaveGraphQLBody{
 		Query: `
 					mutation($input: InvoiceDeleteInput!) {
@@ -351,7 +342,7 @@ func DeleteInvoice(invoiceID string) error {
 		},
 	}
 
-	response, err := createWaveGraphQLRequest(body)
+	response, err := c.createWaveGraphQLRequest(ctx, body)
 	if err != nil {
 		return errors.Wrap(err, "createWaveGraphQLRequest")
 	}
@@ -362,18 +353,8 @@ func DeleteInvoice(invoiceID string) error {
 		return errors.Wrap(err, "json deserialization")
 	}
 
-	inputErrors := mutationData.InvoiceDelete.InputErrors
-	didSucceed := mutationData.InvoiceDelete.DidSucceed
-
-	if inputErrors != nil {
-		return errors.Errorf("%v", *inputErrors)
-	}
-
-	if !didSucceed {
-		return errors.New("Failed to delete invoice.")
-	}
-
-	return nil
+	result := mutationData.InvoiceDelete
+	return mutationError("invoiceDelete", result.DidSucceed, result.InputErrors)
 }
 
 type createInvoiceMutationData struct {
@@ -383,7 +364,7 @@ type createInvoiceMutationData struct {
 	} `json:"invoiceCreate"`
 }
 
-func CreateInvoice(invoiceCreateInput map[string]any) error {
+func (c *Client) CreateInvoice(ctx context.Context, invoiceCreateInput map[string]any) error {
 	body := WaveGraphQLBody{
 		Query: `
 					mutation($input: InvoiceCreateInput!) {
@@ -402,7 +383,7 @@ func CreateInvoice(invoiceCreateInput map[string]any) error {
 		},
 	}
 
-	response, err := createWaveGraphQLRequest(body)
+	response, err := c.createWaveGraphQLRequest(ctx, body)
 	if err != nil {
 		return errors.Wrap(err, "createWaveGraphQLRequest")
 	}
@@ -413,16 +394,6 @@ func CreateInvoice(invoiceCreateInput map[string]any) error {
 		return errors.Wrap(err, "json deserialization")
 	}
 
-	inputErrors := mutationData.InvoiceCreate.InputErrors
-	didSucceed := mutationData.InvoiceCreate.DidSucceed
-
-	if inputErrors != nil {
-		return errors.Errorf("%v", *inputErrors)
-	}
-
-	if !didSucceed {
-		return errors.New("Failed to create invoice.")
-	}
-
-	return nil
+	result := mutationData.InvoiceCreate
+	return mutationError("invoiceCreate", result.DidSucceed, result.InputErrors)
 }
@@ -0,0 +1,160 @@
+package wave
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DEFAULT_TIMEOUT           = 3 * time.Second
+	DEFAULT_MAX_RETRIES       = 2
+	DEFAULT_RETRY_BASE_DELAY  = 250 * time.Millisecond
+	DEFAULT_RETRY_MAX_DELAY   = 2 * time.Second
+	DEFAULT_BREAKER_THRESHOLD = 5
+	DEFAULT_BREAKER_COOLDOWN  = 30 * time.Second
+)
+
+// How long Wave requests may take, how they are retried, and when to stop trying altogether.
+type ClientOptions struct {
+	Timeout          time.Duration // per attempt
+	MaxRetries       int
+	RetryBaseDelay   time.Duration
+	RetryMaxDelay    time.Duration // longer Retry-After headers are not waited out
+	BreakerThreshold int           // consecutive failures that open the circuit breaker
+	BreakerCooldown  time.Duration // how long the breaker stays open before letting a request through
+}
+
+func DefaultClientOptions() ClientOptions {
+	return ClientOptions{
+		Timeout:          DEFAULT_TIMEOUT,
+		MaxRetries:       DEFAULT_MAX_RETRIES,
+		RetryBaseDelay:   DEFAULT_RETRY_BASE_DELAY,
+		RetryMaxDelay:    DEFAULT_RETRY_MAX_DELAY,
+		BreakerThreshold: DEFAULT_BREAKER_THRESHOLD,
+		BreakerCooldown:  DEFAULT_BREAKER_COOLDOWN,
+	}
+}
+
+// Sends a request to Wave, retrying it with jittered exponential backoff when Wave is overloaded or failing.
+// Requests that are not idempotent are only retried on 429, which Wave answers before doing any work.
+// The status code and body of the last attempt are returned.
+func (c *Client) do(ctx context.Context, method string, url string, body []byte, idempotent bool) (int, []byte, error) {
+	token, err := c.token()
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "token")
+	}
+
+	for attempt := 0; ; attempt++ {
+		if !c.breaker.allow() {
+			return 0, nil, ErrUnavailable
+		}
+
+		response, responseBody, err := c.send(ctx, method, url, token, body)
+
+		// the caller giving up says nothing about Wave's health
+		if ctx.Err() != nil {
+			c.breaker.release()
+			return 0, nil, errors.Wrap(ctx.Err(), "waiting for Wave")
+		}
+
+		status := 0
+		if response != nil {
+			status = response.StatusCode
+		}
+
+		failed := err != nil || status >= http.StatusInternalServerError
+		c.breaker.record(!failed)
+
+		retryable := status == http.StatusTooManyRequests || (idempotent && failed)
+		if !retryable || attempt >= c.options.MaxRetries {
+			return status, responseBody, err
+		}
+
+		delay := c.backoff(attempt)
+		if response != nil {
+			if retryAfter, ok := parseRetryAfter(response.Header.Get("Retry-After"), time.Now()); ok {
+				if retryAfter > c.options.RetryMaxDelay {
+					return status, responseBody, err
+				}
+
+				delay = retryAfter
+			}
+		}
+
+		c.logger.Printf("Retrying Wave request %v %v in %v (status %v, error %v)", method, url, delay, status, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, nil, errors.Wrap(ctx.Err(), "waiting for Wave")
+		case <-timer.C:
+		}
+	}
+}
+
+// Makes a single attempt, bounded by the per-attempt timeout.
+func (c *Client) send(ctx context.Context, method string, url string, token string, body []byte) (*http.Response, []byte, error) {
+	if c.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.options.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "creating the %v request", method)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", token))
+
+	response, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "dispatching the %v request", method)
+	}
+
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return response, nil, errors.Wrapf(err, "reading %v response body", method)
+	}
+
+	return response, responseBody, nil
+}
+
+// "Full jitter": a random delay up to an exponentially growing ceiling.
+func (c *Client) backoff(attempt int) time.Duration {
+	ceiling := min(c.options.RetryBaseDelay<<attempt, c.options.RetryMaxDelay)
+	if ceiling <= 0 {
+		return 0
+	}
+
+	return rand.N(ceiling) + 1
+}
+
+// Retry-After is either a number of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0), true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+
+	return 0, false
+}
@@ -1,12 +1,9 @@
 package wave
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
-	"io"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -18,7 +15,22 @@ type WavePageInfoData struct {
 	TotalCount  int `json:"totalCount"`
 }
 
-type WaveInputError map[string]any
+// A problem with one field of a mutation's input.
+type WaveInputError struct {
+	Code    string   `json:"code"`
+	Message string   `json:"message"`
+	Path    []string `json:"path"` // e.g. ["input", "email"]
+}
+
+// The input field the error is about, without the leading "input", e.g. "address.city".
+func (e WaveInputError) Field() string {
+	path := e.Path
+	if len(path) > 0 && path[0] == "input" {
+		path = path[1:]
+	}
+
+	return strings.Join(path, ".")
+}
 
 type WaveGraphQLVariables map[string]any
 
@@ -28,8 +40,14 @@ type WaveGraphQLBody struct {
 }
 
 type WaveGraphQLError struct {
-	Message   string            `json:"message"`
-	Locations *[]map[string]any `json:"locations"`
+	Message    string                     `json:"message"`
+	Locations  *[]map[string]any          `json:"locations"`
+	Path       []any                      `json:"path"` // field names and list indexes
+	Extensions WaveGraphQLErrorExtensions `json:"extensions"`
+}
+
+type WaveGraphQLErrorExtensions struct {
+	Code string `json:"code"` // e.g. NOT_FOUND
 }
 
 type WaveGraphQLResponse struct {
@@ -37,56 +55,23 @@ type WaveGraphQLResponse struct {
 	Errors *[]WaveGraphQLError `json:"errors"`
 }
 
-func getWaveAPIToken() string {
-	return os.Getenv("WAVE_TOKEN")
-}
-
-func transformErrorsArrayIntoError(graphQLErrors []WaveGraphQLError) string {
-	var errorMessages []string
-
-	for _, graphQLError := range graphQLErrors {
-		if graphQLError.Locations != nil {
-			errorMessages = append(errorMessages, fmt.Sprintf("(%v: %v)", graphQLError.Message, *graphQLError.Locations))
-		} else {
-			errorMessages = append(errorMessages, graphQLError.Message)
-		}
-	}
-
-	return strings.Join(errorMessages, ", ")
-}
-
-func createWaveGraphQLRequest(body WaveGraphQLBody) (string, error) {
+func (c *Client) createWaveGraphQLRequest(ctx context.Context, body WaveGraphQLBody) (string, error) {
 	serializedBody, err := json.MarshalIndent(body, "", "\t")
 	if err != nil {
 		return "", errors.Wrap(err, "json serialization")
 	}
 
-	requestBody := bytes.NewBuffer(serializedBody)
-	req, err := http.NewRequest("POST", WAVE_GRAPHQL_URL, requestBody)
-	if err != nil {
-		return "", errors.Wrap(err, "creating the POST request")
-	}
+	// queries can safely be sent again, mutations cannot
+	idempotent := !strings.HasPrefix(strings.TrimSpace(body.Query), "mutation")
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", getWaveAPIToken()))
-
-	client := &http.Client{}
-	response, err := client.Do(req)
-	if err != nil {
-		return "", errors.Wrap(err, "dispatching the POST request")
-	}
-
-	defer response.Body.Close()
-
-	responseBody, err := io.ReadAll(response.Body)
+	status, responseBody, err := c.do(ctx, http.MethodPost, c.graphQLURL, serializedBody, idempotent)
 	if err != nil {
-		return "", errors.Wrap(err, "reading POST response body")
+		return "", errors.Wrap(err, "do")
 	}
 
-	responseBodyText := string(responseBody)
-
-	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusCreated {
-		return "", errors.Wrap(err, responseBodyText)
+	if status < 200 || status > 299 {
+		c.logger.Printf("Wave GraphQL request failed with status %v", status)
+		return "", newStatusError(status, responseBody)
 	}
 
 	var responseStruct WaveGraphQLResponse
@@ -96,7 +81,7 @@ func createWaveGraphQLRequest(body WaveGraphQLBody) (string, error) {
 	}
 
 	if responseStruct.Errors != nil {
-		return "", errors.New(transformErrorsArrayIntoError(*responseStruct.Errors))
+		return "", GraphQLErrors(*responseStruct.Errors)
 	}
 
 	// we serialize the data so that the caller can deserialize the data to whatever structure they desire.
@@ -108,39 +93,22 @@ func createWaveGraphQLRequest(body WaveGraphQLBody) (string, error) {
 	return string(data), nil
 }
 
-func createWaveBusinessAPIRequest(method string, path string, body *map[string]any) (string, error) {
+func (c *Client) createWaveBusinessAPIRequest(ctx context.Context, method string, path string, body *map[string]any) (string, error) {
 	serializedBody, err := json.MarshalIndent(body, "", "\t")
 	if err != nil {
 		return "", errors.Wrap(err, "json serialization")
 	}
 
-	requestBody := bytes.NewBuffer(serializedBody)
-	req, err := http.NewRequest(method, WAVE_BUSINESS_URL+path, requestBody)
+	status, responseBody, err := c.do(ctx, method, c.businessURL+path, serializedBody, method == http.MethodGet)
 	if err != nil {
-		return "", errors.Wrapf(err, "creating the %v request", method)
+		return "", errors.Wrap(err, "do")
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", getWaveAPIToken()))
-
-	client := &http.Client{}
-	response, err := client.Do(req)
-	if err != nil {
-		return "", errors.Wrapf(err, "dispatching the %v request", method)
-	}
-
-	defer response.Body.Close()
-
-	responseBody, err := io.ReadAll(response.Body)
-	if err != nil {
-		return "", errors.Wrapf(err, "reading %v response body", method)
-	}
-
-	responseBodyText := string(responseBody)
-
-	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusCreated {
-		return "", errors.Wrap(err, responseBodyText)
+	// any 2xx is a success, e.g. 204 for a deleted payment
+	if status < 200 || status > 299 {
+		c.logger.Printf("Wave business API request %v %v failed with status %v", method, path, status)
+		return "", newStatusError(status, responseBody)
 	}
 
-	return responseBodyText, nil
+	return string(responseBody), nil
 }